@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
@@ -9,13 +10,25 @@ import (
 type (
 	// Config stores all application settings.
 	Config struct {
-		Environment Environment `yaml:"environment"`
+		Environment Environment `yaml:"environment" env:"ENVIRONMENT" env-required:"true"`
 		Storage     Storage     `yaml:"storage"`
 		Postgres    Postgres    `yaml:"postgres"`
+		SQLite      SQLite      `yaml:"sqlite"`
+		InMemory    InMemory    `yaml:"in_memory"`
+		S3          S3          `yaml:"s3"`
 		Log         Log         `yaml:"log"`
+		Tracing     Tracing     `yaml:"tracing"`
 		HTTP        HTTP        `yaml:"http"`
+		GRPC        GRPC        `yaml:"grpc"`
+		Shutdown    Shutdown    `yaml:"shutdown"`
+		GraphQL     GraphQL     `yaml:"graphql"`
 		Comment     Comment     `yaml:"comment"`
 		Post        Post        `yaml:"post"`
+		Presence    Presence    `yaml:"presence"`
+		Reaction    Reaction    `yaml:"reaction"`
+		Render      Render      `yaml:"render"`
+		Attachment  Attachment  `yaml:"attachment"`
+		Feed        Feed        `yaml:"feed"`
 	}
 
 	// Environment contains settings for application environment.
@@ -23,7 +36,7 @@ type (
 
 	// Storage containts settings for application data storage.
 	Storage struct {
-		Type string `yaml:"type" env:"STORAGE_TYPE" env-required:"true"` // valid values: "in-memory", "postgres"
+		Type string `yaml:"type" env:"STORAGE_TYPE" env-required:"true"` // valid values: "in-memory", "postgres", "sqlite"
 	}
 
 	Postgres struct {
@@ -33,29 +46,139 @@ type (
 		ConnTimeout  uint   `yaml:"conn_timeout" env:"POSTGRES_CONN_TIMEOUT"`
 	}
 
+	// SQLite contains settings for the single-file database. The file and its directory are created if they don't exist.
+	SQLite struct {
+		Path        string `yaml:"path" env:"SQLITE_PATH"`
+		BusyTimeout uint   `yaml:"busy_timeout" env:"SQLITE_BUSY_TIMEOUT" env-default:"5"` // in seconds, waiting for the lock of another writer
+	}
+
+	// InMemory contains settings for persisting the in-memory storage. If Directory is set, changes are written
+	// to a write-ahead log in it and restored on start. Intervals are set in seconds.
+	InMemory struct {
+		Directory        string `yaml:"directory" env:"INMEMORY_DIRECTORY"`
+		Fsync            string `yaml:"fsync" env:"INMEMORY_FSYNC" env-default:"always"` // valid values: "always", "interval", "never"
+		FsyncInterval    uint   `yaml:"fsync_interval" env:"INMEMORY_FSYNC_INTERVAL"`    // for "interval" fsync
+		SnapshotInterval uint   `yaml:"snapshot_interval" env:"INMEMORY_SNAPSHOT_INTERVAL"`
+	}
+
+	// S3 contains settings for an S3-compatible object storage.
+	S3 struct {
+		Endpoint  string `yaml:"endpoint" env:"S3_ENDPOINT"`
+		Region    string `yaml:"region" env:"S3_REGION" env-default:"us-east-1"`
+		Bucket    string `yaml:"bucket" env:"S3_BUCKET"`
+		AccessKey string `yaml:"access_key" env:"S3_ACCESS_KEY"`
+		SecretKey string `yaml:"secret_key" env:"S3_SECRET_KEY"`
+	}
+
 	// Log contains settings for application logger.
+	// Debug records with the same message are sampled: the first SamplingInitial records of every second
+	// are logged, and every SamplingThereafter-th record after them. 0 SamplingInitial disables sampling.
 	Log struct {
-		Level string `yaml:"level" env:"LOG_LEVEL" env-required:"true"`
+		Level              string `yaml:"level" env:"LOG_LEVEL" env-required:"true"`
+		Format             string `yaml:"format" env:"LOG_FORMAT" env-required:"true"` // valid values: "json", "text"
+		SamplingInitial    uint   `yaml:"sampling_initial" env:"LOG_SAMPLING_INITIAL"`
+		SamplingThereafter uint   `yaml:"sampling_thereafter" env:"LOG_SAMPLING_THEREAFTER"`
+	}
+
+	// Tracing contains settings for OpenTelemetry tracing.
+	Tracing struct {
+		Exporter    string  `yaml:"exporter" env:"TRACING_EXPORTER" env-required:"true"` // valid values: "none", "stdout", "otlp"
+		Endpoint    string  `yaml:"endpoint" env:"TRACING_ENDPOINT"`                     // host and port of the OTLP collector
+		Insecure    bool    `yaml:"insecure" env:"TRACING_INSECURE"`                     // connect to the collector without TLS
+		SampleRatio float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
 	}
 
 	// HTTP contains settings for HTTP server.
+	// The admin port serves metrics and must not be reachable by clients of the API.
 	HTTP struct {
-		Port string `yaml:"port" env:"HTTP_PORT" env-required:"true"`
+		Port       string `yaml:"port" env:"HTTP_PORT" env-required:"true"`
+		AdminPort  string `yaml:"admin_port" env:"HTTP_ADMIN_PORT" env-required:"true"`
+		AdminToken string `yaml:"admin_token" env:"HTTP_ADMIN_TOKEN"` // bearer token of admin endpoints changing or exporting data, empty allows the loopback only
+	}
+
+	// GRPC contains settings for the gRPC server.
+	GRPC struct {
+		Port string `yaml:"port" env:"GRPC_PORT" env-required:"true"`
+	}
+
+	// Shutdown contains settings for graceful shutdown. Durations are set in seconds.
+	// The readiness probe fails for Delay before subscriptions are completed and servers stop,
+	// so load balancers stop sending requests to the instance.
+	Shutdown struct {
+		Delay         uint `yaml:"delay" env:"SHUTDOWN_DELAY"`
+		HTTPTimeout   uint `yaml:"http_timeout" env:"SHUTDOWN_HTTP_TIMEOUT" env-required:"true"`
+		GRPCTimeout   uint `yaml:"grpc_timeout" env:"SHUTDOWN_GRPC_TIMEOUT" env-required:"true"`
+		TracerTimeout uint `yaml:"tracer_timeout" env:"SHUTDOWN_TRACER_TIMEOUT" env-required:"true"` // exporting of buffered spans
+	}
+
+	// GraphQL contains settings for GraphQL transports and limits of queries.
+	// Intervals are set in seconds, 0 disables intervals and limits.
+	GraphQL struct {
+		WebsocketKeepAlive uint     `yaml:"websocket_keep_alive" env:"GRAPHQL_WEBSOCKET_KEEP_ALIVE"` // graphql-ws "ka" messages
+		WebsocketPingPong  uint     `yaml:"websocket_ping_pong" env:"GRAPHQL_WEBSOCKET_PING_PONG"`   // graphql-transport-ws pings
+		SSEKeepAlive       uint     `yaml:"sse_keep_alive" env:"GRAPHQL_SSE_KEEP_ALIVE"`
+		MaxUploadSize      uint     `yaml:"max_upload_size" env:"GRAPHQL_MAX_UPLOAD_SIZE"`       // multipart request size in bytes
+		ComplexityLimit    uint     `yaml:"complexity_limit" env:"GRAPHQL_COMPLEXITY_LIMIT"`     // lists weigh as many items as they may return
+		MaxDepth           uint     `yaml:"max_depth" env:"GRAPHQL_MAX_DEPTH"`                   // nesting of selections, introspection isn't counted
+		APQCache           string   `yaml:"apq_cache" env:"GRAPHQL_APQ_CACHE" env-default:"lru"` // valid values: "lru", "postgres"
+		APQCacheSize       uint     `yaml:"apq_cache_size" env:"GRAPHQL_APQ_CACHE_SIZE"`         // queries kept by the APQ cache
+		PersistedQueries   string   `yaml:"persisted_queries" env:"GRAPHQL_PERSISTED_QUERIES"`   // manifest file, if set only its queries are executed
+		Introspection      bool     `yaml:"introspection" env:"GRAPHQL_INTROSPECTION"`
+		PlaygroundPath     string   `yaml:"playground_path" env:"GRAPHQL_PLAYGROUND_PATH"`                       // empty disables the playground
+		AllowedOrigins     []string `yaml:"allowed_origins" env:"GRAPHQL_ALLOWED_ORIGINS" env-separator:","`     // for CORS and websockets, "*" allows any
+		CORSMaxAge         uint     `yaml:"cors_max_age" env:"GRAPHQL_CORS_MAX_AGE"`                             // in seconds, for preflight responses
+		MetricOperations   []string `yaml:"metric_operations" env:"GRAPHQL_METRIC_OPERATIONS" env-separator:","` // names labeling metrics besides ones of the manifest, others are "other"
 	}
 
 	// Comment contains settings for comment service.
 	Comment struct {
 		MaxCharacters uint `yaml:"max_characters" env:"COMMENT_AX_CHARACTERS" env-required:"true"`
-		DefaultPage   uint `yaml:"default_page" env:"COMMENT_DEFAULT_PAGE" env-required:"true"`
+		DefaultPage   uint `yaml:"default_page" env:"COMMENT_DEFAULT_PAGE" env-default:"0"` // pages of comments are numbered from 0
 		DefaultAmount uint `yaml:"default_amount" env:"COMMENT_DEFAULT_AMOUNT" env-required:"true"`
+		MaxAmount     uint `yaml:"max_amount" env:"COMMENT_MAX_AMOUNT" env-required:"true"`
 	}
 
 	// Post contains settings for post service.
 	Post struct {
-		TitleMaxCharacters   uint `yaml:"title_max_characters" env:"POST_TITLE_MAX_CHARACTERS" env-required:"true"`
-		ContentMaxCharacters uint `yaml:"content_max_characters" env:"POST_CONTENT_MAX_CHARACTERS" env-required:"true"`
-		DefaultPage          uint `yaml:"default_page" env:"POST_DEFAULT_PAGE" env-required:"true"`
-		DefaultAmount        uint `yaml:"default_amount" env:"POST_DEFAULT_AMOUNT" env-required:"true"`
+		TitleMaxCharacters   uint  `yaml:"title_max_characters" env:"POST_TITLE_MAX_CHARACTERS" env-required:"true"`
+		ContentMaxCharacters uint  `yaml:"content_max_characters" env:"POST_CONTENT_MAX_CHARACTERS" env-required:"true"`
+		DefaultPage          uint  `yaml:"default_page" env:"POST_DEFAULT_PAGE" env-required:"true"`
+		DefaultAmount        uint  `yaml:"default_amount" env:"POST_DEFAULT_AMOUNT" env-required:"true"`
+		MaxAmount            uint  `yaml:"max_amount" env:"POST_MAX_AMOUNT" env-required:"true"`
+		SchedulerInterval    uint  `yaml:"scheduler_interval" env:"POST_SCHEDULER_INTERVAL" env-required:"true"` // in seconds
+		ModeratorIDs         []int `yaml:"moderator_ids" env:"POST_MODERATOR_IDS" env-separator:","`             // users allowed to edit any post
+	}
+
+	// Presence contains settings for tracking post viewers and typing users. TTLs are set in seconds.
+	Presence struct {
+		ViewerTTL uint `yaml:"viewer_ttl" env:"PRESENCE_VIEWER_TTL" env-required:"true"`
+		TypingTTL uint `yaml:"typing_ttl" env:"PRESENCE_TYPING_TTL" env-required:"true"`
+	}
+
+	// Reaction contains settings for reaction service.
+	Reaction struct {
+		Emojis []string `yaml:"emojis" env:"REACTION_EMOJIS" env-separator:"," env-required:"true"` // allowed emojis
+	}
+
+	// Render contains settings for rendering content of posts and comments to HTML.
+	Render struct {
+		CacheSize uint `yaml:"cache_size" env:"RENDER_CACHE_SIZE" env-required:"true"` // rendered contents kept in memory
+	}
+	// Attachment contains settings for files attached to posts.
+	Attachment struct {
+		Storage      string   `yaml:"storage" env:"ATTACHMENT_STORAGE" env-required:"true"`   // valid values: "filesystem", "s3"
+		Directory    string   `yaml:"directory" env:"ATTACHMENT_DIRECTORY"`                   // for "filesystem" storage
+		MaxSize      uint     `yaml:"max_size" env:"ATTACHMENT_MAX_SIZE" env-required:"true"` // in bytes
+		ContentTypes []string `yaml:"content_types" env:"ATTACHMENT_CONTENT_TYPES" env-separator:"," env-required:"true"`
+	}
+
+	// Feed contains settings for RSS and Atom feeds of posts.
+	Feed struct {
+		Title       string `yaml:"title" env:"FEED_TITLE" env-required:"true"`
+		Description string `yaml:"description" env:"FEED_DESCRIPTION"`
+		Link        string `yaml:"link" env:"FEED_LINK" env-required:"true"`             // public URL of the journal, posts are at <link>/posts/<slug>
+		Size        uint   `yaml:"size" env:"FEED_SIZE" env-required:"true"`             // posts in a feed
+		CacheSize   uint   `yaml:"cache_size" env:"FEED_CACHE_SIZE" env-required:"true"` // feed versions kept for Last-Modified
 	}
 )
 
@@ -69,10 +192,157 @@ func NewConfig() (*Config, error) {
 		return nil, fmt.Errorf("NewConfig - ReadConfig: %w", err)
 	}
 
+	err = validateEnvironment(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(cfg.Log.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		return nil, fmt.Errorf("NewConfig - unknown log level %q", cfg.Log.Level)
+	}
+
+	if cfg.Log.Format != "json" && cfg.Log.Format != "text" {
+		return nil, fmt.Errorf("NewConfig - unknown log format %q", cfg.Log.Format)
+	}
+
+	if cfg.HTTP.AdminPort == cfg.HTTP.Port {
+		return nil, fmt.Errorf("NewConfig - HTTP admin port is the same as the API port")
+	}
+
 	// If the storage type is postgres, the DSN must be set.
 	if cfg.Postgres.DSN == "" && cfg.Storage.Type == "postgres" {
 		return nil, fmt.Errorf("NewConfig - DSN is empty")
 	}
 
+	if cfg.Storage.Type == "sqlite" {
+		if cfg.SQLite.Path == "" {
+			return nil, fmt.Errorf("NewConfig - sqlite path is empty")
+		}
+		if cfg.SQLite.BusyTimeout == 0 {
+			return nil, fmt.Errorf("NewConfig - sqlite busy timeout is zero")
+		}
+	}
+
+	if cfg.InMemory.Directory != "" {
+		switch cfg.InMemory.Fsync {
+		case "always", "never":
+		case "interval":
+			if cfg.InMemory.FsyncInterval == 0 {
+				return nil, fmt.Errorf("NewConfig - in-memory fsync interval is zero")
+			}
+		default:
+			return nil, fmt.Errorf("NewConfig - unknown in-memory fsync %q", cfg.InMemory.Fsync)
+		}
+
+		if cfg.InMemory.SnapshotInterval == 0 {
+			return nil, fmt.Errorf("NewConfig - in-memory snapshot interval is zero")
+		}
+	}
+
+	if cfg.Post.DefaultAmount > cfg.Post.MaxAmount || cfg.Comment.DefaultAmount > cfg.Comment.MaxAmount {
+		return nil, fmt.Errorf("NewConfig - default amount exceeds max amount")
+	}
+
+	if cfg.Post.SchedulerInterval == 0 {
+		return nil, fmt.Errorf("NewConfig - post scheduler interval is zero")
+	}
+
+	// Viewer sessions are refreshed every half of TTL, so it can't be shorter than 2 seconds.
+	if cfg.Presence.ViewerTTL < 2 || cfg.Presence.TypingTTL == 0 {
+		return nil, fmt.Errorf("NewConfig - presence TTLs are too short")
+	}
+
+	if cfg.Render.CacheSize == 0 {
+		return nil, fmt.Errorf("NewConfig - render cache size is zero")
+	}
+
+	switch cfg.Attachment.Storage {
+	case "filesystem":
+		if cfg.Attachment.Directory == "" {
+			return nil, fmt.Errorf("NewConfig - attachment directory is empty")
+		}
+	case "s3":
+		if cfg.S3.Endpoint == "" || cfg.S3.Bucket == "" {
+			return nil, fmt.Errorf("NewConfig - S3 endpoint or bucket is empty")
+		}
+	default:
+		return nil, fmt.Errorf("NewConfig - unknown attachment storage %q", cfg.Attachment.Storage)
+	}
+
+	if cfg.GraphQL.APQCacheSize == 0 {
+		return nil, fmt.Errorf("NewConfig - APQ cache size is zero")
+	}
+
+	switch cfg.GraphQL.APQCache {
+	case "lru":
+	case "postgres":
+		if cfg.Storage.Type != "postgres" {
+			return nil, fmt.Errorf("NewConfig - postgres APQ cache requires postgres storage")
+		}
+	default:
+		return nil, fmt.Errorf("NewConfig - unknown APQ cache %q", cfg.GraphQL.APQCache)
+	}
+
+	// A multipart request carries the file together with the operation, so it must fit an attachment of the max size.
+	if cfg.GraphQL.MaxUploadSize < cfg.Attachment.MaxSize {
+		return nil, fmt.Errorf("NewConfig - GraphQL max upload size is less than attachment max size")
+	}
+
+	if cfg.Feed.Size == 0 || cfg.Feed.CacheSize == 0 {
+		return nil, fmt.Errorf("NewConfig - feed size or cache size is zero")
+	}
+
+	// Feeds are a page of posts, so they are limited by the max amount too.
+	if cfg.Feed.Size > cfg.Post.MaxAmount {
+		return nil, fmt.Errorf("NewConfig - feed size exceeds post max amount")
+	}
+
+	switch cfg.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		if cfg.Tracing.Endpoint == "" {
+			return nil, fmt.Errorf("NewConfig - OTLP tracing endpoint is empty")
+		}
+	default:
+		return nil, fmt.Errorf("NewConfig - unknown tracing exporter %q", cfg.Tracing.Exporter)
+	}
+
+	if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+		return nil, fmt.Errorf("NewConfig - tracing sample ratio is out of [0, 1]")
+	}
+
+	// Links of feeds and posts are built by appending paths to the link.
+	cfg.Feed.Link = strings.TrimSuffix(cfg.Feed.Link, "/")
+
 	return cfg, nil
 }
+
+// validateEnvironment checks the environment and settings it restricts.
+// Production instances can't expose the schema or the playground or allow any origin.
+func validateEnvironment(cfg *Config) error {
+	switch cfg.Environment {
+	case "development":
+	case "production":
+		if cfg.GraphQL.Introspection {
+			return fmt.Errorf("NewConfig - introspection is enabled in production")
+		}
+		if cfg.GraphQL.PlaygroundPath != "" {
+			return fmt.Errorf("NewConfig - playground is enabled in production")
+		}
+		for _, origin := range cfg.GraphQL.AllowedOrigins {
+			if origin == "*" {
+				return fmt.Errorf("NewConfig - any origin is allowed in production")
+			}
+		}
+	default:
+		return fmt.Errorf("NewConfig - unknown environment %q", cfg.Environment)
+	}
+
+	if cfg.GraphQL.PlaygroundPath != "" && !strings.HasPrefix(cfg.GraphQL.PlaygroundPath, "/") {
+		return fmt.Errorf("NewConfig - playground path must start with a slash")
+	}
+
+	return nil
+}
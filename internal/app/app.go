@@ -1,40 +1,128 @@
 package app
 
 import (
-	"net/http"
+	"context"
+	"errors"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 	"time"
 
 	"github.com/oustrix/ozon_journal/config"
 	"github.com/oustrix/ozon_journal/internal"
+	"github.com/oustrix/ozon_journal/internal/controller/admin"
 	"github.com/oustrix/ozon_journal/internal/controller/graphql"
+	"github.com/oustrix/ozon_journal/internal/controller/grpc"
+	"github.com/oustrix/ozon_journal/internal/migration"
+	"github.com/oustrix/ozon_journal/internal/repository/filesystem"
 	"github.com/oustrix/ozon_journal/internal/repository/inmemory"
 	postgresRepository "github.com/oustrix/ozon_journal/internal/repository/postgres"
+	s3Repository "github.com/oustrix/ozon_journal/internal/repository/s3"
+	sqliteRepository "github.com/oustrix/ozon_journal/internal/repository/sqlite"
 	"github.com/oustrix/ozon_journal/internal/service"
+	"github.com/oustrix/ozon_journal/pkg/grpcserver"
+	"github.com/oustrix/ozon_journal/pkg/health"
 	"github.com/oustrix/ozon_journal/pkg/httpserver"
 	"github.com/oustrix/ozon_journal/pkg/logger"
 	"github.com/oustrix/ozon_journal/pkg/postgres"
+	"github.com/oustrix/ozon_journal/pkg/s3"
+	"github.com/oustrix/ozon_journal/pkg/sqlite"
+	"github.com/oustrix/ozon_journal/pkg/tracer"
+	"github.com/oustrix/ozon_journal/pkg/wal"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 )
 
 // Run starts the application.
 func Run(cfg *config.Config) {
 	// Logger
-	log := logger.New(cfg.Log.Level)
-	log.Debug("Logger initialized", "level", cfg.Log.Level)
+	log := logger.New(cfg.Log.Level,
+		logger.Format(cfg.Log.Format),
+		logger.Sampling(int(cfg.Log.SamplingInitial), int(cfg.Log.SamplingThereafter)))
+	log.Debug("Logger initialized", "level", cfg.Log.Level, "format", cfg.Log.Format)
 	log.Info("Starting application")
 
+	// Tracing
+	log.Debug("Creating tracer", "exporter", cfg.Tracing.Exporter, "endpoint", cfg.Tracing.Endpoint,
+		"sampleRatio", cfg.Tracing.SampleRatio)
+	tr, err := tracer.New("ozon_journal",
+		tracer.Exporter(cfg.Tracing.Exporter),
+		tracer.Endpoint(cfg.Tracing.Endpoint),
+		tracer.Insecure(cfg.Tracing.Insecure),
+		tracer.SampleRatio(cfg.Tracing.SampleRatio))
+	if err != nil {
+		log.Error("Failed to create tracer", "error", err.Error())
+		return
+	}
+	// Spans are flushed after all servers stop.
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Shutdown.TracerTimeout)*time.Second)
+		defer cancel()
+
+		err := tr.Shutdown(ctx)
+		if err != nil {
+			log.Error("Got error while shutting down tracer", "error", err.Error())
+		}
+	}()
+
+	// Metrics
+	reg := newMetricsRegistry()
+
+	// Health probes
+	probes := health.New(health.OnFailure(func(ctx context.Context, name string, err error) {
+		log.WarnContext(ctx, "Readiness check failed", "check", name, "error", err.Error())
+	}))
+
 	// Repositories
 	log.Info("Creating repositories", "storage", cfg.Storage.Type)
 
 	var postRepo internal.PostRepository
 	var commentRepo internal.CommentRepository
+	var presenceRepo internal.PresenceRepository
+	var reactionRepo internal.ReactionRepository
+	var attachmentRepo internal.AttachmentRepository
+	var persistedQueryRepo internal.PersistedQueryRepository
+	var storeSizes []func() map[string]int
+	var persistence *inmemory.Persistence
 
 	if cfg.Storage.Type == "in-memory" {
 		log.Debug("Using in-memory storage")
-		postRepo = inmemory.NewPostRepository(log)
-		commentRepo = inmemory.NewCommentRepository(log)
+		posts := inmemory.NewPostRepository(log)
+		postRepo = posts
+		comments := inmemory.NewCommentRepository(log)
+		commentRepo = comments
+		presenceRepo = inmemory.NewPresenceRepository(log)
+		reactions := inmemory.NewReactionRepository(log)
+		reactionRepo = reactions
+		attachments := inmemory.NewAttachmentRepository(log)
+		attachmentRepo = attachments
+
+		storeSizes = append(storeSizes, inmemory.StorageSizes, func() map[string]int {
+			return map[string]int{"attachments": attachments.Len(), "reactions": reactions.Len()}
+		})
+
+		if cfg.InMemory.Directory != "" {
+			log.Info("Restoring in-memory storage", "directory", cfg.InMemory.Directory, "fsync", cfg.InMemory.Fsync)
+			persistence, err = inmemory.NewPersistence(cfg.InMemory.Directory, posts, comments, reactions, attachments, log,
+				wal.Sync(wal.SyncPolicy(cfg.InMemory.Fsync)),
+				wal.Interval(time.Duration(cfg.InMemory.FsyncInterval)*time.Second))
+			if err != nil {
+				log.Error("Failed to restore in-memory storage", "error", err.Error())
+				return
+			}
+			// The storage is saved last, after servers and background work stop changing it.
+			defer func() {
+				log.Info("Saving in-memory storage")
+				err := persistence.Close()
+				if err != nil {
+					log.Error("Failed to save in-memory storage", "error", err.Error())
+					return
+				}
+				log.Info("In-memory storage saved")
+			}()
+
+			probes.Add("wal", persistence.Check)
+		}
 	} else if cfg.Storage.Type == "postgres" {
 		log.Debug("Using postgres storage", "maxPoolSize", cfg.Postgres.MaxPoolSize,
 			"connAttempts", cfg.Postgres.ConnAttempts, "connTimeout", cfg.Postgres.ConnTimeout)
@@ -42,51 +130,220 @@ func Run(cfg *config.Config) {
 		pg, err := postgres.New(cfg.Postgres.DSN,
 			postgres.MaxPoolSize(int(cfg.Postgres.MaxPoolSize)),
 			postgres.ConnAttempts(int(cfg.Postgres.ConnAttempts)),
-			postgres.ConnTimeout(time.Duration(cfg.Postgres.ConnTimeout)*time.Second))
+			postgres.ConnTimeout(time.Duration(cfg.Postgres.ConnTimeout)*time.Second),
+			postgres.TracerProvider(tr.Provider()))
 		if err != nil {
 			log.Error("Failed to connect to postgres", "error", err.Error())
 			return
 		}
-		defer pg.Close()
+		// The pool is closed last, after servers and background work stop using it.
+		defer func() {
+			log.Info("Closing postgres pool")
+			pg.Close()
+			log.Info("Postgres pool closed")
+		}()
+		reg.MustRegister(pg.Collector(metricsNamespace))
 
 		log.Info("Migrating database")
-		err = migrateUp(&cfg.Postgres)
+		db := migration.Postgres(&cfg.Postgres)
+		err = migration.Up(db)
 		if err != nil {
 			log.Error("Failed to apply migrations", "error", err.Error())
 			return
 		}
 		log.Info("Database migrated")
 
+		version, err := migration.Latest(db)
+		if err != nil {
+			log.Error("Failed to get latest migration", "error", err.Error())
+			return
+		}
+
+		probes.Add("postgres", func(ctx context.Context) error {
+			return pg.Pool.Ping(ctx)
+		})
+		probes.Add("migrations", migration.Check(pg, version))
+
 		postRepo = postgresRepository.NewPostRepository(pg, log)
 		commentRepo = postgresRepository.NewCommentRepository(pg, log)
+		presenceRepo = postgresRepository.NewPresenceRepository(pg, log)
+		reactionRepo = postgresRepository.NewReactionRepository(pg, log)
+		attachmentRepo = postgresRepository.NewAttachmentRepository(pg, log)
+
+		if cfg.GraphQL.APQCache == "postgres" {
+			persistedQueryRepo = postgresRepository.NewPersistedQueryRepository(pg, int(cfg.GraphQL.APQCacheSize), log)
+		}
+	} else if cfg.Storage.Type == "sqlite" {
+		log.Debug("Using sqlite storage", "path", cfg.SQLite.Path, "busyTimeout", cfg.SQLite.BusyTimeout)
+
+		db, err := sqlite.New(cfg.SQLite.Path,
+			sqlite.BusyTimeout(time.Duration(cfg.SQLite.BusyTimeout)*time.Second))
+		if err != nil {
+			log.Error("Failed to open sqlite", "error", err.Error())
+			return
+		}
+		// The database is closed last, after servers and background work stop using it.
+		defer func() {
+			log.Info("Closing sqlite")
+			db.Close()
+			log.Info("Sqlite closed")
+		}()
+		reg.MustRegister(collectors.NewDBStatsCollector(db.DB, "sqlite"))
+
+		log.Info("Migrating database")
+		migrations := migration.SQLite(&cfg.SQLite)
+		err = migration.Up(migrations)
+		if err != nil {
+			log.Error("Failed to apply migrations", "error", err.Error())
+			return
+		}
+		log.Info("Database migrated")
+
+		version, err := migration.Latest(migrations)
+		if err != nil {
+			log.Error("Failed to get latest migration", "error", err.Error())
+			return
+		}
+
+		probes.Add("sqlite", func(ctx context.Context) error {
+			return db.DB.PingContext(ctx)
+		})
+		probes.Add("migrations", migration.CheckSQLite(db, version))
+
+		postRepo = sqliteRepository.NewPostRepository(db, log)
+		commentRepo = sqliteRepository.NewCommentRepository(db, log)
+		reactionRepo = sqliteRepository.NewReactionRepository(db, log)
+		attachmentRepo = sqliteRepository.NewAttachmentRepository(db, log)
+		// A single file can't be shared by instances, so presence events don't leave the process.
+		presenceRepo = inmemory.NewPresenceRepository(log)
 	} else {
 		log.Error("Unknown storage type", "type", cfg.Storage.Type)
 		return
 	}
+	// Persisted queries are kept in memory unless they are shared through the database.
+	if persistedQueryRepo == nil {
+		queries, err := inmemory.NewPersistedQueryRepository(int(cfg.GraphQL.APQCacheSize), log)
+		if err != nil {
+			log.Error("Failed to create persisted query repository", "error", err.Error())
+			return
+		}
+		persistedQueryRepo = queries
+
+		storeSizes = append(storeSizes, func() map[string]int {
+			return map[string]int{"persisted_queries": queries.Len()}
+		})
+	}
 	log.Info("Repositories created")
 
+	// Blob store
+	log.Info("Creating blob store", "storage", cfg.Attachment.Storage)
+
+	var blobs internal.BlobStore
+
+	if cfg.Attachment.Storage == "filesystem" {
+		log.Debug("Using filesystem blob store", "directory", cfg.Attachment.Directory)
+
+		fsBlobs, err := filesystem.NewBlobStore(cfg.Attachment.Directory, log)
+		if err != nil {
+			log.Error("Failed to create blob store", "error", err.Error())
+			return
+		}
+		blobs = fsBlobs
+	} else if cfg.Attachment.Storage == "s3" {
+		log.Debug("Using s3 blob store", "endpoint", cfg.S3.Endpoint, "bucket", cfg.S3.Bucket)
+
+		client, err := s3.New(cfg.S3.Endpoint, cfg.S3.Bucket,
+			s3.Region(cfg.S3.Region),
+			s3.Credentials(cfg.S3.AccessKey, cfg.S3.SecretKey))
+		if err != nil {
+			log.Error("Failed to create s3 client", "error", err.Error())
+			return
+		}
+		blobs = s3Repository.NewBlobStore(client, log)
+	} else {
+		log.Error("Unknown attachment storage", "storage", cfg.Attachment.Storage)
+		return
+	}
+	log.Info("Blob store created")
+
+	// Background work: subscription managers and the scheduler run until the context is cancelled.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if persistence != nil {
+		go persistence.Run(ctx, time.Duration(cfg.InMemory.SnapshotInterval)*time.Second)
+	}
+
 	// Services
 	log.Info("Creating services")
-	postService := service.NewPostService(postRepo, &cfg.Post, log)
-	commentService := service.NewCommentService(commentRepo, &cfg.Comment, log)
+	postService := service.NewPostService(ctx, postRepo, attachmentRepo, blobs, &cfg.Post, &cfg.Attachment, log)
+	commentService := service.NewCommentService(ctx, commentRepo, postRepo, presenceRepo, &cfg.Comment, &cfg.Presence, log)
+	reactionService := service.NewReactionService(ctx, reactionRepo, postRepo, commentRepo, &cfg.Reaction, log)
+	dumpService := service.NewDumpService(postRepo, commentRepo, log)
+	renderService, err := service.NewRenderService(&cfg.Render, log)
+	if err != nil {
+		log.Error("Failed to create render service", "error", err.Error())
+		return
+	}
 	log.Info("Services created")
 
-	// Router
-	var router http.Handler
-	log.Debug("Creating router", "environment", cfg.Environment)
-	if cfg.Environment == "development" {
-		router = graphql.NewRouter(log, true, commentService, postService)
-	} else {
-		router = graphql.NewRouter(log, false, commentService, postService)
+	registerSubscriptionMetrics(reg, postService, commentService, reactionService)
+	registerStoreMetrics(reg, storeSizes...)
 
+	probes.Add("subscriptions", func(ctx context.Context) error {
+		return errors.Join(postService.CheckSubscriptions(ctx), commentService.CheckSubscriptions(ctx),
+			reactionService.CheckSubscriptions(ctx))
+	})
+
+	// Scheduler
+	log.Debug("Starting scheduler", "interval", cfg.Post.SchedulerInterval)
+	go runScheduler(ctx, postService, time.Duration(cfg.Post.SchedulerInterval)*time.Second, log)
+
+	// Persisted query allowlist
+	var persistedQueries map[string]string
+	var persistedQueryNames []string
+	if cfg.GraphQL.PersistedQueries != "" {
+		persistedQueries, persistedQueryNames, err = graphql.LoadPersistedQueries(cfg.GraphQL.PersistedQueries)
+		if err != nil {
+			log.Error("Failed to load persisted queries", "error", err.Error())
+			return
+		}
+		log.Info("Persisted queries loaded, other queries are rejected", "queries", len(persistedQueries))
 	}
+
+	// Operations are labeled in metrics by known names only, so clients can't add series.
+	metrics := graphql.NewMetrics(reg, metricsNamespace, slices.Concat(cfg.GraphQL.MetricOperations, persistedQueryNames))
+
+	// Router
+	log.Debug("Creating router", "environment", cfg.Environment, "introspection", cfg.GraphQL.Introspection,
+		"playgroundPath", cfg.GraphQL.PlaygroundPath)
+	subscriptions := &graphql.Subscriptions{}
+	router := graphql.NewRouter(log, &cfg.GraphQL, commentService, postService, reactionService, renderService,
+		&cfg.Feed, &cfg.Post, &cfg.Comment, persistedQueryRepo, persistedQueries, metrics, probes,
+		subscriptions)
 	log.Debug("Router created")
 
 	// HTTP server
 	log.Debug("Creating http server", "port", cfg.HTTP.Port)
-	httpServer := httpserver.New(router, httpserver.Port(cfg.HTTP.Port))
+	httpServer := httpserver.New(router,
+		httpserver.Port(cfg.HTTP.Port),
+		httpserver.ShutdownTimeout(time.Duration(cfg.Shutdown.HTTPTimeout)*time.Second))
 	log.Info("HTTP server started", "port", cfg.HTTP.Port)
 
+	// gRPC server
+	log.Debug("Creating grpc server", "port", cfg.GRPC.Port)
+	grpcServer := grpcserver.New(grpc.NewServer(log, postService, commentService),
+		grpcserver.Port(cfg.GRPC.Port),
+		grpcserver.ShutdownTimeout(time.Duration(cfg.Shutdown.GRPCTimeout)*time.Second))
+	log.Info("gRPC server started", "port", cfg.GRPC.Port)
+
+	// Admin server
+	log.Debug("Creating admin server", "port", cfg.HTTP.AdminPort)
+	adminServer := httpserver.New(admin.NewRouter(log, reg, dumpService, cfg.HTTP.AdminToken),
+		httpserver.Port(cfg.HTTP.AdminPort),
+		httpserver.ShutdownTimeout(time.Duration(cfg.Shutdown.HTTPTimeout)*time.Second))
+	log.Info("Admin server started", "port", cfg.HTTP.AdminPort)
+
 	// Interrupt signal
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
@@ -97,14 +354,63 @@ func Run(cfg *config.Config) {
 		log.Info("Got interrupt signal", "signal", s.String())
 	case err := <-httpServer.Notify():
 		log.Error("Got error while serving http", "error", err.Error())
+	case err := <-grpcServer.Notify():
+		log.Error("Got error while serving grpc", "error", err.Error())
+	case err := <-adminServer.Notify():
+		log.Error("Got error while serving admin http", "error", err.Error())
 	}
 
 	// Shutdown
+	// Load balancers stop sending requests to the instance when the readiness probe fails.
+	probes.Shutdown()
+	if cfg.Shutdown.Delay > 0 {
+		log.Info("Waiting for load balancers to notice the shutdown", "delay", cfg.Shutdown.Delay)
+		time.Sleep(time.Duration(cfg.Shutdown.Delay) * time.Second)
+	}
+
+	// Subscribers get completions of their subscriptions, new subscriptions are rejected.
+	// Event streams and gRPC streams end, so the servers don't wait for them.
+	log.Info("Stopping subscriptions and scheduler")
+	cancel()
+
+	// Websocket connections are closed with the HTTP server, so completions must be sent before.
+	err = waitSubscriptions(subscriptions, time.Duration(cfg.Shutdown.HTTPTimeout)*time.Second)
+	if err != nil {
+		log.Error("Got error while waiting for subscriptions to complete", "error", err.Error())
+	} else {
+		log.Info("Subscriptions completed")
+	}
+
 	log.Info("Shutting down HTTP server")
-	err := httpServer.Shutdown()
+	err = httpServer.Shutdown()
 	if err != nil {
 		log.Error("Got error while shutting down http server", "error", err.Error())
 	} else {
 		log.Info("HTTP server stopped")
 	}
+
+	log.Info("Shutting down gRPC server")
+	err = grpcServer.Shutdown()
+	if err != nil {
+		log.Error("Got error while shutting down grpc server", "error", err.Error())
+	} else {
+		log.Info("gRPC server stopped")
+	}
+
+	// Metrics are served until the API servers stop.
+	log.Info("Shutting down admin server")
+	err = adminServer.Shutdown()
+	if err != nil {
+		log.Error("Got error while shutting down admin server", "error", err.Error())
+	} else {
+		log.Info("Admin server stopped")
+	}
+}
+
+// waitSubscriptions waits until all GraphQL subscriptions end or the timeout passes.
+func waitSubscriptions(subscriptions *graphql.Subscriptions, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return subscriptions.Wait(ctx)
 }
@@ -0,0 +1,90 @@
+package app
+
+import (
+	"github.com/oustrix/ozon_journal/internal/service"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// metricsNamespace prefixes names of all metrics of the application.
+const metricsNamespace = "journal"
+
+// newMetricsRegistry creates a registry with metrics of the runtime and the process.
+func newMetricsRegistry() *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return reg
+}
+
+// subscriptionSource is a service with subscription managers.
+type subscriptionSource interface {
+	SubscriptionStats() map[string]service.SubscriptionStats
+}
+
+// registerSubscriptionMetrics registers gauges of active subscriptions and queued events by kinds of subscriptions.
+func registerSubscriptionMetrics(reg prometheus.Registerer, sources ...subscriptionSource) {
+	stats := func(value func(service.SubscriptionStats) int) func() map[string]int {
+		return func() map[string]int {
+			values := make(map[string]int)
+			for _, source := range sources {
+				for kind, s := range source.SubscriptionStats() {
+					values[kind] = value(s)
+				}
+			}
+			return values
+		}
+	}
+
+	reg.MustRegister(
+		&labeledGauge{
+			desc: prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "subscriptions", "active"),
+				"Number of active subscriptions.", []string{"kind"}, nil),
+			values: stats(func(s service.SubscriptionStats) int { return s.Subscriptions }),
+		},
+		&labeledGauge{
+			desc: prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "subscriptions", "queued_events"),
+				"Number of events waiting to be broadcast to subscribers.", []string{"kind"}, nil),
+			values: stats(func(s service.SubscriptionStats) int { return s.Queued }),
+		},
+	)
+}
+
+// registerStoreMetrics registers a gauge of numbers of items in in-memory stores.
+// Every function returns sizes of stores by their names.
+func registerStoreMetrics(reg prometheus.Registerer, sizes ...func() map[string]int) {
+	reg.MustRegister(&labeledGauge{
+		desc: prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "inmemory", "items"),
+			"Number of items kept in memory.", []string{"store"}, nil),
+		values: func() map[string]int {
+			values := make(map[string]int)
+			for _, f := range sizes {
+				for store, n := range f() {
+					values[store] = n
+				}
+			}
+			return values
+		},
+	})
+}
+
+// labeledGauge is a gauge with a single label whose values are read on every scrape.
+type labeledGauge struct {
+	desc   *prometheus.Desc
+	values func() map[string]int
+}
+
+// Describe implements prometheus.Collector.
+func (g *labeledGauge) Describe(ch chan<- *prometheus.Desc) {
+	ch <- g.desc
+}
+
+// Collect implements prometheus.Collector.
+func (g *labeledGauge) Collect(ch chan<- prometheus.Metric) {
+	for label, value := range g.values() {
+		ch <- prometheus.MustNewConstMetric(g.desc, prometheus.GaugeValue, float64(value), label)
+	}
+}
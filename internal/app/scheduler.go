@@ -0,0 +1,32 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/oustrix/ozon_journal/internal"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+)
+
+// runScheduler publishes scheduled posts when their time comes until the context is done.
+func runScheduler(ctx context.Context, postService internal.PostService, interval time.Duration, log *logger.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			posts, err := postService.PublishDuePosts(ctx)
+			if err != nil {
+				log.Error("Failed to publish scheduled posts", "error", err.Error())
+				continue
+			}
+
+			if len(posts) > 0 {
+				log.Info("Scheduled posts published", "amount", len(posts))
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oustrix/ozon_journal/internal"
+	"github.com/oustrix/ozon_journal/internal/entity"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+)
+
+// schedulerPostService counts calls of PublishDuePosts. Other methods aren't implemented.
+type schedulerPostService struct {
+	internal.PostService
+	calls atomic.Int32
+	err   error
+}
+
+func (s *schedulerPostService) PublishDuePosts(context.Context) ([]entity.Post, error) {
+	s.calls.Add(1)
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	return []entity.Post{{ID: 1}}, nil
+}
+
+func TestRunScheduler(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "posts published"},
+		// Errors don't stop the scheduler, the posts are published on the next tick.
+		{name: "publishing fails", err: errors.New("storage is unavailable")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			postService := &schedulerPostService{err: tt.err}
+			ctx, cancel := context.WithCancel(context.Background())
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				runScheduler(ctx, postService, 10*time.Millisecond, logger.New("error"))
+			}()
+
+			deadline := time.After(time.Second)
+			for postService.calls.Load() < 3 {
+				select {
+				case <-deadline:
+					t.Fatalf("PublishDuePosts() is called %d times, want at least 3", postService.calls.Load())
+				case <-time.After(10 * time.Millisecond):
+				}
+			}
+
+			cancel()
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("the scheduler doesn't stop when the context is done")
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/oustrix/ozon_journal/internal"
+	"github.com/oustrix/ozon_journal/internal/entity"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// NewRouter creates a router of the admin server. It is served on a separate port, so the endpoints
+// aren't reachable by clients of the API. Endpoints changing the application or exporting its data require
+// the token, or requests from the loopback interface if the token is empty.
+func NewRouter(log *logger.Logger, gatherer prometheus.Gatherer, dump internal.DumpService, token string) http.Handler {
+	log = log.With("layer", "controller")
+
+	r := mux.NewRouter()
+
+	r.Handle("/metrics", promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{
+		ErrorLog: promhttpLogger{log: log},
+		// Metrics that were collected are served even if some collectors fail.
+		ErrorHandling: promhttp.ContinueOnError,
+	})).Methods("GET")
+
+	r.HandleFunc("/log/level", getLogLevel(log)).Methods("GET")
+	r.Handle("/log/level", authorized(token, setLogLevel(log))).Methods("PUT")
+
+	r.Handle("/dump", authorized(token, exportDump(log, dump))).Methods("GET")
+	r.Handle("/dump", authorized(token, importDump(log, dump))).Methods("POST")
+
+	return r
+}
+
+// logLevel is a body of requests and responses of the log level endpoint.
+type logLevel struct {
+	Level string `json:"level"`
+}
+
+// getLogLevel returns the current level of the application logger.
+func getLogLevel(log *logger.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, &logLevel{Level: log.Level()})
+	}
+}
+
+// setLogLevel changes the level of the application logger until the next restart.
+func setLogLevel(log *logger.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body logLevel
+		err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid body: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		previous := log.Level()
+
+		err = log.SetLevel(body.Level)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		log.Warn("log level changed", "from", previous, "to", log.Level())
+
+		writeJSON(w, http.StatusOK, &logLevel{Level: log.Level()})
+	}
+}
+
+// exportDump streams all posts and comments as a JSON Lines dump. The response is written
+// while the dump is read, so an error after the first line can only be logged.
+func exportDump(log *logger.Logger, dump internal.DumpService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// A dump of a big journal takes longer than the write timeout of the server.
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", `attachment; filename="journal.jsonl"`)
+
+		stats, err := dump.Export(r.Context(), w)
+		if err != nil {
+			log.ErrorContext(r.Context(), "failed to export dump", "error", err.Error())
+			return
+		}
+
+		log.InfoContext(r.Context(), "dump exported", "posts", stats.Posts, "comments", stats.Comments)
+	}
+}
+
+// importDump reads a JSON Lines dump from the body into the empty storage. With dry_run=true
+// the dump is only validated.
+func importDump(log *logger.Logger, dump internal.DumpService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dryRun := false
+		if value := r.URL.Query().Get("dry_run"); value != "" {
+			var err error
+			dryRun, err = strconv.ParseBool(value)
+			if err != nil {
+				http.Error(w, "dry_run must be a boolean", http.StatusBadRequest)
+				return
+			}
+		}
+
+		// A dump of a big journal takes longer than the read timeout of the server.
+		_ = http.NewResponseController(w).SetReadDeadline(time.Time{})
+
+		stats, err := dump.Import(r.Context(), r.Body, dryRun)
+		if err != nil {
+			importError(w, r, log, err)
+			return
+		}
+
+		if !dryRun {
+			log.WarnContext(r.Context(), "dump imported", "posts", stats.Posts, "comments", stats.Comments)
+		}
+
+		writeJSON(w, http.StatusOK, stats)
+	}
+}
+
+// importError reports errors of invalid dumps by their kinds. Other errors are failures of the storage
+// or of reading the body, so they are logged and reported as internal ones.
+func importError(w http.ResponseWriter, r *http.Request, log *logger.Logger, err error) {
+	switch {
+	case errors.Is(err, entity.ErrInvalidArgument):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, entity.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		log.ErrorContext(r.Context(), "failed to import dump", "error", err.Error())
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// authorized passes requests with the bearer token to the handler. If the token is empty,
+// requests from the loopback interface are passed only.
+func authorized(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			host, _, err := net.SplitHostPort(r.RemoteAddr)
+			if ip := net.ParseIP(host); err != nil || ip == nil || !ip.IsLoopback() {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// promhttpLogger passes errors of collecting metrics to the logger.
+type promhttpLogger struct {
+	log *logger.Logger
+}
+
+func (l promhttpLogger) Println(v ...interface{}) {
+	l.log.Error("failed to collect metrics", "error", fmt.Sprint(v...))
+}
@@ -0,0 +1,72 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/oustrix/ozon_journal/internal/entity"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// importDumpService fails imports with its error. Exports aren't implemented.
+type importDumpService struct {
+	err error
+}
+
+func (s *importDumpService) Export(context.Context, io.Writer) (*entity.DumpStats, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *importDumpService) Import(context.Context, io.Reader, bool) (*entity.DumpStats, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	return &entity.DumpStats{Posts: 1}, nil
+}
+
+func TestImportDumpStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		err      error
+		want     int
+		wantBody string
+	}{
+		{name: "imported", want: http.StatusOK},
+		{name: "invalid dry_run", query: "?dry_run=maybe", want: http.StatusBadRequest,
+			wantBody: "dry_run must be a boolean"},
+		{name: "invalid dump", err: fmt.Errorf("line 2: %w", entity.InvalidArgumentError("duplicate ID")),
+			want: http.StatusBadRequest, wantBody: "line 2: duplicate ID"},
+		{name: "missing entity", err: entity.NotFoundError("post not found"), want: http.StatusNotFound},
+		// Details of failures aren't revealed.
+		{name: "storage failure", err: errors.New("failed to import post 1: connection refused"),
+			want: http.StatusInternalServerError, wantBody: "internal server error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRouter(logger.New("error", logger.Output(io.Discard)), prometheus.NewRegistry(),
+				&importDumpService{err: tt.err}, "token")
+
+			req := httptest.NewRequest(http.MethodPost, "/dump"+tt.query, strings.NewReader(""))
+			req.Header.Set("Authorization", "Bearer token")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body = %q, want %q in it", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package graphql
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/oustrix/ozon_journal/internal"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+)
+
+// attachmentsPath is the path attachments are served from.
+const attachmentsPath = "/attachments"
+
+// attachmentHandler serves contents of attachments. Attachments of unpublished posts are served
+// to their authors only, who pass their ID in the viewerID query parameter.
+func attachmentHandler(postService internal.PostService, log *logger.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		// The route accepts digits only, so the conversion may fail on overflow only.
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		// If viewerID is not set or invalid, leave it 0 to indicate an anonymous viewer.
+		viewerID, _ := strconv.Atoi(r.URL.Query().Get("viewerID"))
+
+		attachment, content, err := postService.GetAttachment(ctx, id, viewerID)
+		if err != nil {
+			log.ErrorContext(
+				ctx,
+				"failed to get attachment",
+				"error", err.Error(),
+				"attachmentID", id,
+			)
+			http.NotFound(w, r)
+			return
+		}
+		defer content.Close()
+
+		w.Header().Set("Content-Type", attachment.ContentType)
+		w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": attachment.FileName}))
+		w.Header().Set("Cache-Control", "private, max-age=86400")
+		// Browsers must not guess another type, even if a file is crafted to look like a page.
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+
+		if r.Method == http.MethodHead {
+			return
+		}
+
+		_, err = io.Copy(w, content)
+		if err != nil {
+			log.ErrorContext(
+				ctx,
+				"failed to send attachment",
+				"error", err.Error(),
+				"attachmentID", id,
+			)
+		}
+	})
+}
@@ -0,0 +1,148 @@
+package graphql
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/oustrix/ozon_journal/config"
+	"github.com/oustrix/ozon_journal/internal"
+	"github.com/oustrix/ozon_journal/internal/entity"
+	"github.com/oustrix/ozon_journal/pkg/feed"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+)
+
+// Feed routes. Every feed is served as RSS and as Atom depending on the extension.
+const (
+	feedPath       = "/feed.{format:rss|atom}"
+	authorFeedPath = "/authors/{authorID:[0-9]+}/feed.{format:rss|atom}"
+)
+
+// feedVersion is the last served version of a feed.
+type feedVersion struct {
+	etag     string
+	modified time.Time
+}
+
+// feedHandler serves feeds of the latest published posts, of all authors or of one.
+//
+// Posts don't keep the time of their last edit, so the Last-Modified time of a feed is the time
+// its contents were first seen to change. Versions are kept in memory, so after a restart
+// the first request of every feed moves its Last-Modified time forward, and clients fetch it once again.
+func feedHandler(postService internal.PostService, renderService internal.RenderService, cfg *config.Feed,
+	log *logger.Logger) http.Handler {
+	// The size is validated by the config.
+	versions, _ := lru.New[string, feedVersion](int(cfg.CacheSize))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		vars := mux.Vars(r)
+
+		f := &feed.Feed{
+			Title:       cfg.Title,
+			Description: cfg.Description,
+			Link:        cfg.Link,
+			Self:        cfg.Link + r.URL.Path,
+		}
+
+		var posts *[]entity.Post
+		var err error
+		if vars["authorID"] != "" {
+			// The route accepts digits only, so the conversion may fail on overflow only.
+			var authorID int
+			authorID, err = strconv.Atoi(vars["authorID"])
+			if err != nil {
+				http.NotFound(w, r)
+				return
+			}
+
+			f.Title = fmt.Sprintf("%s: author %d", cfg.Title, authorID)
+			posts, err = postService.GetPostsByAuthorID(ctx, authorID, 1, int(cfg.Size))
+		} else {
+			posts, err = postService.GetPosts(ctx, 1, int(cfg.Size))
+		}
+		if err != nil {
+			log.ErrorContext(
+				ctx,
+				"failed to get posts for feed",
+				"error", err.Error(),
+				"path", r.URL.Path,
+			)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		for _, post := range *posts {
+			content, err := renderService.RenderPost(ctx, &post)
+			if err != nil {
+				log.ErrorContext(
+					ctx,
+					"failed to render post for feed",
+					"error", err.Error(),
+					"postID", post.ID,
+				)
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			published := time.Unix(int64(post.PublishedAt), 0)
+			if published.After(f.Updated) {
+				f.Updated = published
+			}
+
+			f.Items = append(f.Items, feed.Item{
+				// Slugs change with titles, so IDs are built of post IDs.
+				ID:        fmt.Sprintf("%s/posts/%d", cfg.Link, post.ID),
+				Title:     post.Title,
+				Link:      cfg.Link + "/posts/" + post.Slug,
+				Author:    fmt.Sprintf("User %d", post.AuthorID),
+				Content:   content,
+				Published: published,
+				Updated:   published,
+			})
+		}
+
+		var body []byte
+		if vars["format"] == "atom" {
+			w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+			body, err = f.Atom()
+		} else {
+			w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+			body, err = f.RSS()
+		}
+		if err != nil {
+			log.ErrorContext(
+				ctx,
+				"failed to encode feed",
+				"error", err.Error(),
+				"path", r.URL.Path,
+			)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		sum := sha256.Sum256(body)
+		version := feedVersion{
+			etag:     `"` + hex.EncodeToString(sum[:16]) + `"`,
+			modified: time.Now().Truncate(time.Second),
+		}
+		if last, ok := versions.Get(r.URL.Path); ok && last.etag == version.etag {
+			version.modified = last.modified
+		} else {
+			versions.Add(r.URL.Path, version)
+		}
+
+		w.Header().Set("ETag", version.etag)
+		w.Header().Set("Cache-Control", "public, max-age=60")
+
+		// ServeContent answers conditional requests with 304 Not Modified, If-None-Match takes precedence.
+		http.ServeContent(w, r, "", version.modified, bytes.NewReader(body))
+	})
+}
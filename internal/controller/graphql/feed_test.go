@@ -0,0 +1,131 @@
+package graphql
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/oustrix/ozon_journal/config"
+	"github.com/oustrix/ozon_journal/internal"
+	"github.com/oustrix/ozon_journal/internal/entity"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+)
+
+// feedPostService returns the same posts for every feed. Other methods aren't implemented.
+type feedPostService struct {
+	internal.PostService
+	posts []entity.Post
+}
+
+func (s *feedPostService) GetPosts(context.Context, int, int) (*[]entity.Post, error) {
+	posts := append([]entity.Post(nil), s.posts...)
+	return &posts, nil
+}
+
+func (s *feedPostService) GetPostsByAuthorID(_ context.Context, authorID int, _ int, _ int) (*[]entity.Post, error) {
+	posts := make([]entity.Post, 0)
+	for _, post := range s.posts {
+		if post.AuthorID == authorID {
+			posts = append(posts, post)
+		}
+	}
+
+	return &posts, nil
+}
+
+// feedRenderService returns content of posts as is.
+type feedRenderService struct {
+	internal.RenderService
+}
+
+func (feedRenderService) RenderPost(_ context.Context, post *entity.Post) (string, error) {
+	return post.Content, nil
+}
+
+func TestFeedConditionalGet(t *testing.T) {
+	postService := &feedPostService{posts: []entity.Post{
+		{ID: 1, Title: "First", Slug: "first", Content: "Content", AuthorID: 1, PublishedAt: 1700000000},
+	}}
+
+	r := mux.NewRouter()
+	feeds := feedHandler(postService, feedRenderService{}, &config.Feed{Title: "Journal", Link: "https://example.com",
+		Size: 10, CacheSize: 10}, logger.New("error"))
+	r.Handle(feedPath, feeds).Methods("GET", "HEAD")
+	r.Handle(authorFeedPath, feeds).Methods("GET", "HEAD")
+
+	get := func(path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := get("/feed.rss", nil)
+	if first.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", first.Code, http.StatusOK)
+	}
+	etag, modified := first.Header().Get("ETag"), first.Header().Get("Last-Modified")
+	if etag == "" || modified == "" {
+		t.Fatalf("ETag = %q, Last-Modified = %q, want both set", etag, modified)
+	}
+	modifiedAt, err := http.ParseTime(modified)
+	if err != nil {
+		t.Fatalf("invalid Last-Modified %q: %v", modified, err)
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		header http.Header
+		want   int
+	}{
+		{name: "no conditions", path: "/feed.rss", want: http.StatusOK},
+		{name: "same ETag", path: "/feed.rss", header: http.Header{"If-None-Match": {etag}}, want: http.StatusNotModified},
+		{name: "one of ETags", path: "/feed.rss", header: http.Header{"If-None-Match": {`"other", ` + etag}},
+			want: http.StatusNotModified},
+		{name: "other ETag", path: "/feed.rss", header: http.Header{"If-None-Match": {`"other"`}}, want: http.StatusOK},
+		{name: "not modified since", path: "/feed.rss", header: http.Header{"If-Modified-Since": {modified}},
+			want: http.StatusNotModified},
+		{name: "modified since", path: "/feed.rss",
+			header: http.Header{"If-Modified-Since": {modifiedAt.Add(-time.Hour).Format(http.TimeFormat)}}, want: http.StatusOK},
+		// If-None-Match takes precedence over If-Modified-Since.
+		{name: "other ETag and not modified since", path: "/feed.rss",
+			header: http.Header{"If-None-Match": {`"other"`}, "If-Modified-Since": {modified}}, want: http.StatusOK},
+		// Every feed has its own ETag.
+		{name: "ETag of another format", path: "/feed.atom", header: http.Header{"If-None-Match": {etag}}, want: http.StatusOK},
+		{name: "ETag of another feed", path: "/authors/1/feed.rss", header: http.Header{"If-None-Match": {etag}},
+			want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := get(tt.path, tt.header)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+
+	t.Run("changed feed", func(t *testing.T) {
+		postService.posts = append(postService.posts,
+			entity.Post{ID: 2, Title: "Second", Slug: "second", Content: "Content", AuthorID: 2, PublishedAt: 1700000100})
+
+		rec := get("/feed.rss", http.Header{"If-None-Match": {etag}})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+		}
+		if rec.Header().Get("ETag") == etag {
+			t.Error("ETag of the changed feed is the same")
+		}
+		if !strings.Contains(rec.Body.String(), "Second") {
+			t.Error("the changed feed doesn't contain the new post")
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package graphql
+
+import (
+	"context"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/oustrix/ozon_journal/config"
+	"github.com/oustrix/ozon_journal/internal/controller/graphql/generated"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// complexity weights lists by the number of items they may return, so asking for many posts
+// with their comments costs as much as all of those comments.
+func complexity(postCfg *config.Post, commentCfg *config.Comment) generated.ComplexityRoot {
+	var c generated.ComplexityRoot
+
+	c.Query.Posts = func(childComplexity int, page *int, amount *int) int {
+		return listComplexity(childComplexity, amount, postCfg.DefaultAmount)
+	}
+	c.Query.Drafts = func(childComplexity int, authorID int, page *int, amount *int) int {
+		return listComplexity(childComplexity, amount, postCfg.DefaultAmount)
+	}
+	c.Query.Comments = func(childComplexity int, postID int, page *int, amount *int, viewerID *int) int {
+		return listComplexity(childComplexity, amount, commentCfg.DefaultAmount)
+	}
+	c.Post.Comments = func(childComplexity int, page *int, amount *int) int {
+		return listComplexity(childComplexity, amount, commentCfg.DefaultAmount)
+	}
+
+	return c
+}
+
+// listComplexity returns the complexity of a page of amount items, or of defaultAmount items if amount isn't set.
+func listComplexity(childComplexity int, amount *int, defaultAmount uint) int {
+	n := int(defaultAmount)
+	if amount != nil && *amount >= 0 {
+		n = *amount
+	}
+
+	return 1 + childComplexity*n
+}
+
+// DepthLimit is an extension rejecting operations with selections nested deeper than the limit.
+// Introspection fields aren't counted, since introspection queries of tools are deep by design.
+type DepthLimit struct {
+	MaxDepth int
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+} = DepthLimit{}
+
+// ExtensionName returns the name of the extension.
+func (DepthLimit) ExtensionName() string {
+	return "DepthLimit"
+}
+
+// Validate does nothing, the extension doesn't depend on the schema.
+func (DepthLimit) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// MutateOperationContext rejects the operation if it is too deep.
+func (d DepthLimit) MutateOperationContext(ctx context.Context, rc *graphql.OperationContext) *gqlerror.Error {
+	depth := selectionDepth(rc.Operation.SelectionSet)
+	if depth > d.MaxDepth {
+		err := gqlerror.Errorf("operation has depth %d, which exceeds the limit of %d", depth, d.MaxDepth)
+		errcode.Set(err, "DEPTH_LIMIT_EXCEEDED")
+		return err
+	}
+
+	return nil
+}
+
+// selectionDepth returns the depth of the deepest field of a selection set. Fragments don't add to the depth.
+func selectionDepth(set ast.SelectionSet) int {
+	depth := 0
+
+	for _, selection := range set {
+		var d int
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(selection.Name, "__") {
+				continue
+			}
+			d = 1 + selectionDepth(selection.SelectionSet)
+		case *ast.InlineFragment:
+			d = selectionDepth(selection.SelectionSet)
+		case *ast.FragmentSpread:
+			// Validation resolves spreads and rejects cycles before the operation is executed.
+			if selection.Definition != nil {
+				d = selectionDepth(selection.Definition.SelectionSet)
+			}
+		}
+
+		if d > depth {
+			depth = d
+		}
+	}
+
+	return depth
+}
@@ -0,0 +1,62 @@
+package graphql
+
+import (
+	"testing"
+
+	"github.com/oustrix/ozon_journal/config"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+func TestComplexity(t *testing.T) {
+	c := complexity(&config.Post{DefaultAmount: 10}, &config.Comment{DefaultAmount: 20})
+	amount := func(n int) *int { return &n }
+
+	tests := []struct {
+		name string
+		got  int
+		want int
+	}{
+		{name: "posts by default", got: c.Query.Posts(3, nil, nil), want: 31},
+		{name: "page of posts", got: c.Query.Posts(3, nil, amount(5)), want: 16},
+		{name: "empty page of posts", got: c.Query.Posts(3, nil, amount(0)), want: 1},
+		{name: "negative amount is the default", got: c.Query.Posts(3, nil, amount(-1)), want: 31},
+		{name: "comments by default", got: c.Query.Comments(2, 1, nil, nil, nil), want: 41},
+		{name: "comments of a post", got: c.Post.Comments(2, nil, amount(100)), want: 201},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("complexity = %d, want %d", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectionDepth(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{name: "single field", query: "{ posts { id } }", want: 2},
+		{name: "nested comments", query: "{ posts { id comments { id author } } }", want: 3},
+		{name: "deepest branch", query: "{ post(id: 1) { comments { id } } posts { id } }", want: 3},
+		{name: "inline fragment", query: "{ posts { ... on Post { comments { id } } } }", want: 3},
+		{name: "introspection", query: "{ __schema { types { fields { type { name } } } } posts { id } }", want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parser.ParseQuery(&ast.Source{Input: tt.query})
+			if err != nil {
+				t.Fatalf("ParseQuery() error = %v", err)
+			}
+
+			if got := selectionDepth(doc.Operations[0].SelectionSet); got != tt.want {
+				t.Errorf("selectionDepth() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package graphql
+
+import (
+	"context"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// Metrics is an extension collecting Prometheus metrics of operations and resolvers.
+// Clients choose names of operations, so only known names label operations and others
+// are labeled "other". The number of series doesn't depend on clients.
+type Metrics struct {
+	names             map[string]bool
+	operations        *prometheus.CounterVec
+	operationErrors   *prometheus.CounterVec
+	operationDuration *prometheus.HistogramVec
+	fieldDuration     *prometheus.HistogramVec
+	fieldErrors       *prometheus.CounterVec
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationInterceptor
+	graphql.ResponseInterceptor
+	graphql.FieldInterceptor
+} = &Metrics{}
+
+// NewMetrics creates the extension labeling operations by the names and registers its metrics in the namespace.
+func NewMetrics(reg prometheus.Registerer, namespace string, names []string) *Metrics {
+	byOperation := []string{"operation", "type"}
+	byField := []string{"object", "field"}
+
+	m := &Metrics{
+		names: make(map[string]bool, len(names)),
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "graphql",
+			Name:      "operations_total",
+			Help:      "Number of executed operations.",
+		}, byOperation),
+		operationErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "graphql",
+			Name:      "operation_errors_total",
+			Help:      "Number of responses with errors, every event of a subscription is a response.",
+		}, byOperation),
+		operationDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "graphql",
+			Name:      "operation_duration_seconds",
+			Help:      "Time from the start of a query or a mutation to its response, including parsing and validation.",
+			Buckets:   prometheus.DefBuckets,
+		}, byOperation),
+		fieldDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "graphql",
+			Name:      "field_duration_seconds",
+			Help:      "Time spent in resolvers of fields. Fields without resolvers aren't measured.",
+			Buckets:   prometheus.DefBuckets,
+		}, byField),
+		fieldErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "graphql",
+			Name:      "field_errors_total",
+			Help:      "Number of errors returned by resolvers of fields.",
+		}, byField),
+	}
+
+	for _, name := range names {
+		m.names[name] = true
+	}
+
+	reg.MustRegister(m.operations, m.operationErrors, m.operationDuration, m.fieldDuration, m.fieldErrors)
+
+	return m
+}
+
+// ExtensionName returns the name of the extension.
+func (*Metrics) ExtensionName() string {
+	return "Metrics"
+}
+
+// Validate does nothing, the extension doesn't depend on the schema.
+func (*Metrics) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// InterceptOperation counts operations that passed validation.
+func (m *Metrics) InterceptOperation(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	m.operations.WithLabelValues(m.operationLabels(graphql.GetOperationContext(ctx))...).Inc()
+
+	return next(ctx)
+}
+
+// InterceptResponse measures queries and mutations and counts responses with errors, including invalid requests.
+// Subscriptions last as long as clients want, so their durations aren't measured.
+func (m *Metrics) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	resp := next(ctx)
+	if resp == nil {
+		// The end of a subscription.
+		return resp
+	}
+
+	// Requests failing parsing or validation have no operations.
+	if !graphql.HasOperationContext(ctx) || graphql.GetOperationContext(ctx).Operation == nil {
+		m.operationErrors.WithLabelValues("unknown", "unknown").Inc()
+		return resp
+	}
+
+	oc := graphql.GetOperationContext(ctx)
+	labels := m.operationLabels(oc)
+
+	if len(resp.Errors) > 0 {
+		m.operationErrors.WithLabelValues(labels...).Inc()
+	}
+
+	if oc.Operation.Operation != ast.Subscription {
+		m.operationDuration.WithLabelValues(labels...).Observe(time.Since(oc.Stats.OperationStart).Seconds())
+	}
+
+	return resp
+}
+
+// InterceptField measures resolvers. Fields read from models are skipped, they would only add overhead.
+func (m *Metrics) InterceptField(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if !fc.IsResolver {
+		return next(ctx)
+	}
+
+	start := time.Now()
+	res, err := next(ctx)
+
+	m.fieldDuration.WithLabelValues(fc.Object, fc.Field.Name).Observe(time.Since(start).Seconds())
+	if err != nil {
+		m.fieldErrors.WithLabelValues(fc.Object, fc.Field.Name).Inc()
+	}
+
+	return res, err
+}
+
+// operationLabels returns the name and the type of the operation. Anonymous operations share a name.
+func operationLabels(oc *graphql.OperationContext) []string {
+	name := oc.OperationName
+	if name == "" {
+		name = oc.Operation.Name
+	}
+	if name == "" {
+		name = "anonymous"
+	}
+
+	return []string{name, string(oc.Operation.Operation)}
+}
+
+// operationLabels returns the labels of the operation, replacing names that aren't known with "other".
+func (m *Metrics) operationLabels(oc *graphql.OperationContext) []string {
+	labels := operationLabels(oc)
+	if labels[0] != "anonymous" && !m.names[labels[0]] {
+		labels[0] = "other"
+	}
+
+	return labels
+}
@@ -2,21 +2,298 @@
 
 package model
 
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+type Attachment struct {
+	ID          int    `json:"id"`
+	PostID      int    `json:"postID"`
+	FileName    string `json:"fileName"`
+	ContentType string `json:"contentType"`
+	Size        int    `json:"size"`
+	UploaderID  int    `json:"uploaderID"`
+	CreatedAt   int    `json:"createdAt"`
+	URL         string `json:"url"`
+}
+
 type Comment struct {
-	ID              int    `json:"id"`
-	Content         string `json:"content"`
-	AuthorID        int    `json:"authorID"`
-	PostID          int    `json:"postID"`
-	PublishedAt     int    `json:"publishedAt"`
-	ParentCommentID int    `json:"parentCommentID"`
+	ID              int              `json:"id"`
+	Content         string           `json:"content"`
+	ContentFormat   ContentFormat    `json:"contentFormat"`
+	ContentHTML     string           `json:"contentHtml"`
+	AuthorID        int              `json:"authorID"`
+	PostID          int              `json:"postID"`
+	PublishedAt     int              `json:"publishedAt"`
+	ParentCommentID int              `json:"parentCommentID"`
+	Reactions       []*ReactionCount `json:"reactions"`
+}
+
+type DiffChunk struct {
+	Operation DiffOperation `json:"operation"`
+	Text      string        `json:"text"`
 }
 
 type Post struct {
-	ID          int        `json:"id"`
-	Title       string     `json:"title"`
-	Content     string     `json:"content"`
-	PublishedAt int        `json:"publishedAt"`
-	AuthorID    int        `json:"authorID"`
-	Commentable bool       `json:"commentable"`
-	Comments    []*Comment `json:"comments,omitempty"`
+	ID            int              `json:"id"`
+	Title         string           `json:"title"`
+	Slug          string           `json:"slug"`
+	Content       string           `json:"content"`
+	ContentFormat ContentFormat    `json:"contentFormat"`
+	ContentHTML   string           `json:"contentHtml"`
+	PublishedAt   int              `json:"publishedAt"`
+	AuthorID      int              `json:"authorID"`
+	Commentable   bool             `json:"commentable"`
+	Status        PostStatus       `json:"status"`
+	PublishAt     *int             `json:"publishAt,omitempty"`
+	Revision      int              `json:"revision"`
+	Revisions     []*PostRevision  `json:"revisions"`
+	Attachments   []*Attachment    `json:"attachments"`
+	Comments      []*Comment       `json:"comments,omitempty"`
+	Reactions     []*ReactionCount `json:"reactions"`
+}
+
+type PostRevision struct {
+	Version   int    `json:"version"`
+	Title     string `json:"title"`
+	Content   string `json:"content"`
+	EditorID  int    `json:"editorID"`
+	CreatedAt int    `json:"createdAt"`
+}
+
+type PostRevisionDiff struct {
+	PostID  int          `json:"postID"`
+	From    int          `json:"from"`
+	To      int          `json:"to"`
+	Title   []*DiffChunk `json:"title"`
+	Content []*DiffChunk `json:"content"`
+}
+
+type Presence struct {
+	PostID    int   `json:"postID"`
+	Viewers   int   `json:"viewers"`
+	ViewerIDs []int `json:"viewerIDs"`
+	TypingIDs []int `json:"typingIDs"`
+}
+
+type ReactionCount struct {
+	Emoji string `json:"emoji"`
+	Count int    `json:"count"`
+}
+
+type ReactionsChange struct {
+	TargetType ReactionTarget   `json:"targetType"`
+	TargetID   int              `json:"targetID"`
+	Reactions  []*ReactionCount `json:"reactions"`
+}
+
+type ContentFormat string
+
+const (
+	ContentFormatPlain    ContentFormat = "PLAIN"
+	ContentFormatMarkdown ContentFormat = "MARKDOWN"
+)
+
+var AllContentFormat = []ContentFormat{
+	ContentFormatPlain,
+	ContentFormatMarkdown,
+}
+
+func (e ContentFormat) IsValid() bool {
+	switch e {
+	case ContentFormatPlain, ContentFormatMarkdown:
+		return true
+	}
+	return false
+}
+
+func (e ContentFormat) String() string {
+	return string(e)
+}
+
+func (e *ContentFormat) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ContentFormat(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ContentFormat", str)
+	}
+	return nil
+}
+
+func (e ContentFormat) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type DiffMode string
+
+const (
+	DiffModeLine DiffMode = "LINE"
+	DiffModeWord DiffMode = "WORD"
+)
+
+var AllDiffMode = []DiffMode{
+	DiffModeLine,
+	DiffModeWord,
+}
+
+func (e DiffMode) IsValid() bool {
+	switch e {
+	case DiffModeLine, DiffModeWord:
+		return true
+	}
+	return false
+}
+
+func (e DiffMode) String() string {
+	return string(e)
+}
+
+func (e *DiffMode) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = DiffMode(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid DiffMode", str)
+	}
+	return nil
+}
+
+func (e DiffMode) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type DiffOperation string
+
+const (
+	DiffOperationEqual  DiffOperation = "EQUAL"
+	DiffOperationInsert DiffOperation = "INSERT"
+	DiffOperationDelete DiffOperation = "DELETE"
+)
+
+var AllDiffOperation = []DiffOperation{
+	DiffOperationEqual,
+	DiffOperationInsert,
+	DiffOperationDelete,
+}
+
+func (e DiffOperation) IsValid() bool {
+	switch e {
+	case DiffOperationEqual, DiffOperationInsert, DiffOperationDelete:
+		return true
+	}
+	return false
+}
+
+func (e DiffOperation) String() string {
+	return string(e)
+}
+
+func (e *DiffOperation) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = DiffOperation(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid DiffOperation", str)
+	}
+	return nil
+}
+
+func (e DiffOperation) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type PostStatus string
+
+const (
+	PostStatusDraft     PostStatus = "DRAFT"
+	PostStatusScheduled PostStatus = "SCHEDULED"
+	PostStatusPublished PostStatus = "PUBLISHED"
+)
+
+var AllPostStatus = []PostStatus{
+	PostStatusDraft,
+	PostStatusScheduled,
+	PostStatusPublished,
+}
+
+func (e PostStatus) IsValid() bool {
+	switch e {
+	case PostStatusDraft, PostStatusScheduled, PostStatusPublished:
+		return true
+	}
+	return false
+}
+
+func (e PostStatus) String() string {
+	return string(e)
+}
+
+func (e *PostStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = PostStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid PostStatus", str)
+	}
+	return nil
+}
+
+func (e PostStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ReactionTarget string
+
+const (
+	ReactionTargetPost    ReactionTarget = "POST"
+	ReactionTargetComment ReactionTarget = "COMMENT"
+)
+
+var AllReactionTarget = []ReactionTarget{
+	ReactionTargetPost,
+	ReactionTargetComment,
+}
+
+func (e ReactionTarget) IsValid() bool {
+	switch e {
+	case ReactionTargetPost, ReactionTargetComment:
+		return true
+	}
+	return false
+}
+
+func (e ReactionTarget) String() string {
+	return string(e)
+}
+
+func (e *ReactionTarget) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ReactionTarget(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ReactionTarget", str)
+	}
+	return nil
+}
+
+func (e ReactionTarget) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
 }
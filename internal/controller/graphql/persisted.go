@@ -0,0 +1,199 @@
+package graphql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/oustrix/ozon_journal/internal"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// pendingQueriesSize is the number of queries registered by clients that may wait for their validation at once.
+const pendingQueriesSize = 1000
+
+// persistedQueryCache is a graphql.Cache of automatic persisted queries kept in a repository.
+// The APQ extension adds queries before they are parsed, so they are kept pending and saved
+// by the cache as an extension only when they pass validation and limits. Clients can't fill
+// the repository with queries that are never executed.
+type persistedQueryCache struct {
+	repo    internal.PersistedQueryRepository
+	pending *lru.Cache[string, string] // by hashes
+	log     *logger.Logger
+}
+
+var _ interface {
+	graphql.Cache
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+} = &persistedQueryCache{}
+
+func newPersistedQueryCache(repo internal.PersistedQueryRepository, log *logger.Logger) *persistedQueryCache {
+	// The size is a positive constant, so creating the cache doesn't fail.
+	pending, _ := lru.New[string, string](pendingQueriesSize)
+
+	return &persistedQueryCache{repo: repo, pending: pending, log: log}
+}
+
+// Get returns a query by its hash. Failures of the repository are logged and reported as misses,
+// so clients fall back to sending full queries.
+func (c *persistedQueryCache) Get(ctx context.Context, hash string) (interface{}, bool) {
+	query, err := c.repo.GetPersistedQuery(ctx, hash)
+	if err != nil {
+		c.log.ErrorContext(
+			ctx,
+			"failed to get persisted query",
+			"error", err.Error(),
+			"hash", hash,
+		)
+		return nil, false
+	}
+
+	return query, query != ""
+}
+
+// Add keeps a query pending until it is validated. The APQ extension has checked that the hash matches the query.
+func (c *persistedQueryCache) Add(ctx context.Context, hash string, query interface{}) {
+	c.pending.Add(hash, query.(string))
+}
+
+// ExtensionName returns the name of the extension.
+func (c *persistedQueryCache) ExtensionName() string {
+	return "PersistedQueryCache"
+}
+
+// Validate does nothing, the extension doesn't depend on the schema.
+func (c *persistedQueryCache) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// MutateOperationContext saves the query of the operation if it is pending. Operations reach extensions
+// only after validation and extensions registered earlier, so the cache must be registered after limits.
+func (c *persistedQueryCache) MutateOperationContext(ctx context.Context, rc *graphql.OperationContext) *gqlerror.Error {
+	hash := queryHash(rc.RawQuery)
+
+	query, ok := c.pending.Peek(hash)
+	if !ok {
+		return nil
+	}
+	c.pending.Remove(hash)
+
+	err := c.repo.SavePersistedQuery(ctx, hash, query)
+	if err != nil {
+		c.log.ErrorContext(
+			ctx,
+			"failed to save persisted query",
+			"error", err.Error(),
+			"hash", hash,
+		)
+	}
+
+	return nil
+}
+
+// PersistedQueryAllowlist is an extension executing only queries registered in advance.
+// Clients send SHA-256 hashes of queries as automatic persisted queries do, full queries are accepted
+// if their hashes are registered. Queries can't be registered by clients.
+type PersistedQueryAllowlist struct {
+	Queries map[string]string // by hashes
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationParameterMutator
+} = PersistedQueryAllowlist{}
+
+// ExtensionName returns the name of the extension.
+func (PersistedQueryAllowlist) ExtensionName() string {
+	return "PersistedQueryAllowlist"
+}
+
+// Validate does nothing, the extension doesn't depend on the schema.
+func (PersistedQueryAllowlist) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// MutateOperationParameters replaces the hash of a query with the registered query.
+func (a PersistedQueryAllowlist) MutateOperationParameters(ctx context.Context, rawParams *graphql.RawParams) *gqlerror.Error {
+	var hash string
+	if extension, ok := rawParams.Extensions["persistedQuery"].(map[string]interface{}); ok {
+		hash, _ = extension["sha256Hash"].(string)
+	}
+
+	if hash == "" {
+		if rawParams.Query == "" {
+			return gqlerror.Errorf("query or its hash must be set")
+		}
+		hash = queryHash(rawParams.Query)
+	}
+
+	query, ok := a.Queries[hash]
+	if !ok {
+		err := gqlerror.Errorf("query isn't in the persisted query allowlist")
+		errcode.Set(err, "PERSISTED_QUERY_NOT_ALLOWED")
+		return err
+	}
+
+	if rawParams.Query != "" && rawParams.Query != query {
+		return gqlerror.Errorf("provided persisted query hash does not match query")
+	}
+
+	rawParams.Query = query
+
+	return nil
+}
+
+// persistedQueryManifest is a manifest of persisted queries in the format of Apollo tools.
+type persistedQueryManifest struct {
+	Format     string `json:"format"`
+	Version    int    `json:"version"`
+	Operations []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+		Body string `json:"body"`
+	} `json:"operations"`
+}
+
+// LoadPersistedQueries reads queries from a manifest file in the apollo-persisted-query-manifest format
+// and returns them by their hashes with names of their operations.
+// IDs of operations must be SHA-256 hashes of their bodies, as the Apollo tools make them by default.
+func LoadPersistedQueries(path string) (map[string]string, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var manifest persistedQueryManifest
+	err = json.Unmarshal(data, &manifest)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+
+	if manifest.Format != "apollo-persisted-query-manifest" || manifest.Version != 1 {
+		return nil, nil, fmt.Errorf("unsupported manifest format %q version %d", manifest.Format, manifest.Version)
+	}
+
+	queries := make(map[string]string, len(manifest.Operations))
+	names := make([]string, 0, len(manifest.Operations))
+	for _, op := range manifest.Operations {
+		if queryHash(op.Body) != op.ID {
+			return nil, nil, fmt.Errorf("ID of operation %q isn't a hash of its body", op.Name)
+		}
+		queries[op.ID] = op.Body
+		names = append(names, op.Name)
+	}
+
+	return queries, names, nil
+}
+
+func queryHash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
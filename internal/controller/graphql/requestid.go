@@ -0,0 +1,63 @@
+package graphql
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+	"github.com/oustrix/ozon_journal/pkg/requestid"
+)
+
+// RequestID is an extension logging operations and returning request IDs in extensions of responses.
+// Request IDs are added to contexts by requestid.Middleware, so operations sent over the same websocket
+// share the ID of the connection.
+type RequestID struct {
+	Log *logger.Logger
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationInterceptor
+	graphql.ResponseInterceptor
+} = RequestID{}
+
+// ExtensionName returns the name of the extension.
+func (RequestID) ExtensionName() string {
+	return "RequestID"
+}
+
+// Validate does nothing, the extension doesn't depend on the schema.
+func (RequestID) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// InterceptOperation logs the operation.
+func (e RequestID) InterceptOperation(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	labels := operationLabels(graphql.GetOperationContext(ctx))
+
+	e.Log.DebugContext(
+		ctx,
+		"received request",
+		"operation", labels[0],
+		"type", labels[1],
+	)
+
+	return next(ctx)
+}
+
+// InterceptResponse adds the request ID to extensions of the response.
+func (RequestID) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	resp := next(ctx)
+	if resp == nil {
+		return resp
+	}
+
+	if id := requestid.FromContext(ctx); id != "" {
+		if resp.Extensions == nil {
+			resp.Extensions = make(map[string]interface{})
+		}
+		resp.Extensions["requestID"] = id
+	}
+
+	return resp
+}
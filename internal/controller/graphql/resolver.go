@@ -1,7 +1,6 @@
 package graphql
 
 import (
-	"github.com/gofrs/uuid"
 	"github.com/oustrix/ozon_journal/internal"
 	"github.com/oustrix/ozon_journal/pkg/logger"
 )
@@ -11,8 +10,9 @@ import (
 // It serves as dependency injection for your app, add any dependencies you require here.
 
 type Resolver struct {
-	postService    internal.PostService
-	commentService internal.CommentService
-	log            *logger.Logger
-	gen            uuid.Generator
+	postService     internal.PostService
+	commentService  internal.CommentService
+	reactionService internal.ReactionService
+	renderService   internal.RenderService
+	log             *logger.Logger
 }
@@ -3,52 +3,152 @@ package graphql
 import (
 	"context"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/99designs/gqlgen/graphql"
 	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
 	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/99designs/gqlgen/graphql/playground"
-	"github.com/gofrs/uuid"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
+	"github.com/oustrix/ozon_journal/config"
 	"github.com/oustrix/ozon_journal/internal"
 	"github.com/oustrix/ozon_journal/internal/controller/graphql/generated"
+	"github.com/oustrix/ozon_journal/internal/controller/rest"
+	"github.com/oustrix/ozon_journal/pkg/health"
 	"github.com/oustrix/ozon_journal/pkg/logger"
+	"github.com/oustrix/ozon_journal/pkg/requestid"
+	"github.com/rs/cors"
 	"github.com/vektah/gqlparser/v2/gqlerror"
 )
 
 // NewRouter creates a new graphql router.
-func NewRouter(log *logger.Logger, isPlayground bool, commentService internal.CommentService, postService internal.PostService) http.Handler {
+func NewRouter(log *logger.Logger, cfg *config.GraphQL, commentService internal.CommentService,
+	postService internal.PostService, reactionService internal.ReactionService, renderService internal.RenderService,
+	feedCfg *config.Feed, postCfg *config.Post, commentCfg *config.Comment, persistedQueryRepo internal.PersistedQueryRepository,
+	persistedQueries map[string]string, metrics *Metrics, probes *health.Health,
+	subscriptions *Subscriptions) http.Handler {
+	log = log.With("layer", "controller")
+
 	// Setting up the GraphQL server handler.
-	srv := handler.NewDefaultServer(generated.NewExecutableSchema(generated.Config{Resolvers: &Resolver{
-		commentService: commentService,
-		postService:    postService,
-		log:            log,
-		gen:            uuid.NewGen(),
-	}}))
-	srv.AddTransport(transport.POST{})
+	srv := handler.New(generated.NewExecutableSchema(generated.Config{Resolvers: &Resolver{
+		commentService:  commentService,
+		postService:     postService,
+		reactionService: reactionService,
+		renderService:   renderService,
+		log:             log,
+	}, Complexity: complexity(postCfg, commentCfg)}))
+
+	// Order matters: the first transport that supports a request handles it,
+	// so event streams must be matched before plain GET and POST requests.
 	srv.AddTransport(transport.Websocket{
-		KeepAlivePingInterval: 10 * time.Second,
+		KeepAlivePingInterval: time.Duration(cfg.WebsocketKeepAlive) * time.Second,
+		PingPongInterval:      time.Duration(cfg.WebsocketPingPong) * time.Second,
 		Upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				return true
-			},
+			CheckOrigin:  checkOrigin(cfg.AllowedOrigins),
+			Subprotocols: []string{"graphql-transport-ws", "graphql-ws"},
 		},
 	})
+	srv.AddTransport(sseTransport{keepAlive: time.Duration(cfg.SSEKeepAlive) * time.Second})
+	srv.AddTransport(transport.Options{})
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.AddTransport(transport.MultipartForm{MaxUploadSize: int64(cfg.MaxUploadSize)})
+
+	srv.SetQueryCache(lru.New(1000))
+
+	srv.Use(metrics)
+	srv.Use(Tracing{})
+	srv.Use(RequestID{Log: log})
+	srv.Use(subscriptions)
+
+	if cfg.Introspection {
+		srv.Use(extension.Introspection{})
+	}
+
+	// Limits are checked before execution, so queries exceeding them don't reach services.
+	if cfg.ComplexityLimit > 0 {
+		srv.Use(extension.FixedComplexityLimit(int(cfg.ComplexityLimit)))
+	}
+	if cfg.MaxDepth > 0 {
+		srv.Use(DepthLimit{MaxDepth: int(cfg.MaxDepth)})
+	}
+
+	// With an allowlist, only queries of the manifest are executed, so clients can't register their own ones.
+	if persistedQueries != nil {
+		srv.Use(PersistedQueryAllowlist{Queries: persistedQueries})
+	} else {
+		cache := newPersistedQueryCache(persistedQueryRepo, log)
+		srv.Use(extension.AutomaticPersistedQuery{Cache: cache})
+		srv.Use(cache)
+	}
 
 	srv.SetErrorPresenter(func(ctx context.Context, e error) *gqlerror.Error {
-		log.Error("GraphQL error", "error", e.Error())
+		log.ErrorContext(ctx, "GraphQL error", "error", e.Error())
 		return graphql.DefaultErrorPresenter(ctx, e)
 	})
 
 	r := mux.NewRouter()
+	r.Use(traceMiddleware)
 
 	// Setting up routes.
-	if isPlayground {
-		r.Handle("/", playground.Handler("GraphQL playground", "/query")).Methods("GET")
+	r.Handle("/healthz", probes.LivenessHandler()).Methods("GET", "HEAD")
+	r.Handle("/readyz", probes.ReadinessHandler()).Methods("GET", "HEAD")
+	if cfg.PlaygroundPath != "" {
+		r.Handle(cfg.PlaygroundPath, playground.Handler("GraphQL playground", "/query")).Methods("GET")
 	}
-	r.Handle("/query", srv).Methods("POST", "POST")
+	r.Handle("/query", srv).Methods("GET", "POST", "OPTIONS")
+	r.Handle(attachmentsPath+"/{id:[0-9]+}", attachmentHandler(postService, log)).Methods("GET", "HEAD")
 
-	return r
+	feeds := feedHandler(postService, renderService, feedCfg, log)
+	r.Handle(feedPath, feeds).Methods("GET", "HEAD")
+	r.Handle(authorFeedPath, feeds).Methods("GET", "HEAD")
+
+	// REST API for clients that don't use GraphQL.
+	r.PathPrefix(rest.Prefix).Handler(rest.NewRouter(log, postService, commentService))
+
+	// Browsers may call the API from allowed origins only. Requests of other clients don't carry origins.
+	options := cors.Options{
+		AllowedOrigins: cfg.AllowedOrigins,
+		AllowedMethods: []string{http.MethodGet, http.MethodHead, http.MethodPost},
+		AllowedHeaders: []string{"*"},
+		ExposedHeaders: []string{requestid.Header},
+		MaxAge:         int(cfg.CORSMaxAge),
+	}
+	// The cors package allows any origin if none are listed, so no origins are denied explicitly.
+	if len(cfg.AllowedOrigins) == 0 {
+		options.AllowOriginFunc = func(string) bool { return false }
+	}
+	c := cors.New(options)
+
+	return c.Handler(requestid.Middleware(r))
+}
+
+// checkOrigin allows websocket upgrades from the same host as the API and from the allowed origins.
+// Upgrades without origins come from clients other than browsers, which aren't subject to cross-site requests.
+func checkOrigin(allowedOrigins []string) func(r *http.Request) bool {
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		allowed[strings.ToLower(origin)] = true
+	}
+
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" || allowed["*"] {
+			return true
+		} else if allowed[strings.ToLower(origin)] {
+			return true
+		}
+
+		u, err := url.Parse(origin)
+		if err != nil {
+			return false
+		}
+
+		return strings.EqualFold(u.Host, r.Host)
+	}
 }
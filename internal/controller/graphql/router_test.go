@@ -0,0 +1,39 @@
+package graphql
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		origin  string
+		want    bool
+	}{
+		{name: "no origin", want: true},
+		{name: "no origin with allowed origins", allowed: []string{"https://example.com"}, want: true},
+		{name: "same host", origin: "https://journal.example.com", want: true},
+		{name: "allowed origin", allowed: []string{"https://example.com"}, origin: "https://example.com", want: true},
+		{name: "allowed origin in another case", allowed: []string{"https://Example.com"}, origin: "https://example.COM",
+			want: true},
+		{name: "other origin", allowed: []string{"https://example.com"}, origin: "https://evil.com"},
+		{name: "any origin", allowed: []string{"*"}, origin: "https://evil.com", want: true},
+		{name: "invalid origin", origin: "://journal.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://journal.example.com/query", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+
+			if got := checkOrigin(tt.allowed)(r); got != tt.want {
+				t.Errorf("checkOrigin() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -9,58 +9,86 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/99designs/gqlgen/graphql"
 	"github.com/oustrix/ozon_journal/internal/controller/graphql/generated"
 	"github.com/oustrix/ozon_journal/internal/controller/graphql/model"
 	"github.com/oustrix/ozon_journal/internal/entity"
 )
 
-// CreatePost is the resolver for the createPost field.
-func (r *mutationResolver) CreatePost(ctx context.Context, title string, content string, authorID int, commentable bool) (*model.Post, error) {
-	start := time.Now()
+// ContentHTML is the resolver for the contentHtml field.
+func (r *commentResolver) ContentHTML(ctx context.Context, obj *model.Comment) (string, error) {
+	html, err := r.Resolver.renderService.RenderComment(ctx, &entity.Comment{
+		ID:            obj.ID,
+		Content:       obj.Content,
+		ContentFormat: contentFormatToEntity(&obj.ContentFormat),
+	})
+	if err != nil {
+		r.Resolver.log.ErrorContext(
+			ctx,
+			"failed to render comment",
+			"error", err.Error(),
+			"commentID", obj.ID,
+		)
+		return "", fmt.Errorf("failed to render comment: %w", err)
+	}
 
-	// Generate a new request ID.
-	reqID, err := r.Resolver.gen.NewV4()
+	return html, nil
+}
+
+// Reactions is the resolver for the reactions field.
+func (r *commentResolver) Reactions(ctx context.Context, obj *model.Comment) ([]*model.ReactionCount, error) {
+	counts, err := r.Resolver.reactionService.GetReactionCounts(ctx, entity.ReactionTargetComment, obj.ID)
 	if err != nil {
-		r.Resolver.log.Error(
-			"failed to generate request ID",
-			"layer", "controller",
+		r.Resolver.log.ErrorContext(
+			ctx,
+			"failed to get reactions",
 			"error", err.Error(),
-			"method", "CreatePost",
+			"commentID", obj.ID,
 		)
-		return nil, fmt.Errorf("failed to generate request ID: %w", err)
+		return nil, fmt.Errorf("failed to get reactions: %w", err)
 	}
 
-	// Add the request ID to the context.
-	ctx = context.WithValue(ctx, "requestID", reqID.String())
-	r.Resolver.log.Debug(
-		"received request",
-		"layer", "controller",
-		"method", "CreatePost",
-		"requestID", reqID.String(),
-	)
+	return reactionCountsToGraphQL(counts), nil
+}
+
+// CreatePost is the resolver for the createPost field.
+func (r *mutationResolver) CreatePost(ctx context.Context, title string, content string, authorID int, commentable bool, status *model.PostStatus, publishAt *int, contentFormat *model.ContentFormat) (*model.Post, error) {
+	start := time.Now()
 
 	post := &entity.Post{
-		Title:       title,
-		Content:     content,
-		AuthorID:    authorID,
-		Commentable: commentable,
+		Title:         title,
+		Content:       content,
+		ContentFormat: contentFormatToEntity(contentFormat),
+		AuthorID:      authorID,
+		Commentable:   commentable,
+	}
+
+	// If publishAt is set, the post is scheduled by default. Otherwise, it is published right away.
+	if publishAt != nil {
+		post.PublishAt = *publishAt
+		post.Status = entity.PostStatusScheduled
+	} else {
+		post.Status = entity.PostStatusPublished
 	}
 
-	post, err = r.Resolver.postService.CreatePost(ctx, post)
+	if status != nil {
+		post.Status = postStatusToEntity(*status)
+	}
+
+	post, err := r.Resolver.postService.CreatePost(ctx, post)
 	if err != nil {
-		r.Resolver.log.Error(
+		r.Resolver.log.ErrorContext(
+			ctx,
 			"failed to create post",
 			"error", err.Error(),
-			"requestID", reqID.String(),
 		)
 		return nil, fmt.Errorf("failed to create post: %w", err)
 
 	}
 
-	r.Resolver.log.Info(
+	r.Resolver.log.InfoContext(
+		ctx,
 		"post created",
-		"layer", "controller",
-		"requestID", reqID.String(),
 		"postID", post.ID,
 		"duration", time.Since(start).String(),
 	)
@@ -68,35 +96,134 @@ func (r *mutationResolver) CreatePost(ctx context.Context, title string, content
 	return postToGraphQL(post), nil
 }
 
-// AddComment is the resolver for the addComment field.
-func (r *mutationResolver) AddComment(ctx context.Context, postID int, content string, authorID int, parentCommentID *int) (*model.Comment, error) {
+// PublishPost is the resolver for the publishPost field.
+func (r *mutationResolver) PublishPost(ctx context.Context, id int, authorID int, publishAt *int) (*model.Post, error) {
+	start := time.Now()
+
+	// If publishAt is nil, set it to 0 to publish the post right away.
+	var publishTime int
+	if publishAt != nil {
+		publishTime = *publishAt
+	}
+
+	post, err := r.Resolver.postService.PublishPost(ctx, id, authorID, publishTime)
+	if err != nil {
+		r.Resolver.log.ErrorContext(
+			ctx,
+			"failed to publish post",
+			"error", err.Error(),
+			"postID", id,
+		)
+		return nil, fmt.Errorf("failed to publish post: %w", err)
+	}
+
+	r.Resolver.log.InfoContext(
+		ctx,
+		"post published",
+		"postID", post.ID,
+		"status", post.Status,
+		"duration", time.Since(start).String(),
+	)
+
+	return postToGraphQL(post), nil
+}
+
+// UpdatePost is the resolver for the updatePost field.
+func (r *mutationResolver) UpdatePost(ctx context.Context, id int, editorID int, title *string, content *string) (*model.Post, error) {
+	start := time.Now()
+
+	post, err := r.Resolver.postService.UpdatePost(ctx, id, editorID, title, content)
+	if err != nil {
+		r.Resolver.log.ErrorContext(
+			ctx,
+			"failed to update post",
+			"error", err.Error(),
+			"postID", id,
+		)
+		return nil, fmt.Errorf("failed to update post: %w", err)
+	}
+
+	r.Resolver.log.InfoContext(
+		ctx,
+		"post updated",
+		"postID", post.ID,
+		"revision", post.Revision,
+		"duration", time.Since(start).String(),
+	)
+
+	return postToGraphQL(post), nil
+}
+
+// RestorePostRevision is the resolver for the restorePostRevision field.
+func (r *mutationResolver) RestorePostRevision(ctx context.Context, postID int, version int, editorID int) (*model.Post, error) {
+	start := time.Now()
+
+	post, err := r.Resolver.postService.RestorePostRevision(ctx, postID, version, editorID)
+	if err != nil {
+		r.Resolver.log.ErrorContext(
+			ctx,
+			"failed to restore post revision",
+			"error", err.Error(),
+			"postID", postID,
+			"version", version,
+		)
+		return nil, fmt.Errorf("failed to restore post revision: %w", err)
+	}
+
+	r.Resolver.log.InfoContext(
+		ctx,
+		"post revision restored",
+		"postID", post.ID,
+		"version", version,
+		"revision", post.Revision,
+		"duration", time.Since(start).String(),
+	)
+
+	return postToGraphQL(post), nil
+}
+
+// AttachFile is the resolver for the attachFile field.
+func (r *mutationResolver) AttachFile(ctx context.Context, postID int, authorID int, file graphql.Upload) (*model.Attachment, error) {
 	start := time.Now()
 
-	// Generate a new request ID.
-	reqID, err := r.Resolver.gen.NewV4()
+	attachment, err := r.Resolver.postService.AttachFile(ctx, postID, authorID, &entity.Upload{
+		FileName:    file.Filename,
+		ContentType: file.ContentType,
+		Size:        file.Size,
+		Content:     file.File,
+	})
 	if err != nil {
-		r.Resolver.log.Error(
-			"failed to generate request ID",
-			"layer", "controller",
+		r.Resolver.log.ErrorContext(
+			ctx,
+			"failed to attach file",
 			"error", err.Error(),
-			"method", "AddComment",
+			"postID", postID,
 		)
-		return nil, fmt.Errorf("failed to generate request ID: %w", err)
+		return nil, fmt.Errorf("failed to attach file: %w", err)
 	}
 
-	// Add the request ID to the context.
-	ctx = context.WithValue(ctx, "requestID", reqID.String())
-	r.Resolver.log.Debug(
-		"received request",
-		"layer", "controller",
-		"method", "AddComment",
-		"requestID", reqID.String(),
+	r.Resolver.log.InfoContext(
+		ctx,
+		"file attached",
+		"postID", postID,
+		"attachmentID", attachment.ID,
+		"contentType", attachment.ContentType,
+		"size", attachment.Size,
+		"duration", time.Since(start).String(),
 	)
 
+	return attachmentToGraphQL(attachment), nil
+}
+
+// AddComment is the resolver for the addComment field.
+func (r *mutationResolver) AddComment(ctx context.Context, postID int, content string, authorID int, parentCommentID *int, contentFormat *model.ContentFormat) (*model.Comment, error) {
+	start := time.Now()
+
 	comment := &entity.Comment{
-		PostID:   postID,
-		Content:  content,
-		AuthorID: authorID,
+		PostID:        postID,
+		Content:       content,
+		ContentFormat: contentFormatToEntity(contentFormat),
+		AuthorID:      authorID,
 	}
 
 	// If parentCommentID is nil, set it to -1 to indicate that it is not set.
@@ -106,20 +233,19 @@ func (r *mutationResolver) AddComment(ctx context.Context, postID int, content s
 		comment.ParentCommentID = *parentCommentID
 	}
 
-	comment, err = r.Resolver.commentService.CreateComment(ctx, comment)
+	comment, err := r.Resolver.commentService.CreateComment(ctx, comment)
 	if err != nil {
-		r.Resolver.log.Error(
+		r.Resolver.log.ErrorContext(
+			ctx,
 			"failed to add comment",
 			"error", err.Error(),
-			"requestID", reqID.String(),
 		)
 		return nil, fmt.Errorf("failed to add comment: %w", err)
 	}
 
-	r.Resolver.log.Info(
+	r.Resolver.log.InfoContext(
+		ctx,
 		"comment added",
-		"layer", "controller",
-		"requestID", reqID.String(),
 		"commentID", comment.ID,
 		"duration", time.Since(start).String(),
 	)
@@ -127,31 +253,210 @@ func (r *mutationResolver) AddComment(ctx context.Context, postID int, content s
 	return commentToGraphQL(comment), nil
 }
 
-// Posts is the resolver for the posts field.
-func (r *queryResolver) Posts(ctx context.Context, page *int, amount *int) ([]*model.Post, error) {
+// SetTyping is the resolver for the setTyping field.
+func (r *mutationResolver) SetTyping(ctx context.Context, postID int, userID int) (bool, error) {
 	start := time.Now()
 
-	// Generate a new request ID.
-	reqID, err := r.Resolver.gen.NewV4()
+	err := r.Resolver.commentService.SetTyping(ctx, postID, userID)
 	if err != nil {
-		r.Resolver.log.Error(
-			"failed to generate request ID",
-			"layer", "controller",
+		r.Resolver.log.ErrorContext(
+			ctx,
+			"failed to set typing",
 			"error", err.Error(),
-			"method", "Posts",
 		)
-		return nil, fmt.Errorf("failed to generate request ID: %w", err)
+		return false, fmt.Errorf("failed to set typing: %w", err)
 	}
 
-	// Add the request ID to the context.
-	ctx = context.WithValue(ctx, "requestID", reqID.String())
-	r.Resolver.log.Debug(
-		"received request",
-		"layer", "controller",
-		"method", "Posts",
-		"requestID", reqID.String(),
+	r.Resolver.log.InfoContext(
+		ctx,
+		"typing set",
+		"postID", postID,
+		"userID", userID,
+		"duration", time.Since(start).String(),
 	)
 
+	return true, nil
+}
+
+// React is the resolver for the react field.
+func (r *mutationResolver) React(ctx context.Context, targetType model.ReactionTarget, targetID int, userID int, emoji string) ([]*model.ReactionCount, error) {
+	start := time.Now()
+
+	reaction := &entity.Reaction{
+		TargetType: reactionTargetToEntity(targetType),
+		TargetID:   targetID,
+		UserID:     userID,
+		Emoji:      emoji,
+	}
+
+	counts, err := r.Resolver.reactionService.React(ctx, reaction)
+	if err != nil {
+		r.Resolver.log.ErrorContext(
+			ctx,
+			"failed to react",
+			"error", err.Error(),
+		)
+		return nil, fmt.Errorf("failed to react: %w", err)
+	}
+
+	r.Resolver.log.InfoContext(
+		ctx,
+		"reaction added",
+		"targetType", reaction.TargetType,
+		"targetID", targetID,
+		"duration", time.Since(start).String(),
+	)
+
+	return reactionCountsToGraphQL(counts), nil
+}
+
+// Unreact is the resolver for the unreact field.
+func (r *mutationResolver) Unreact(ctx context.Context, targetType model.ReactionTarget, targetID int, userID int, emoji string) ([]*model.ReactionCount, error) {
+	start := time.Now()
+
+	reaction := &entity.Reaction{
+		TargetType: reactionTargetToEntity(targetType),
+		TargetID:   targetID,
+		UserID:     userID,
+		Emoji:      emoji,
+	}
+
+	counts, err := r.Resolver.reactionService.Unreact(ctx, reaction)
+	if err != nil {
+		r.Resolver.log.ErrorContext(
+			ctx,
+			"failed to unreact",
+			"error", err.Error(),
+		)
+		return nil, fmt.Errorf("failed to unreact: %w", err)
+	}
+
+	r.Resolver.log.InfoContext(
+		ctx,
+		"reaction removed",
+		"targetType", reaction.TargetType,
+		"targetID", targetID,
+		"duration", time.Since(start).String(),
+	)
+
+	return reactionCountsToGraphQL(counts), nil
+}
+
+// ContentHTML is the resolver for the contentHtml field.
+func (r *postResolver) ContentHTML(ctx context.Context, obj *model.Post) (string, error) {
+	html, err := r.Resolver.renderService.RenderPost(ctx, &entity.Post{
+		ID:            obj.ID,
+		Content:       obj.Content,
+		ContentFormat: contentFormatToEntity(&obj.ContentFormat),
+		Revision:      obj.Revision,
+	})
+	if err != nil {
+		r.Resolver.log.ErrorContext(
+			ctx,
+			"failed to render post",
+			"error", err.Error(),
+			"postID", obj.ID,
+		)
+		return "", fmt.Errorf("failed to render post: %w", err)
+	}
+
+	return html, nil
+}
+
+// Revisions is the resolver for the revisions field.
+func (r *postResolver) Revisions(ctx context.Context, obj *model.Post) ([]*model.PostRevision, error) {
+	revisions, err := r.Resolver.postService.GetPostRevisions(ctx, obj.ID)
+	if err != nil {
+		r.Resolver.log.ErrorContext(
+			ctx,
+			"failed to get post revisions",
+			"error", err.Error(),
+			"postID", obj.ID,
+		)
+		return nil, fmt.Errorf("failed to get post revisions: %w", err)
+	}
+
+	return postRevisionsToGraphQL(revisions), nil
+}
+
+// Attachments is the resolver for the attachments field.
+func (r *postResolver) Attachments(ctx context.Context, obj *model.Post) ([]*model.Attachment, error) {
+	attachments, err := r.Resolver.postService.GetAttachments(ctx, obj.ID)
+	if err != nil {
+		r.Resolver.log.ErrorContext(
+			ctx,
+			"failed to get attachments",
+			"error", err.Error(),
+			"postID", obj.ID,
+		)
+		return nil, fmt.Errorf("failed to get attachments: %w", err)
+	}
+
+	result := make([]*model.Attachment, 0, len(attachments))
+	for _, attachment := range attachments {
+		result = append(result, attachmentToGraphQL(&attachment))
+	}
+
+	return result, nil
+}
+
+// Comments is the resolver for the comments field.
+func (r *postResolver) Comments(ctx context.Context, obj *model.Post, page *int, amount *int) ([]*model.Comment, error) {
+	// If page or amount is nil, set them to -1 to indicate that they are not set.
+	var pageNumber, amountCount int
+	if page == nil || *page < 0 {
+		pageNumber = -1
+	} else {
+		pageNumber = *page
+	}
+
+	if amount == nil || *amount < 0 {
+		amountCount = -1
+	} else {
+		amountCount = *amount
+	}
+
+	// The post was checked to be visible to the viewer when it was resolved,
+	// so comments are listed on behalf of its author, who can always see it.
+	comments, err := r.Resolver.commentService.GetCommentsByPostID(ctx, obj.ID, obj.AuthorID, pageNumber, amountCount)
+	if err != nil {
+		r.Resolver.log.ErrorContext(
+			ctx,
+			"failed to get comments of post",
+			"error", err.Error(),
+			"postID", obj.ID,
+		)
+		return nil, fmt.Errorf("failed to get comments: %w", err)
+	}
+
+	graphQLComments := make([]*model.Comment, 0, len(*comments))
+	for _, comment := range *comments {
+		graphQLComments = append(graphQLComments, commentToGraphQL(&comment))
+	}
+
+	return graphQLComments, nil
+}
+
+// Reactions is the resolver for the reactions field.
+func (r *postResolver) Reactions(ctx context.Context, obj *model.Post) ([]*model.ReactionCount, error) {
+	counts, err := r.Resolver.reactionService.GetReactionCounts(ctx, entity.ReactionTargetPost, obj.ID)
+	if err != nil {
+		r.Resolver.log.ErrorContext(
+			ctx,
+			"failed to get reactions",
+			"error", err.Error(),
+			"postID", obj.ID,
+		)
+		return nil, fmt.Errorf("failed to get reactions: %w", err)
+	}
+
+	return reactionCountsToGraphQL(counts), nil
+}
+
+// Posts is the resolver for the posts field.
+func (r *queryResolver) Posts(ctx context.Context, page *int, amount *int) ([]*model.Post, error) {
+	start := time.Now()
+
 	// If page or amount is nil, set them to -1 to indicate that they are not set.
 	var pageNumber, amountCount int
 	if page == nil {
@@ -168,10 +473,10 @@ func (r *queryResolver) Posts(ctx context.Context, page *int, amount *int) ([]*m
 
 	posts, err := r.Resolver.postService.GetPosts(ctx, pageNumber, amountCount)
 	if err != nil {
-		r.Resolver.log.Error(
+		r.Resolver.log.ErrorContext(
+			ctx,
 			"failed to get posts",
 			"error", err.Error(),
-			"requestID", reqID.String(),
 		)
 		return nil, fmt.Errorf("failed to get posts: %w", err)
 	}
@@ -182,11 +487,10 @@ func (r *queryResolver) Posts(ctx context.Context, page *int, amount *int) ([]*m
 		graphQLPosts = append(graphQLPosts, postToGraphQL(&post))
 	}
 
-	r.Resolver.log.Info(
+	r.Resolver.log.InfoContext(
+		ctx,
 		"posts retrieved",
-		"layer", "controller",
 		"amount", len(graphQLPosts),
-		"requestID", reqID.String(),
 		"duration", time.Since(start).String(),
 	)
 
@@ -194,77 +498,146 @@ func (r *queryResolver) Posts(ctx context.Context, page *int, amount *int) ([]*m
 }
 
 // Post is the resolver for the post field.
-func (r *queryResolver) Post(ctx context.Context, id int) (*model.Post, error) {
+func (r *queryResolver) Post(ctx context.Context, id *int, slug *string, viewerID *int) (*model.Post, error) {
 	start := time.Now()
 
-	// Generate a new request ID.
-	reqID, err := r.Resolver.gen.NewV4()
-	if err != nil {
-		r.Resolver.log.Error(
-			"failed to generate request ID",
-			"layer", "controller",
-			"error", err.Error(),
-			"request", "Post",
+	// If viewerID is nil, set it to 0 to indicate an anonymous viewer.
+	var viewer int
+	if viewerID != nil {
+		viewer = *viewerID
+	}
+
+	// A post is looked up either by its ID or by its current or earlier slug.
+	if (id == nil) == (slug == nil) {
+		r.Resolver.log.ErrorContext(
+			ctx,
+			"invalid post lookup",
 		)
-		return nil, fmt.Errorf("failed to generate request ID: %w", err)
+		return nil, fmt.Errorf("exactly one of id and slug must be set")
+	}
+
+	var post *entity.Post
+	var err error
+	if id != nil {
+		post, err = r.Resolver.postService.GetPostByID(ctx, *id, viewer)
+		if err != nil {
+			r.Resolver.log.ErrorContext(
+				ctx,
+				"failed to get post by id",
+				"error", err.Error(),
+				"postID", *id,
+			)
+			return nil, fmt.Errorf("failed to get post by id: %w", err)
+		}
+	} else {
+		post, err = r.Resolver.postService.GetPostBySlug(ctx, *slug, viewer)
+		if err != nil {
+			r.Resolver.log.ErrorContext(
+				ctx,
+				"failed to get post by slug",
+				"error", err.Error(),
+				"slug", *slug,
+			)
+			return nil, fmt.Errorf("failed to get post by slug: %w", err)
+		}
 	}
 
-	// Add the request ID to the context.
-	ctx = context.WithValue(ctx, "requestID", reqID.String())
-	r.Resolver.log.Debug(
-		"received request",
-		"layer", "controller",
-		"method", "Post",
-		"requestID", reqID.String(),
+	r.Resolver.log.InfoContext(
+		ctx,
+		"post retrieved",
+		"postID", post.ID,
+		"duration", time.Since(start).String(),
 	)
 
-	post, err := r.Resolver.postService.GetPostByID(ctx, id)
+	return postToGraphQL(post), nil
+}
+
+// Drafts is the resolver for the drafts field.
+func (r *queryResolver) Drafts(ctx context.Context, authorID int, page *int, amount *int) ([]*model.Post, error) {
+	start := time.Now()
+
+	// If page or amount is nil, set them to -1 to indicate that they are not set.
+	var pageNumber, amountCount int
+	if page == nil {
+		pageNumber = -1
+	} else {
+		pageNumber = *page
+	}
+
+	if amount == nil {
+		amountCount = -1
+	} else {
+		amountCount = *amount
+	}
+
+	posts, err := r.Resolver.postService.GetUnpublishedPosts(ctx, authorID, pageNumber, amountCount)
 	if err != nil {
-		r.Resolver.log.Error(
-			"failed to get post by id",
+		r.Resolver.log.ErrorContext(
+			ctx,
+			"failed to get drafts",
 			"error", err.Error(),
-			"postID", id,
-			"requestID", reqID.String(),
 		)
-		return nil, fmt.Errorf("failed to get post by id: %w", err)
+		return nil, fmt.Errorf("failed to get drafts: %w", err)
 	}
 
-	r.Resolver.log.Info(
-		"post retrieved",
-		"postID", post.ID,
-		"layer", "controller",
-		"requestID", reqID.String(),
+	// Convert the slice of entity.Post to a slice of model.Post.
+	graphQLPosts := make([]*model.Post, 0, len(*posts))
+	for _, post := range *posts {
+		graphQLPosts = append(graphQLPosts, postToGraphQL(&post))
+	}
+
+	r.Resolver.log.InfoContext(
+		ctx,
+		"drafts retrieved",
+		"amount", len(graphQLPosts),
 		"duration", time.Since(start).String(),
 	)
 
-	return postToGraphQL(post), nil
+	return graphQLPosts, nil
 }
 
-// Comments is the resolver for the comments field.
-func (r *queryResolver) Comments(ctx context.Context, postID int, page *int, amount *int) ([]*model.Comment, error) {
+// PostRevisionDiff is the resolver for the postRevisionDiff field.
+func (r *queryResolver) PostRevisionDiff(ctx context.Context, postID int, from int, to int, mode *model.DiffMode, viewerID *int) (*model.PostRevisionDiff, error) {
 	start := time.Now()
 
-	// Generate a new request ID.
-	reqID, err := r.Resolver.gen.NewV4()
+	// If viewerID is nil, set it to 0 to indicate an anonymous viewer.
+	var viewer int
+	if viewerID != nil {
+		viewer = *viewerID
+	}
+
+	diffMode := entity.DiffModeLine
+	if mode != nil {
+		diffMode = diffModeToEntity(*mode)
+	}
+
+	diff, err := r.Resolver.postService.DiffPostRevisions(ctx, postID, from, to, diffMode, viewer)
 	if err != nil {
-		r.Resolver.log.Error(
-			"failed to generate request ID",
-			"layer", "controller",
+		r.Resolver.log.ErrorContext(
+			ctx,
+			"failed to diff post revisions",
 			"error", err.Error(),
-			"method", "Comments",
+			"postID", postID,
 		)
-		return nil, fmt.Errorf("failed to generate request ID: %w", err)
+		return nil, fmt.Errorf("failed to diff post revisions: %w", err)
 	}
 
-	// Add the request ID to the context.
-	ctx = context.WithValue(ctx, "requestID", reqID.String())
-	r.Resolver.log.Debug(
-		"received request",
-		"layer", "controller",
-		"method", "Comments",
-		"requestID", reqID.String(),
+	r.Resolver.log.InfoContext(
+		ctx,
+		"post revisions diffed",
+		"postID", postID,
+		"from", from,
+		"to", to,
+		"duration", time.Since(start).String(),
 	)
 
+	return postRevisionDiffToGraphQL(diff), nil
+}
+
+// Comments is the resolver for the comments field.
+func (r *queryResolver) Comments(ctx context.Context, postID int, page *int, amount *int, viewerID *int) ([]*model.Comment, error) {
+	start := time.Now()
+
 	// If page or amount is nil, set them to -1 to indicate that they are not set.
 	var pageNumber, amountCount int
 	if page == nil || *page < 0 {
@@ -279,12 +652,18 @@ func (r *queryResolver) Comments(ctx context.Context, postID int, page *int, amo
 		amountCount = *amount
 	}
 
-	comments, err := r.Resolver.commentService.GetCommentsByPostID(ctx, postID, pageNumber, amountCount)
+	// If viewerID is nil, set it to 0 to indicate an anonymous viewer.
+	var viewer int
+	if viewerID != nil {
+		viewer = *viewerID
+	}
+
+	comments, err := r.Resolver.commentService.GetCommentsByPostID(ctx, postID, viewer, pageNumber, amountCount)
 	if err != nil {
-		r.Resolver.log.Error(
+		r.Resolver.log.ErrorContext(
+			ctx,
 			"failed to get comments",
 			"error", err.Error(),
-			"requestID", reqID.String(),
 		)
 		return nil, fmt.Errorf("failed to get comments: %w", err)
 	}
@@ -295,48 +674,75 @@ func (r *queryResolver) Comments(ctx context.Context, postID int, page *int, amo
 		graphQLComments = append(graphQLComments, commentToGraphQL(&comment))
 	}
 
-	r.Resolver.log.Info(
+	r.Resolver.log.InfoContext(
+		ctx,
 		"comments retrieved",
-		"layer", "controller",
 		"amount", len(graphQLComments),
-		"requestID", reqID.String(),
 		"duration", time.Since(start).String(),
 	)
 
 	return graphQLComments, nil
 }
 
-// CommentAdded is the resolver for the commentAdded field.
-func (r *subscriptionResolver) CommentAdded(ctx context.Context, postID int) (<-chan *model.Comment, error) {
+// PostAdded is the resolver for the postAdded field.
+func (r *subscriptionResolver) PostAdded(ctx context.Context) (<-chan *model.Post, error) {
 	start := time.Now()
 
-	// Generate a new request ID.
-	reqID, err := r.Resolver.gen.NewV4()
+	ch, subID, err := r.Resolver.postService.SubscribePosts(ctx)
 	if err != nil {
-		r.Resolver.log.Error(
-			"failed to generate request ID",
-			"layer", "controller",
+		r.Resolver.log.ErrorContext(
+			ctx,
+			"failed to subscribe to posts",
 			"error", err.Error(),
-			"method", "CommentAdded",
 		)
-		return nil, fmt.Errorf("failed to generate request ID: %w", err)
+		return nil, fmt.Errorf("failed to subscribe to posts: %w", err)
 	}
 
-	// Add the request ID to the context.
-	ctx = context.WithValue(ctx, "requestID", reqID.String())
-	r.Resolver.log.Debug(
-		"received request",
-		"layer", "controller",
-		"method", "CommentAdded",
-		"requestID", reqID.String(),
+	// Unsubscribe from posts when the context is done.
+	go func() {
+		<-ctx.Done()
+		r.log.InfoContext(
+			ctx,
+			"Unsubscribe signal received",
+			"SubscriptionID", subID,
+		)
+
+		r.Resolver.postService.UnsubscribePosts(ctx, subID)
+	}()
+
+	// Convert the channel of entity.Post to a channel of model.Post.
+	postCh := make(chan *model.Post)
+	go func() {
+		for post := range ch {
+			// The client may be gone, so sending stops after the context is done until the subscription is closed.
+			select {
+			case postCh <- postToGraphQL(post):
+			case <-ctx.Done():
+			}
+		}
+
+		close(postCh)
+	}()
+
+	r.Resolver.log.InfoContext(
+		ctx,
+		"subscribed to posts",
+		"duration", time.Since(start).String(),
 	)
 
-	ch, subID, err := r.Resolver.commentService.SubscribeComments(ctx, postID)
+	return postCh, nil
+}
+
+// CommentAdded is the resolver for the commentAdded field.
+func (r *subscriptionResolver) CommentAdded(ctx context.Context, postIDs []int) (<-chan *model.Comment, error) {
+	start := time.Now()
+
+	ch, subID, err := r.Resolver.commentService.SubscribeComments(ctx, postIDs)
 	if err != nil {
-		r.Resolver.log.Error(
+		r.Resolver.log.ErrorContext(
+			ctx,
 			"failed to subscribe to comments",
 			"error", err.Error(),
-			"requestID", reqID.String(),
 		)
 		return nil, fmt.Errorf("failed to subscribe to comments: %w", err)
 	}
@@ -344,11 +750,10 @@ func (r *subscriptionResolver) CommentAdded(ctx context.Context, postID int) (<-
 	// Unsubscribe from comments when the context is done.
 	go func() {
 		<-ctx.Done()
-		r.log.Info(
+		r.log.InfoContext(
+			ctx,
 			"Unsubscribe signal received",
-			"layer", "controller",
 			"SubscriptionID", subID,
-			"RequestID", reqID.String(),
 		)
 
 		r.Resolver.commentService.UnsubscribeComments(ctx, subID)
@@ -358,32 +763,202 @@ func (r *subscriptionResolver) CommentAdded(ctx context.Context, postID int) (<-
 	commentCh := make(chan *model.Comment)
 	go func() {
 		for comment := range ch {
-			commentCh <- commentToGraphQL(comment)
+			// The client may be gone, so sending stops after the context is done until the subscription is closed.
+			select {
+			case commentCh <- commentToGraphQL(comment):
+			case <-ctx.Done():
+			}
 		}
 
 		close(commentCh)
 	}()
 
-	r.Resolver.log.Info(
+	r.Resolver.log.InfoContext(
+		ctx,
 		"subscribed to comments",
-		"layer", "controller",
-		"postID", postID,
-		"requestID", reqID.String(),
+		"postIDs", postIDs,
+		"duration", time.Since(start).String(),
+	)
+
+	return commentCh, nil
+}
+
+// RepliesAdded is the resolver for the repliesAdded field.
+func (r *subscriptionResolver) RepliesAdded(ctx context.Context, commentID int) (<-chan *model.Comment, error) {
+	start := time.Now()
+
+	ch, subID, err := r.Resolver.commentService.SubscribeReplies(ctx, commentID)
+	if err != nil {
+		r.Resolver.log.ErrorContext(
+			ctx,
+			"failed to subscribe to replies",
+			"error", err.Error(),
+		)
+		return nil, fmt.Errorf("failed to subscribe to replies: %w", err)
+	}
+
+	// Unsubscribe from replies when the context is done.
+	go func() {
+		<-ctx.Done()
+		r.log.InfoContext(
+			ctx,
+			"Unsubscribe signal received",
+			"SubscriptionID", subID,
+		)
+
+		r.Resolver.commentService.UnsubscribeComments(ctx, subID)
+	}()
+
+	// Convert the channel of entity.Comment to a channel of model.Comment.
+	commentCh := make(chan *model.Comment)
+	go func() {
+		for comment := range ch {
+			// The client may be gone, so sending stops after the context is done until the subscription is closed.
+			select {
+			case commentCh <- commentToGraphQL(comment):
+			case <-ctx.Done():
+			}
+		}
+
+		close(commentCh)
+	}()
+
+	r.Resolver.log.InfoContext(
+		ctx,
+		"subscribed to replies",
+		"commentID", commentID,
 		"duration", time.Since(start).String(),
 	)
 
 	return commentCh, nil
 }
 
+// Presence is the resolver for the presence field.
+func (r *subscriptionResolver) Presence(ctx context.Context, postID int, userID *int) (<-chan *model.Presence, error) {
+	start := time.Now()
+
+	// If userID is nil, set it to 0 to indicate an anonymous viewer.
+	var viewerID int
+	if userID != nil {
+		viewerID = *userID
+	}
+
+	ch, subID, err := r.Resolver.commentService.SubscribePresence(ctx, postID, viewerID)
+	if err != nil {
+		r.Resolver.log.ErrorContext(
+			ctx,
+			"failed to subscribe to presence",
+			"error", err.Error(),
+		)
+		return nil, fmt.Errorf("failed to subscribe to presence: %w", err)
+	}
+
+	// Unsubscribe from presence when the context is done.
+	go func() {
+		<-ctx.Done()
+		r.log.InfoContext(
+			ctx,
+			"Unsubscribe signal received",
+			"SubscriptionID", subID,
+		)
+
+		r.Resolver.commentService.UnsubscribePresence(ctx, subID)
+	}()
+
+	// Convert the channel of entity.Presence to a channel of model.Presence.
+	presenceCh := make(chan *model.Presence)
+	go func() {
+		for presence := range ch {
+			// Presence is sent without waiting for slow subscribers, so it's dropped after the context is done
+			// until the subscription is closed.
+			select {
+			case presenceCh <- presenceToGraphQL(presence):
+			case <-ctx.Done():
+			}
+		}
+
+		close(presenceCh)
+	}()
+
+	r.Resolver.log.InfoContext(
+		ctx,
+		"subscribed to presence",
+		"postID", postID,
+		"duration", time.Since(start).String(),
+	)
+
+	return presenceCh, nil
+}
+
+// ReactionsChanged is the resolver for the reactionsChanged field.
+func (r *subscriptionResolver) ReactionsChanged(ctx context.Context, targetType model.ReactionTarget, targetID int) (<-chan *model.ReactionsChange, error) {
+	start := time.Now()
+
+	ch, subID, err := r.Resolver.reactionService.SubscribeReactions(ctx, reactionTargetToEntity(targetType), targetID)
+	if err != nil {
+		r.Resolver.log.ErrorContext(
+			ctx,
+			"failed to subscribe to reactions",
+			"error", err.Error(),
+		)
+		return nil, fmt.Errorf("failed to subscribe to reactions: %w", err)
+	}
+
+	// Unsubscribe from reactions when the context is done.
+	go func() {
+		<-ctx.Done()
+		r.log.InfoContext(
+			ctx,
+			"Unsubscribe signal received",
+			"SubscriptionID", subID,
+		)
+
+		r.Resolver.reactionService.UnsubscribeReactions(ctx, subID)
+	}()
+
+	// Convert the channel of entity.ReactionsChange to a channel of model.ReactionsChange.
+	changeCh := make(chan *model.ReactionsChange)
+	go func() {
+		for change := range ch {
+			// Changes are sent without waiting for slow subscribers, so they're dropped after the context is done
+			// until the subscription is closed.
+			select {
+			case changeCh <- reactionsChangeToGraphQL(change):
+			case <-ctx.Done():
+			}
+		}
+
+		close(changeCh)
+	}()
+
+	r.Resolver.log.InfoContext(
+		ctx,
+		"subscribed to reactions",
+		"targetType", targetType,
+		"targetID", targetID,
+		"duration", time.Since(start).String(),
+	)
+
+	return changeCh, nil
+}
+
+// Comment returns generated.CommentResolver implementation.
+func (r *Resolver) Comment() generated.CommentResolver { return &commentResolver{r} }
+
 // Mutation returns generated.MutationResolver implementation.
 func (r *Resolver) Mutation() generated.MutationResolver { return &mutationResolver{r} }
 
+// Post returns generated.PostResolver implementation.
+func (r *Resolver) Post() generated.PostResolver { return &postResolver{r} }
+
 // Query returns generated.QueryResolver implementation.
 func (r *Resolver) Query() generated.QueryResolver { return &queryResolver{r} }
 
 // Subscription returns generated.SubscriptionResolver implementation.
 func (r *Resolver) Subscription() generated.SubscriptionResolver { return &subscriptionResolver{r} }
 
+type commentResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
+type postResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
 type subscriptionResolver struct{ *Resolver }
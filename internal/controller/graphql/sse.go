@@ -0,0 +1,193 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// sseTransport is a Server-Sent Events transport in the "distinct connections" mode of the graphql-sse protocol.
+// Unlike transport.SSE it accepts GET requests and keeps idle streams alive with comment pings,
+// so proxies don't close them.
+type sseTransport struct {
+	keepAlive time.Duration
+}
+
+var _ graphql.Transport = sseTransport{}
+
+// Supports checks if the request asks for an event stream.
+func (t sseTransport) Supports(r *http.Request) bool {
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		return false
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		return true
+	case http.MethodPost:
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		return err == nil && mediaType == "application/json"
+	default:
+		return false
+	}
+}
+
+// Do executes the operation and streams its results as "next" events followed by a "complete" event.
+func (t sseTransport) Do(w http.ResponseWriter, r *http.Request, exec graphql.GraphExecutor) {
+	ctx := r.Context()
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		transport.SendErrorf(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	params := &graphql.RawParams{Headers: r.Header}
+	params.ReadTime.Start = graphql.Now()
+	err := readSSEParams(r, params)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		writeSSEJSON(w, exec.DispatchError(ctx, gqlerror.List{gqlerror.Errorf("%s", err.Error())}))
+		return
+	}
+	params.ReadTime.End = graphql.Now()
+
+	rc, opErr := exec.CreateOperationContext(ctx, params)
+	ctx = graphql.WithOperationContext(ctx, rc)
+
+	// Cross-site GET requests are sent by browsers without preflight, so GET must not change anything,
+	// the same way transport.GET allows queries only.
+	if opErr == nil && r.Method == http.MethodGet {
+		op := rc.Doc.Operations.ForName(rc.OperationName)
+		if op.Operation != ast.Query && op.Operation != ast.Subscription {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotAcceptable)
+			writeSSEJSON(w, exec.DispatchError(ctx, gqlerror.List{
+				gqlerror.Errorf("GET requests only allow query and subscription operations"),
+			}))
+			return
+		}
+	}
+
+	// The stream lives as long as the operation, so the write timeout of the server must not apply to it.
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	fmt.Fprint(w, ":\n\n")
+	flusher.Flush()
+
+	if opErr != nil {
+		writeSSEEvent(w, exec.DispatchError(ctx, opErr))
+	} else {
+		responses, ctx := exec.DispatchOperation(ctx, rc)
+
+		// Reading the next response blocks until it is ready, so it's done in a separate goroutine
+		// to be able to send keepalive pings in the meantime.
+		events := make(chan *graphql.Response)
+		go func() {
+			defer close(events)
+			for {
+				response := responses(ctx)
+				if response == nil {
+					return
+				}
+
+				select {
+				case events <- response:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+
+		var keepAlive <-chan time.Time
+		if t.keepAlive > 0 {
+			ticker := time.NewTicker(t.keepAlive)
+			defer ticker.Stop()
+			keepAlive = ticker.C
+		}
+
+	loop:
+		for {
+			select {
+			case response, ok := <-events:
+				if !ok {
+					break loop
+				}
+				writeSSEEvent(w, response)
+			case <-keepAlive:
+				fmt.Fprint(w, ":\n\n")
+			}
+			flusher.Flush()
+		}
+	}
+
+	fmt.Fprint(w, "event: complete\n\n")
+	flusher.Flush()
+}
+
+// readSSEParams reads the operation from the JSON body of a POST request or from the URL of a GET request.
+func readSSEParams(r *http.Request, params *graphql.RawParams) error {
+	if r.Method == http.MethodPost {
+		err := decodeJSON(r.Body, params)
+		if err != nil {
+			return fmt.Errorf("json request body could not be decoded: %w", err)
+		}
+
+		return nil
+	}
+
+	query := r.URL.Query()
+	params.Query = query.Get("query")
+	params.OperationName = query.Get("operationName")
+
+	if variables := query.Get("variables"); variables != "" {
+		err := decodeJSON(strings.NewReader(variables), &params.Variables)
+		if err != nil {
+			return fmt.Errorf("variables could not be decoded: %w", err)
+		}
+	}
+
+	if extensions := query.Get("extensions"); extensions != "" {
+		err := decodeJSON(strings.NewReader(extensions), &params.Extensions)
+		if err != nil {
+			return fmt.Errorf("extensions could not be decoded: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// decodeJSON decodes JSON keeping numbers as json.Number, the same way gqlgen transports do.
+func decodeJSON(r io.Reader, val interface{}) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	return dec.Decode(val)
+}
+
+func writeSSEEvent(w io.Writer, response *graphql.Response) {
+	fmt.Fprint(w, "event: next\ndata: ")
+	writeSSEJSON(w, response)
+	fmt.Fprint(w, "\n\n")
+}
+
+func writeSSEJSON(w io.Writer, response *graphql.Response) {
+	b, err := json.Marshal(response)
+	if err != nil {
+		panic(err)
+	}
+
+	w.Write(b)
+}
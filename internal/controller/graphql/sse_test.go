@@ -0,0 +1,105 @@
+package graphql
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+func TestSSETransportSupports(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		accept      string
+		contentType string
+		want        bool
+	}{
+		{name: "GET stream", method: http.MethodGet, accept: "text/event-stream", want: true},
+		{name: "POST stream", method: http.MethodPost, accept: "text/event-stream", contentType: "application/json", want: true},
+		{name: "POST stream with charset", method: http.MethodPost, accept: "text/event-stream",
+			contentType: "application/json; charset=utf-8", want: true},
+		{name: "POST stream without JSON", method: http.MethodPost, accept: "text/event-stream",
+			contentType: "multipart/form-data"},
+		{name: "plain GET", method: http.MethodGet, accept: "application/json"},
+		{name: "plain POST", method: http.MethodPost, accept: "application/json", contentType: "application/json"},
+		{name: "PUT stream", method: http.MethodPut, accept: "text/event-stream", contentType: "application/json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/query", nil)
+			r.Header.Set("Accept", tt.accept)
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+
+			if got := (sseTransport{}).Supports(r); got != tt.want {
+				t.Errorf("Supports() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadSSEParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		url     string
+		body    string
+		want    graphql.RawParams
+		wantErr bool
+	}{
+		{
+			name:   "POST",
+			method: http.MethodPost,
+			url:    "/query",
+			body:   `{"query":"subscription { comments(postIDs: [1]) { id } }","variables":{"id":1}}`,
+			want: graphql.RawParams{
+				Query:     "subscription { comments(postIDs: [1]) { id } }",
+				Variables: map[string]interface{}{"id": json.Number("1")},
+			},
+		},
+		{
+			name:   "GET",
+			method: http.MethodGet,
+			url:    "/query?query=subscription+Watch+%7B+comments%28postIDs%3A+%5B1%5D%29+%7B+id+%7D+%7D&operationName=Watch&variables=%7B%22id%22%3A1%7D",
+			want: graphql.RawParams{
+				Query:         "subscription Watch { comments(postIDs: [1]) { id } }",
+				OperationName: "Watch",
+				Variables:     map[string]interface{}{"id": json.Number("1")},
+			},
+		},
+		{name: "invalid body", method: http.MethodPost, url: "/query", body: `{"query":`, wantErr: true},
+		{name: "invalid variables", method: http.MethodGet, url: "/query?query=%7B+posts+%7D&variables=%7B", wantErr: true},
+		{name: "invalid extensions", method: http.MethodGet, url: "/query?query=%7B+posts+%7D&extensions=1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+
+			var params graphql.RawParams
+			err := readSSEParams(r, &params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readSSEParams() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if params.Query != tt.want.Query || params.OperationName != tt.want.OperationName {
+				t.Errorf("readSSEParams() = %q %q, want %q %q", params.Query, params.OperationName,
+					tt.want.Query, tt.want.OperationName)
+			}
+			got, _ := json.Marshal(params.Variables)
+			want, _ := json.Marshal(tt.want.Variables)
+			if string(got) != string(want) {
+				t.Errorf("variables = %s, want %s", got, want)
+			}
+		})
+	}
+}
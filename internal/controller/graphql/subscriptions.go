@@ -0,0 +1,69 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// Subscriptions is an extension counting running subscriptions. On shutdown, subscriptions are completed
+// by services, and connections of subscribers may be closed only after they get completions.
+type Subscriptions struct {
+	running sync.WaitGroup
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationInterceptor
+} = &Subscriptions{}
+
+// ExtensionName returns the name of the extension.
+func (*Subscriptions) ExtensionName() string {
+	return "Subscriptions"
+}
+
+// Validate does nothing, the extension doesn't depend on the schema.
+func (*Subscriptions) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// InterceptOperation counts the subscription until its last response.
+func (s *Subscriptions) InterceptOperation(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	oc := graphql.GetOperationContext(ctx)
+	if oc.Operation == nil || oc.Operation.Operation != ast.Subscription {
+		return next(ctx)
+	}
+
+	s.running.Add(1)
+	var once sync.Once
+
+	handler := next(ctx)
+
+	return func(ctx context.Context) *graphql.Response {
+		resp := handler(ctx)
+		if resp == nil {
+			// The end of the subscription, transports send the completion right after it.
+			once.Do(s.running.Done)
+		}
+
+		return resp
+	}
+}
+
+// Wait waits until all subscriptions end or the context is done.
+func (s *Subscriptions) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,119 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/gorilla/mux"
+	"github.com/vektah/gqlparser/v2/ast"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer creates spans of requests, operations and resolvers with the global tracer provider.
+var tracer = otel.Tracer("github.com/oustrix/ozon_journal/internal/controller/graphql")
+
+// traceMiddleware starts a span for every routed HTTP request. If the request carries a W3C traceparent header,
+// the span continues the trace of the client. Spans are named by route templates, so IDs don't end up in names.
+func traceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		route := r.URL.Path
+		if template, err := mux.CurrentRoute(r).GetPathTemplate(); err == nil {
+			route = template
+		}
+
+		ctx, span := tracer.Start(ctx, fmt.Sprintf("%s %s", r.Method, route),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(r.URL.Path),
+			))
+		defer span.End()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Tracing is an extension creating spans of operations and of resolvers of fields.
+// Spans of subscriptions last until the subscriptions end.
+type Tracing struct{}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationInterceptor
+	graphql.FieldInterceptor
+} = Tracing{}
+
+// ExtensionName returns the name of the extension.
+func (Tracing) ExtensionName() string {
+	return "Tracing"
+}
+
+// Validate does nothing, the extension doesn't depend on the schema.
+func (Tracing) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// InterceptOperation starts a span of the operation and ends it with the last response.
+func (Tracing) InterceptOperation(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	oc := graphql.GetOperationContext(ctx)
+	labels := operationLabels(oc)
+
+	ctx, span := tracer.Start(ctx, fmt.Sprintf("%s %s", labels[1], labels[0]), trace.WithAttributes(
+		attribute.String("graphql.operation.name", labels[0]),
+		attribute.String("graphql.operation.type", labels[1]),
+	))
+
+	handler := next(ctx)
+
+	return func(ctx context.Context) *graphql.Response {
+		resp := handler(ctx)
+
+		switch {
+		case resp == nil:
+			// The end of a subscription.
+			span.End()
+		case oc.Operation.Operation == ast.Subscription:
+			// Errors of events don't fail the subscription.
+		default:
+			if len(resp.Errors) > 0 {
+				span.SetStatus(codes.Error, resp.Errors.Error())
+			}
+			if resp.HasNext == nil || !*resp.HasNext {
+				span.End()
+			}
+		}
+
+		return resp
+	}
+}
+
+// InterceptField starts a span of the resolver. Fields read from models don't get spans.
+func (Tracing) InterceptField(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if !fc.IsResolver {
+		return next(ctx)
+	}
+
+	ctx, span := tracer.Start(ctx, fc.Object+"."+fc.Field.Name, trace.WithAttributes(
+		attribute.String("graphql.field.path", fc.Path().String()),
+	))
+	defer span.End()
+
+	res, err := next(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return res, err
+}
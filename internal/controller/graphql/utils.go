@@ -1,34 +1,195 @@
 package graphql
 
 import (
+	"fmt"
+
 	"github.com/oustrix/ozon_journal/internal/controller/graphql/model"
 	"github.com/oustrix/ozon_journal/internal/entity"
 )
 
+// postToGraphQL converts a post. Comments aren't converted, they are paged by the resolver of the field.
 func postToGraphQL(post *entity.Post) *model.Post {
-	comments := make([]*model.Comment, 0, len(post.Comments))
-	for _, c := range post.Comments {
-		comments = append(comments, commentToGraphQL(&c))
+	return &model.Post{
+		ID:            post.ID,
+		Title:         post.Title,
+		Slug:          post.Slug,
+		Content:       post.Content,
+		ContentFormat: contentFormatToGraphQL(post.ContentFormat),
+		PublishedAt:   post.PublishedAt,
+		AuthorID:      post.AuthorID,
+		Commentable:   post.Commentable,
+		Status:        postStatusToGraphQL(post.Status),
+		PublishAt:     optionalInt(post.PublishAt),
+		Revision:      post.Revision,
 	}
+}
 
-	return &model.Post{
-		ID:          post.ID,
-		Title:       post.Title,
-		Content:     post.Content,
-		PublishedAt: post.PublishedAt,
-		AuthorID:    post.AuthorID,
-		Commentable: post.Commentable,
-		Comments:    comments,
+// contentFormatToEntity converts a content format, treating a missing one as plain text.
+func contentFormatToEntity(format *model.ContentFormat) entity.ContentFormat {
+	if format != nil && *format == model.ContentFormatMarkdown {
+		return entity.ContentFormatMarkdown
+	}
+
+	return entity.ContentFormatPlain
+}
+
+func contentFormatToGraphQL(format entity.ContentFormat) model.ContentFormat {
+	if format == entity.ContentFormatMarkdown {
+		return model.ContentFormatMarkdown
+	}
+
+	return model.ContentFormatPlain
+}
+
+func postStatusToEntity(status model.PostStatus) entity.PostStatus {
+	switch status {
+	case model.PostStatusDraft:
+		return entity.PostStatusDraft
+	case model.PostStatusScheduled:
+		return entity.PostStatusScheduled
+	default:
+		return entity.PostStatusPublished
+	}
+}
+
+func postStatusToGraphQL(status entity.PostStatus) model.PostStatus {
+	switch status {
+	case entity.PostStatusDraft:
+		return model.PostStatusDraft
+	case entity.PostStatusScheduled:
+		return model.PostStatusScheduled
+	default:
+		return model.PostStatusPublished
+	}
+}
+
+func postRevisionsToGraphQL(revisions []entity.PostRevision) []*model.PostRevision {
+	result := make([]*model.PostRevision, 0, len(revisions))
+	for _, r := range revisions {
+		result = append(result, &model.PostRevision{
+			Version:   r.Version,
+			Title:     r.Title,
+			Content:   r.Content,
+			EditorID:  r.EditorID,
+			CreatedAt: r.CreatedAt,
+		})
+	}
+
+	return result
+}
+
+func attachmentToGraphQL(attachment *entity.Attachment) *model.Attachment {
+	return &model.Attachment{
+		ID:          attachment.ID,
+		PostID:      attachment.PostID,
+		FileName:    attachment.FileName,
+		ContentType: attachment.ContentType,
+		Size:        int(attachment.Size),
+		UploaderID:  attachment.UploaderID,
+		CreatedAt:   attachment.CreatedAt,
+		URL:         fmt.Sprintf("%s/%d", attachmentsPath, attachment.ID),
+	}
+}
+
+func diffModeToEntity(mode model.DiffMode) entity.DiffMode {
+	if mode == model.DiffModeWord {
+		return entity.DiffModeWord
+	}
+
+	return entity.DiffModeLine
+}
+
+func postRevisionDiffToGraphQL(diff *entity.PostRevisionDiff) *model.PostRevisionDiff {
+	return &model.PostRevisionDiff{
+		PostID:  diff.PostID,
+		From:    diff.From,
+		To:      diff.To,
+		Title:   diffChunksToGraphQL(diff.Title),
+		Content: diffChunksToGraphQL(diff.Content),
+	}
+}
+
+func diffChunksToGraphQL(chunks []entity.DiffChunk) []*model.DiffChunk {
+	result := make([]*model.DiffChunk, 0, len(chunks))
+	for _, c := range chunks {
+		var operation model.DiffOperation
+		switch c.Operation {
+		case entity.DiffInsert:
+			operation = model.DiffOperationInsert
+		case entity.DiffDelete:
+			operation = model.DiffOperationDelete
+		default:
+			operation = model.DiffOperationEqual
+		}
+
+		result = append(result, &model.DiffChunk{Operation: operation, Text: c.Text})
+	}
+
+	return result
+}
+
+// optionalInt converts zero to nil for nullable GraphQL fields.
+func optionalInt(value int) *int {
+	if value == 0 {
+		return nil
 	}
+
+	return &value
 }
 
 func commentToGraphQL(comment *entity.Comment) *model.Comment {
 	return &model.Comment{
 		ID:              comment.ID,
 		Content:         comment.Content,
+		ContentFormat:   contentFormatToGraphQL(comment.ContentFormat),
 		AuthorID:        comment.AuthorID,
 		PostID:          comment.PostID,
 		PublishedAt:     comment.PublishedAt,
 		ParentCommentID: comment.ParentCommentID,
 	}
 }
+
+func presenceToGraphQL(presence *entity.Presence) *model.Presence {
+	return &model.Presence{
+		PostID:    presence.PostID,
+		Viewers:   presence.Viewers,
+		ViewerIDs: presence.ViewerIDs,
+		TypingIDs: presence.TypingIDs,
+	}
+}
+
+func reactionTargetToEntity(target model.ReactionTarget) entity.ReactionTargetType {
+	if target == model.ReactionTargetComment {
+		return entity.ReactionTargetComment
+	}
+
+	return entity.ReactionTargetPost
+}
+
+func reactionTargetToGraphQL(targetType entity.ReactionTargetType) model.ReactionTarget {
+	if targetType == entity.ReactionTargetComment {
+		return model.ReactionTargetComment
+	}
+
+	return model.ReactionTargetPost
+}
+
+func reactionCountsToGraphQL(counts []entity.ReactionCount) []*model.ReactionCount {
+	reactions := make([]*model.ReactionCount, 0, len(counts))
+	for _, c := range counts {
+		reactions = append(reactions, &model.ReactionCount{
+			Emoji: c.Emoji,
+			Count: c.Count,
+		})
+	}
+
+	return reactions
+}
+
+func reactionsChangeToGraphQL(change *entity.ReactionsChange) *model.ReactionsChange {
+	return &model.ReactionsChange{
+		TargetType: reactionTargetToGraphQL(change.TargetType),
+		TargetID:   change.TargetID,
+		Reactions:  reactionCountsToGraphQL(change.Reactions),
+	}
+}
@@ -0,0 +1,93 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/oustrix/ozon_journal/internal"
+	"github.com/oustrix/ozon_journal/internal/controller/grpc/pb"
+	"github.com/oustrix/ozon_journal/internal/entity"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+)
+
+// commentServer implements pb.CommentServiceServer with the comment service.
+type commentServer struct {
+	pb.UnimplementedCommentServiceServer
+	commentService internal.CommentService
+	log            *logger.Logger
+}
+
+// ListComments returns comments of a post. If page or amount isn't set, defaults are used.
+func (s *commentServer) ListComments(ctx context.Context, req *pb.ListCommentsRequest) (*pb.ListCommentsResponse, error) {
+	// Clients of the gRPC API are anonymous viewers.
+	comments, err := s.commentService.GetCommentsByPostID(ctx, int(req.PostId), 0, optionalInt(req.Page),
+		optionalInt(req.Amount))
+	if err != nil {
+		return nil, serviceError(ctx, s.log, err)
+	}
+
+	resp := &pb.ListCommentsResponse{Comments: make([]*pb.Comment, 0, len(*comments))}
+	for _, comment := range *comments {
+		resp.Comments = append(resp.Comments, commentToGRPC(&comment))
+	}
+
+	return resp, nil
+}
+
+// AddComment comments a post or replies to a comment.
+func (s *commentServer) AddComment(ctx context.Context, req *pb.AddCommentRequest) (*pb.Comment, error) {
+	comment := &entity.Comment{
+		PostID:          int(req.PostId),
+		Content:         req.Content,
+		ContentFormat:   contentFormatToEntity(req.ContentFormat),
+		AuthorID:        int(req.AuthorId),
+		ParentCommentID: optionalInt(req.ParentCommentId),
+	}
+
+	comment, err := s.commentService.CreateComment(ctx, comment)
+	if err != nil {
+		return nil, serviceError(ctx, s.log, err)
+	}
+
+	return commentToGRPC(comment), nil
+}
+
+// WatchComments sends new comments of posts to the stream until the call ends.
+func (s *commentServer) WatchComments(req *pb.WatchCommentsRequest, stream pb.CommentService_WatchCommentsServer) error {
+	ctx := stream.Context()
+
+	postIDs := make([]int, 0, len(req.PostIds))
+	for _, id := range req.PostIds {
+		postIDs = append(postIDs, int(id))
+	}
+
+	ch, subID, err := s.commentService.SubscribeComments(ctx, postIDs)
+	if err != nil {
+		return serviceError(ctx, s.log, err)
+	}
+
+	defer s.commentService.UnsubscribeComments(ctx, subID)
+
+	s.log.InfoContext(
+		ctx,
+		"subscribed to comments",
+		"postIDs", postIDs,
+		"subscriptionID", subID,
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case comment, ok := <-ch:
+			// The channel is closed on shutdown and when the client doesn't keep up with comments.
+			if !ok {
+				return nil
+			}
+
+			err = stream.Send(commentToGRPC(comment))
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
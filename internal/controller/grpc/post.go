@@ -0,0 +1,88 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/oustrix/ozon_journal/internal"
+	"github.com/oustrix/ozon_journal/internal/controller/grpc/pb"
+	"github.com/oustrix/ozon_journal/internal/entity"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// postServer implements pb.PostServiceServer with the post service.
+type postServer struct {
+	pb.UnimplementedPostServiceServer
+	postService internal.PostService
+	log         *logger.Logger
+}
+
+// ListPosts returns published posts. If page or amount isn't set, defaults are used.
+func (s *postServer) ListPosts(ctx context.Context, req *pb.ListPostsRequest) (*pb.ListPostsResponse, error) {
+	posts, err := s.postService.GetPosts(ctx, optionalInt(req.Page), optionalInt(req.Amount))
+	if err != nil {
+		return nil, serviceError(ctx, s.log, err)
+	}
+
+	resp := &pb.ListPostsResponse{Posts: make([]*pb.Post, 0, len(*posts))}
+	for _, post := range *posts {
+		resp.Posts = append(resp.Posts, postToGRPC(&post))
+	}
+
+	return resp, nil
+}
+
+// GetPost returns a post by its ID or slug.
+func (s *postServer) GetPost(ctx context.Context, req *pb.GetPostRequest) (*pb.Post, error) {
+	var post *entity.Post
+	var err error
+
+	switch lookup := req.Lookup.(type) {
+	case *pb.GetPostRequest_Id:
+		post, err = s.postService.GetPostByID(ctx, int(lookup.Id), int(req.ViewerId))
+	case *pb.GetPostRequest_Slug:
+		post, err = s.postService.GetPostBySlug(ctx, lookup.Slug, int(req.ViewerId))
+	default:
+		return nil, status.Error(codes.InvalidArgument, "id or slug must be set")
+	}
+	if err != nil {
+		return nil, serviceError(ctx, s.log, err)
+	}
+
+	return postToGRPC(post), nil
+}
+
+// CreatePost creates a post. Without a status, it is scheduled if publish_at is set and published otherwise.
+func (s *postServer) CreatePost(ctx context.Context, req *pb.CreatePostRequest) (*pb.Post, error) {
+	post := &entity.Post{
+		Title:         req.Title,
+		Content:       req.Content,
+		ContentFormat: contentFormatToEntity(req.ContentFormat),
+		AuthorID:      int(req.AuthorId),
+		Commentable:   req.Commentable,
+	}
+
+	if req.PublishAt != nil {
+		post.PublishAt = int(*req.PublishAt)
+		post.Status = entity.PostStatusScheduled
+	} else {
+		post.Status = entity.PostStatusPublished
+	}
+
+	switch req.Status {
+	case pb.PostStatus_POST_STATUS_DRAFT:
+		post.Status = entity.PostStatusDraft
+	case pb.PostStatus_POST_STATUS_SCHEDULED:
+		post.Status = entity.PostStatusScheduled
+	case pb.PostStatus_POST_STATUS_PUBLISHED:
+		post.Status = entity.PostStatusPublished
+	}
+
+	post, err := s.postService.CreatePost(ctx, post)
+	if err != nil {
+		return nil, serviceError(ctx, s.log, err)
+	}
+
+	return postToGRPC(post), nil
+}
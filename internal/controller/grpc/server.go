@@ -0,0 +1,131 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/oustrix/ozon_journal/internal"
+	"github.com/oustrix/ozon_journal/internal/controller/grpc/pb"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+	"github.com/oustrix/ozon_journal/pkg/requestid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// NewServer creates a new gRPC server with the post and comment services registered.
+func NewServer(log *logger.Logger, postService internal.PostService, commentService internal.CommentService) *grpc.Server {
+	log = log.With("layer", "controller")
+
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unaryInterceptor(log)),
+		grpc.ChainStreamInterceptor(streamInterceptor(log)),
+	)
+
+	pb.RegisterPostServiceServer(server, &postServer{postService: postService, log: log})
+	pb.RegisterCommentServiceServer(server, &commentServer{commentService: commentService, log: log})
+
+	return server
+}
+
+// unaryInterceptor adds request IDs to contexts of calls and logs the calls.
+func unaryInterceptor(log *logger.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+
+		ctx, id := withRequestID(ctx)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, id))
+
+		log.DebugContext(
+			ctx,
+			"received request",
+			"method", info.FullMethod,
+		)
+
+		resp, err := handler(ctx, req)
+		if err != nil {
+			log.ErrorContext(
+				ctx,
+				"failed to handle call",
+				"method", info.FullMethod,
+				"error", err.Error(),
+			)
+			return nil, err
+		}
+
+		log.InfoContext(
+			ctx,
+			"call handled",
+			"method", info.FullMethod,
+			"duration", time.Since(start).String(),
+		)
+
+		return resp, nil
+	}
+}
+
+// streamInterceptor adds request IDs to contexts of streaming calls and logs the calls.
+func streamInterceptor(log *logger.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+
+		ctx, id := withRequestID(ss.Context())
+		_ = ss.SetHeader(metadata.Pairs(requestIDKey, id))
+
+		log.DebugContext(
+			ctx,
+			"received request",
+			"method", info.FullMethod,
+		)
+
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		if err != nil {
+			log.ErrorContext(
+				ctx,
+				"failed to handle stream",
+				"method", info.FullMethod,
+				"error", err.Error(),
+			)
+			return err
+		}
+
+		log.InfoContext(
+			ctx,
+			"stream closed",
+			"method", info.FullMethod,
+			"duration", time.Since(start).String(),
+		)
+
+		return nil
+	}
+}
+
+// requestIDKey is the metadata key of request IDs, the same as the HTTP header.
+var requestIDKey = strings.ToLower(requestid.Header)
+
+// withRequestID takes the request ID from the metadata of a call or generates a new one
+// and adds it to the context.
+func withRequestID(ctx context.Context) (context.Context, string) {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDKey); len(values) > 0 {
+			id = values[0]
+		}
+	}
+
+	if !requestid.Valid(id) {
+		id = requestid.New()
+	}
+
+	return requestid.WithID(ctx, id), id
+}
+
+// serverStream is a grpc.ServerStream with a context replaced.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,102 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/oustrix/ozon_journal/internal/controller/grpc/pb"
+	"github.com/oustrix/ozon_journal/internal/entity"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// internalError hides details of internal errors from clients, they are logged by interceptors.
+func internalError() error {
+	return status.Error(codes.Internal, "internal error")
+}
+
+// serviceError converts an error of a service to a status by its kind. Other errors are failures
+// of the application, so they are logged with details and reported as internal ones.
+func serviceError(ctx context.Context, log *logger.Logger, err error) error {
+	switch {
+	case errors.Is(err, entity.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, entity.ErrInvalidArgument):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, entity.ErrPermissionDenied):
+		return status.Error(codes.PermissionDenied, err.Error())
+	default:
+		log.ErrorContext(
+			ctx,
+			"service failed",
+			"error", err.Error(),
+		)
+		return internalError()
+	}
+}
+
+func postToGRPC(post *entity.Post) *pb.Post {
+	return &pb.Post{
+		Id:            int32(post.ID),
+		Title:         post.Title,
+		Slug:          post.Slug,
+		Content:       post.Content,
+		ContentFormat: contentFormatToGRPC(post.ContentFormat),
+		PublishedAt:   int64(post.PublishedAt),
+		AuthorId:      int32(post.AuthorID),
+		Commentable:   post.Commentable,
+		Status:        postStatusToGRPC(post.Status),
+		PublishAt:     int64(post.PublishAt),
+		Revision:      int32(post.Revision),
+	}
+}
+
+func commentToGRPC(comment *entity.Comment) *pb.Comment {
+	return &pb.Comment{
+		Id:              int32(comment.ID),
+		Content:         comment.Content,
+		ContentFormat:   contentFormatToGRPC(comment.ContentFormat),
+		AuthorId:        int32(comment.AuthorID),
+		PostId:          int32(comment.PostID),
+		PublishedAt:     int64(comment.PublishedAt),
+		ParentCommentId: int32(comment.ParentCommentID),
+	}
+}
+
+// contentFormatToEntity converts a content format, treating an unspecified one as plain text.
+func contentFormatToEntity(format pb.ContentFormat) entity.ContentFormat {
+	if format == pb.ContentFormat_CONTENT_FORMAT_MARKDOWN {
+		return entity.ContentFormatMarkdown
+	}
+
+	return entity.ContentFormatPlain
+}
+
+func contentFormatToGRPC(format entity.ContentFormat) pb.ContentFormat {
+	if format == entity.ContentFormatMarkdown {
+		return pb.ContentFormat_CONTENT_FORMAT_MARKDOWN
+	}
+
+	return pb.ContentFormat_CONTENT_FORMAT_PLAIN
+}
+
+func postStatusToGRPC(status entity.PostStatus) pb.PostStatus {
+	switch status {
+	case entity.PostStatusDraft:
+		return pb.PostStatus_POST_STATUS_DRAFT
+	case entity.PostStatusScheduled:
+		return pb.PostStatus_POST_STATUS_SCHEDULED
+	default:
+		return pb.PostStatus_POST_STATUS_PUBLISHED
+	}
+}
+
+// optionalInt returns the value of an optional field or -1 if it isn't set.
+func optionalInt(value *int32) int {
+	if value == nil {
+		return -1
+	}
+
+	return int(*value)
+}
@@ -0,0 +1,46 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/oustrix/ozon_journal/internal/entity"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestServiceError(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantCode    codes.Code
+		wantMessage string
+	}{
+		{name: "not found", err: entity.NotFoundError("post with ID 1 not found"), wantCode: codes.NotFound,
+			wantMessage: "post with ID 1 not found"},
+		{name: "invalid argument", err: entity.InvalidArgumentError("content is empty"), wantCode: codes.InvalidArgument,
+			wantMessage: "content is empty"},
+		{name: "permission denied", err: entity.PermissionDeniedError("post can be edited by its author only"),
+			wantCode: codes.PermissionDenied, wantMessage: "post can be edited by its author only"},
+		{name: "wrapped kind", err: fmt.Errorf("failed to get post: %w", entity.NotFoundError("post not found")),
+			wantCode: codes.NotFound, wantMessage: "failed to get post: post not found"},
+		// Details of internal errors aren't revealed to clients.
+		{name: "internal error", err: errors.New("connection refused"), wantCode: codes.Internal,
+			wantMessage: "internal error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, ok := status.FromError(serviceError(context.Background(), logger.New("error"), tt.err))
+			if !ok {
+				t.Fatal("serviceError() didn't return a status")
+			}
+			if st.Code() != tt.wantCode || st.Message() != tt.wantMessage {
+				t.Errorf("serviceError() = %v %q, want %v %q", st.Code(), st.Message(), tt.wantCode, tt.wantMessage)
+			}
+		})
+	}
+}
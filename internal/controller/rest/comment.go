@@ -0,0 +1,78 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/oustrix/ozon_journal/internal/entity"
+)
+
+func (h *handler) listComments(ctx context.Context, r *http.Request) (any, error) {
+	postID, err := pathID(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// If page or amount isn't set, -1 tells the service to use defaults.
+	page, err := queryInt(r, "page", -1)
+	if err != nil {
+		return nil, err
+	}
+
+	amount, err := queryInt(r, "amount", -1)
+	if err != nil {
+		return nil, err
+	}
+
+	// If viewerID isn't set, 0 indicates an anonymous viewer.
+	viewerID, err := queryInt(r, "viewerID", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	comments, err := h.commentService.GetCommentsByPostID(ctx, postID, viewerID, page, amount)
+	if err != nil {
+		return nil, serviceError(err)
+	}
+
+	response := make([]*Comment, 0, len(*comments))
+	for _, comment := range *comments {
+		response = append(response, commentToREST(&comment))
+	}
+
+	return response, nil
+}
+
+func (h *handler) createComment(ctx context.Context, r *http.Request) (any, error) {
+	postID, err := pathID(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var request CreateCommentRequest
+	err = decodeBody(r, &request)
+	if err != nil {
+		return nil, err
+	}
+
+	comment := &entity.Comment{
+		PostID:        postID,
+		Content:       request.Content,
+		ContentFormat: contentFormatToEntity(request.ContentFormat),
+		AuthorID:      request.AuthorID,
+	}
+
+	// If parentCommentId isn't set, -1 indicates a comment on the post itself.
+	if request.ParentCommentID == nil {
+		comment.ParentCommentID = -1
+	} else {
+		comment.ParentCommentID = *request.ParentCommentID
+	}
+
+	comment, err = h.commentService.CreateComment(ctx, comment)
+	if err != nil {
+		return nil, serviceError(err)
+	}
+
+	return commentToREST(comment), nil
+}
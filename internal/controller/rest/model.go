@@ -0,0 +1,99 @@
+package rest
+
+import (
+	"github.com/oustrix/ozon_journal/internal/entity"
+)
+
+// Post is a post in API responses.
+type Post struct {
+	ID            int    `json:"id"`
+	Title         string `json:"title"`
+	Slug          string `json:"slug" doc:"Current slug of the post"`
+	Content       string `json:"content"`
+	ContentFormat string `json:"contentFormat" enum:"plain,markdown"`
+	PublishedAt   int    `json:"publishedAt" doc:"Unix time, 0 for unpublished posts"`
+	AuthorID      int    `json:"authorId"`
+	Commentable   bool   `json:"commentable"`
+	Status        string `json:"status" enum:"draft,scheduled,published"`
+	PublishAt     *int   `json:"publishAt,omitempty" doc:"Unix time of publication of a scheduled post"`
+	Revision      int    `json:"revision" doc:"Version of the current title and content"`
+}
+
+// Comment is a comment in API responses.
+type Comment struct {
+	ID              int    `json:"id"`
+	Content         string `json:"content"`
+	ContentFormat   string `json:"contentFormat" enum:"plain,markdown"`
+	AuthorID        int    `json:"authorId"`
+	PostID          int    `json:"postId"`
+	PublishedAt     int    `json:"publishedAt" doc:"Unix time"`
+	ParentCommentID int    `json:"parentCommentId" doc:"-1 for comments on the post itself"`
+}
+
+// CreatePostRequest is a body of a post creation request.
+type CreatePostRequest struct {
+	Title         string  `json:"title"`
+	Content       string  `json:"content"`
+	ContentFormat *string `json:"contentFormat,omitempty" enum:"plain,markdown" doc:"plain by default"`
+	AuthorID      int     `json:"authorId"`
+	Commentable   bool    `json:"commentable"`
+	Status        *string `json:"status,omitempty" enum:"draft,scheduled,published" doc:"scheduled if publishAt is set, published otherwise"`
+	PublishAt     *int    `json:"publishAt,omitempty" doc:"Unix time of publication of a scheduled post"`
+}
+
+// CreateCommentRequest is a body of a comment creation request.
+type CreateCommentRequest struct {
+	Content         string  `json:"content"`
+	ContentFormat   *string `json:"contentFormat,omitempty" enum:"plain,markdown" doc:"plain by default"`
+	AuthorID        int     `json:"authorId"`
+	ParentCommentID *int    `json:"parentCommentId,omitempty" doc:"ID of the comment to reply to"`
+}
+
+// Error is a body of an error response.
+type Error struct {
+	Message string `json:"message"`
+}
+
+func postToREST(post *entity.Post) *Post {
+	p := &Post{
+		ID:            post.ID,
+		Title:         post.Title,
+		Slug:          post.Slug,
+		Content:       post.Content,
+		ContentFormat: string(post.ContentFormat),
+		PublishedAt:   post.PublishedAt,
+		AuthorID:      post.AuthorID,
+		Commentable:   post.Commentable,
+		Status:        string(post.Status),
+		Revision:      post.Revision,
+	}
+
+	if post.PublishAt != 0 {
+		publishAt := post.PublishAt
+		p.PublishAt = &publishAt
+	}
+
+	return p
+}
+
+func commentToREST(comment *entity.Comment) *Comment {
+	return &Comment{
+		ID:              comment.ID,
+		Content:         comment.Content,
+		ContentFormat:   string(comment.ContentFormat),
+		AuthorID:        comment.AuthorID,
+		PostID:          comment.PostID,
+		PublishedAt:     comment.PublishedAt,
+		ParentCommentID: comment.ParentCommentID,
+	}
+}
+
+// contentFormatToEntity converts a content format, treating a missing one as plain text.
+// Unknown formats are passed as is and rejected by services.
+func contentFormatToEntity(format *string) entity.ContentFormat {
+	if format == nil {
+		return entity.ContentFormatPlain
+	}
+
+	return entity.ContentFormat(*format)
+}
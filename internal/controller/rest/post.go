@@ -0,0 +1,98 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/oustrix/ozon_journal/internal/entity"
+)
+
+func (h *handler) listPosts(ctx context.Context, r *http.Request) (any, error) {
+	// If page or amount isn't set, -1 tells the service to use defaults.
+	page, err := queryInt(r, "page", -1)
+	if err != nil {
+		return nil, err
+	}
+
+	amount, err := queryInt(r, "amount", -1)
+	if err != nil {
+		return nil, err
+	}
+
+	posts, err := h.postService.GetPosts(ctx, page, amount)
+	if err != nil {
+		return nil, serviceError(err)
+	}
+
+	response := make([]*Post, 0, len(*posts))
+	for _, post := range *posts {
+		response = append(response, postToREST(&post))
+	}
+
+	return response, nil
+}
+
+func (h *handler) createPost(ctx context.Context, r *http.Request) (any, error) {
+	var request CreatePostRequest
+	err := decodeBody(r, &request)
+	if err != nil {
+		return nil, err
+	}
+
+	post := &entity.Post{
+		Title:         request.Title,
+		Content:       request.Content,
+		ContentFormat: contentFormatToEntity(request.ContentFormat),
+		AuthorID:      request.AuthorID,
+		Commentable:   request.Commentable,
+	}
+
+	// If publishAt is set, the post is scheduled by default. Otherwise, it is published right away.
+	if request.PublishAt != nil {
+		post.PublishAt = *request.PublishAt
+		post.Status = entity.PostStatusScheduled
+	} else {
+		post.Status = entity.PostStatusPublished
+	}
+
+	if request.Status != nil {
+		post.Status = entity.PostStatus(*request.Status)
+	}
+
+	post, err = h.postService.CreatePost(ctx, post)
+	if err != nil {
+		return nil, serviceError(err)
+	}
+
+	return postToREST(post), nil
+}
+
+func (h *handler) getPost(ctx context.Context, r *http.Request) (any, error) {
+	id, err := pathID(r)
+	if err != nil {
+		return nil, err
+	}
+
+	post, err := h.visiblePost(ctx, r, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return postToREST(post), nil
+}
+
+// visiblePost returns a post if the viewer from the viewerID query parameter may see it.
+func (h *handler) visiblePost(ctx context.Context, r *http.Request, id int) (*entity.Post, error) {
+	// If viewerID isn't set, 0 indicates an anonymous viewer.
+	viewerID, err := queryInt(r, "viewerID", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	post, err := h.postService.GetPostByID(ctx, id, viewerID)
+	if err != nil {
+		return nil, serviceError(err)
+	}
+
+	return post, nil
+}
@@ -0,0 +1,297 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/oustrix/ozon_journal/internal"
+	"github.com/oustrix/ozon_journal/internal/entity"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+	"github.com/oustrix/ozon_journal/pkg/openapi"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Prefix is the path prefix of the API.
+const Prefix = "/api/v1"
+
+// maxBodySize limits bodies of requests, posts are the largest of them.
+const maxBodySize = 1 << 20
+
+// handler serves the API with the same services the GraphQL API uses.
+type handler struct {
+	postService    internal.PostService
+	commentService internal.CommentService
+	log            *logger.Logger
+}
+
+// endpoint is a definition of an API operation. Both routes and the OpenAPI document are built from endpoints.
+type endpoint struct {
+	method   string
+	path     string // mux path template relative to Prefix
+	id       string
+	summary  string
+	params   []openapi.Parameter
+	request  any // zero value of the request body type, nil for requests without a body
+	response any // zero value of the response body type
+	status   int // status of successful responses
+	handle   func(ctx context.Context, r *http.Request) (any, error)
+}
+
+// apiError is an error with the status of its response. Other errors are reported as internal ones.
+type apiError struct {
+	status  int
+	message string
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+// serviceError converts errors caused by requests to API errors by their kinds. Other errors are returned
+// as they are, so they are reported as internal ones without revealing their messages.
+func serviceError(err error) error {
+	switch {
+	case errors.Is(err, entity.ErrNotFound):
+		return &apiError{status: http.StatusNotFound, message: err.Error()}
+	case errors.Is(err, entity.ErrInvalidArgument):
+		return &apiError{status: http.StatusBadRequest, message: err.Error()}
+	case errors.Is(err, entity.ErrPermissionDenied):
+		return &apiError{status: http.StatusForbidden, message: err.Error()}
+	default:
+		return err
+	}
+}
+
+// NewRouter creates a new router of the API. It must be mounted at Prefix of a router adding request IDs.
+func NewRouter(log *logger.Logger, postService internal.PostService, commentService internal.CommentService) http.Handler {
+	h := &handler{
+		postService:    postService,
+		commentService: commentService,
+		log:            log,
+	}
+
+	doc := openapi.New(openapi.Info{
+		Title:       "Ozon Journal",
+		Description: "Read and write API of posts and comments for clients that don't use GraphQL.",
+		Version:     "1.0.0",
+	}, openapi.Server{URL: Prefix})
+
+	r := mux.NewRouter().PathPrefix(Prefix).Subrouter()
+
+	for _, e := range h.endpoints() {
+		r.Handle(e.path, h.serve(e)).Methods(e.method)
+		doc.AddOperation(e.method, templatePath(e.path), e.operation(doc))
+	}
+
+	// The document doesn't change, so it is encoded once.
+	spec, err := json.Marshal(doc)
+	if err != nil {
+		panic(fmt.Sprintf("failed to encode OpenAPI document: %v", err))
+	}
+
+	r.HandleFunc("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(spec)
+	}).Methods("GET")
+
+	return r
+}
+
+// endpoints returns all operations of the API.
+func (h *handler) endpoints() []endpoint {
+	id := openapi.Parameter{Name: "id", In: "path", Description: "ID of the post", Required: true, Schema: &openapi.Schema{Type: "integer"}}
+	page := openapi.Parameter{Name: "page", In: "query", Description: "Page number, starting from 1", Schema: &openapi.Schema{Type: "integer"}}
+	commentPage := openapi.Parameter{Name: "page", In: "query", Description: "Page number, starting from 0", Schema: &openapi.Schema{Type: "integer"}}
+	amount := openapi.Parameter{Name: "amount", In: "query", Description: "Number of items on a page", Schema: &openapi.Schema{Type: "integer"}}
+	viewerID := openapi.Parameter{Name: "viewerID", In: "query", Description: "ID of the user, unpublished posts are visible to their authors only",
+		Schema: &openapi.Schema{Type: "integer"}}
+
+	return []endpoint{
+		{
+			method:   http.MethodGet,
+			path:     "/posts",
+			id:       "listPosts",
+			summary:  "List published posts, the latest first",
+			params:   []openapi.Parameter{page, amount},
+			response: []Post{},
+			status:   http.StatusOK,
+			handle:   h.listPosts,
+		},
+		{
+			method:   http.MethodPost,
+			path:     "/posts",
+			id:       "createPost",
+			summary:  "Create a post",
+			request:  CreatePostRequest{},
+			response: Post{},
+			status:   http.StatusCreated,
+			handle:   h.createPost,
+		},
+		{
+			method:   http.MethodGet,
+			path:     "/posts/{id:[0-9]+}",
+			id:       "getPost",
+			summary:  "Get a post by its ID",
+			params:   []openapi.Parameter{id, viewerID},
+			response: Post{},
+			status:   http.StatusOK,
+			handle:   h.getPost,
+		},
+		{
+			method:   http.MethodGet,
+			path:     "/posts/{id:[0-9]+}/comments",
+			id:       "listComments",
+			summary:  "List comments of a post",
+			params:   []openapi.Parameter{id, commentPage, amount, viewerID},
+			response: []Comment{},
+			status:   http.StatusOK,
+			handle:   h.listComments,
+		},
+		{
+			method:   http.MethodPost,
+			path:     "/posts/{id:[0-9]+}/comments",
+			id:       "createComment",
+			summary:  "Comment a post or reply to a comment",
+			params:   []openapi.Parameter{id},
+			request:  CreateCommentRequest{},
+			response: Comment{},
+			status:   http.StatusCreated,
+			handle:   h.createComment,
+		},
+	}
+}
+
+// operation describes the endpoint in the document.
+func (e *endpoint) operation(doc *openapi.Document) *openapi.Operation {
+	errorResponse := func(description string) *openapi.Response {
+		return &openapi.Response{Description: description, Content: doc.JSON(Error{})}
+	}
+
+	op := &openapi.Operation{
+		OperationID: e.id,
+		Summary:     e.summary,
+		Parameters:  e.params,
+		Responses: map[string]*openapi.Response{
+			strconv.Itoa(e.status): {Description: http.StatusText(e.status), Content: doc.JSON(e.response)},
+			"400":                  errorResponse("Invalid request"),
+			"500":                  errorResponse("Internal error"),
+		},
+	}
+
+	if e.request != nil {
+		op.RequestBody = &openapi.RequestBody{Required: true, Content: doc.JSON(e.request)}
+	}
+
+	for _, param := range e.params {
+		if param.In == "path" {
+			op.Responses["404"] = errorResponse("Post not found")
+		}
+	}
+
+	return op
+}
+
+// serve wraps handling of the endpoint with logging and encoding of responses.
+func (h *handler) serve(e endpoint) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx := r.Context()
+
+		// The span of the request is started by the main router, which knows the prefix only.
+		trace.SpanFromContext(ctx).SetName(fmt.Sprintf("%s %s%s", e.method, Prefix, templatePath(e.path)))
+
+		h.log.DebugContext(
+			ctx,
+			"received request",
+			"method", e.id,
+		)
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+
+		response, err := e.handle(ctx, r)
+		if err != nil {
+			h.log.ErrorContext(
+				ctx,
+				"failed to handle request",
+				"method", e.id,
+				"error", err.Error(),
+			)
+
+			var apiErr *apiError
+			if errors.As(err, &apiErr) {
+				writeJSON(w, apiErr.status, &Error{Message: apiErr.message})
+			} else {
+				writeJSON(w, http.StatusInternalServerError, &Error{Message: "internal server error"})
+			}
+			return
+		}
+
+		writeJSON(w, e.status, response)
+
+		h.log.InfoContext(
+			ctx,
+			"request handled",
+			"method", e.id,
+			"duration", time.Since(start).String(),
+		)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// decodeBody decodes a JSON request body into v. Unknown fields are rejected, so typos don't go unnoticed.
+func decodeBody(r *http.Request, v any) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(v)
+	if err != nil {
+		return &apiError{status: http.StatusBadRequest, message: fmt.Sprintf("invalid body: %s", err.Error())}
+	}
+
+	return nil
+}
+
+// pathID returns the ID path variable. The routes accept digits only, so it may be invalid on overflow only.
+func pathID(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return 0, &apiError{status: http.StatusNotFound, message: "post not found"}
+	}
+
+	return id, nil
+}
+
+// queryInt returns an integer query parameter or def if it isn't set.
+func queryInt(r *http.Request, name string, def int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, &apiError{status: http.StatusBadRequest, message: fmt.Sprintf("%s must be an integer", name)}
+	}
+
+	return n, nil
+}
+
+// pathVariable matches variables of mux path templates with their patterns.
+var pathVariable = regexp.MustCompile(`\{([^}:]+)(:[^}]+)?\}`)
+
+// templatePath converts a mux path template to an OpenAPI one by removing patterns of variables.
+func templatePath(path string) string {
+	return pathVariable.ReplaceAllString(path, "{$1}")
+}
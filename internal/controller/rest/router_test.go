@@ -0,0 +1,60 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/oustrix/ozon_journal/internal"
+	"github.com/oustrix/ozon_journal/internal/entity"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+)
+
+// errPostService fails every request for a post with its error. Other methods aren't implemented.
+type errPostService struct {
+	internal.PostService
+	err error
+}
+
+func (s *errPostService) GetPostByID(context.Context, int, int) (*entity.Post, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	return &entity.Post{ID: 1, Title: "Title"}, nil
+}
+
+func TestServiceErrorStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		err  error
+		want int
+	}{
+		{name: "found", path: "/api/v1/posts/1", want: http.StatusOK},
+		{name: "not found", path: "/api/v1/posts/1", err: entity.NotFoundError("post not found"), want: http.StatusNotFound},
+		{name: "invalid argument", path: "/api/v1/posts/1", err: entity.InvalidArgumentError("invalid"),
+			want: http.StatusBadRequest},
+		{name: "permission denied", path: "/api/v1/posts/1", err: entity.PermissionDeniedError("denied"),
+			want: http.StatusForbidden},
+		{name: "wrapped kind", path: "/api/v1/posts/1",
+			err: errors.Join(errors.New("context"), entity.NotFoundError("post not found")), want: http.StatusNotFound},
+		{name: "internal error", path: "/api/v1/posts/1", err: errors.New("connection refused"),
+			want: http.StatusInternalServerError},
+		{name: "invalid query", path: "/api/v1/posts/1?viewerID=x", want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRouter(logger.New("error"), &errPostService{err: tt.err}, nil)
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package entity
+
+import "io"
+
+// Attachment is a file attached to a post. The content is kept in a blob store under Key.
+type Attachment struct {
+	ID          int    `json:"id"`
+	PostID      int    `json:"post_id"`
+	Key         string `json:"key"`
+	FileName    string `json:"file_name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	UploaderID  int    `json:"uploader_id"`
+	CreatedAt   int    `json:"created_at"`
+}
+
+// Upload is a file received from a client.
+type Upload struct {
+	FileName    string
+	ContentType string // As declared by the client, it isn't trusted.
+	Size        int64
+	Content     io.Reader
+}
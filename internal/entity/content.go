@@ -0,0 +1,9 @@
+package entity
+
+// ContentFormat is a format of the content of posts and comments.
+type ContentFormat string
+
+const (
+	ContentFormatPlain    ContentFormat = "plain"
+	ContentFormatMarkdown ContentFormat = "markdown"
+)
@@ -0,0 +1,7 @@
+package entity
+
+// DumpStats is an amount of entities exported to or imported from a dump.
+type DumpStats struct {
+	Posts    int `json:"posts"`
+	Comments int `json:"comments"`
+}
@@ -0,0 +1,46 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kinds of errors caused by requests. Controllers check them with errors.Is to choose response statuses,
+// other errors are failures of the application.
+var (
+	// ErrNotFound is wrapped by errors about entities that don't exist or that the caller may not see.
+	ErrNotFound = errors.New("not found")
+	// ErrInvalidArgument is wrapped by errors about requests breaking validation rules.
+	ErrInvalidArgument = errors.New("invalid argument")
+	// ErrPermissionDenied is wrapped by errors about actions the caller may not take on entities it can see.
+	ErrPermissionDenied = errors.New("permission denied")
+)
+
+// kindError is an error of a kind, which isn't added to its message.
+type kindError struct {
+	kind    error
+	message string
+}
+
+func (e *kindError) Error() string {
+	return e.message
+}
+
+func (e *kindError) Unwrap() error {
+	return e.kind
+}
+
+// NotFoundError returns an error with the formatted message wrapping ErrNotFound.
+func NotFoundError(format string, args ...any) error {
+	return &kindError{kind: ErrNotFound, message: fmt.Sprintf(format, args...)}
+}
+
+// InvalidArgumentError returns an error with the formatted message wrapping ErrInvalidArgument.
+func InvalidArgumentError(format string, args ...any) error {
+	return &kindError{kind: ErrInvalidArgument, message: fmt.Sprintf(format, args...)}
+}
+
+// PermissionDeniedError returns an error with the formatted message wrapping ErrPermissionDenied.
+func PermissionDeniedError(format string, args ...any) error {
+	return &kindError{kind: ErrPermissionDenied, message: fmt.Sprintf(format, args...)}
+}
@@ -1,11 +1,25 @@
 package entity
 
+// PostStatus is a publication status of a post.
+type PostStatus string
+
+const (
+	PostStatusDraft     PostStatus = "draft"
+	PostStatusScheduled PostStatus = "scheduled"
+	PostStatusPublished PostStatus = "published"
+)
+
 type Post struct {
-	ID          int       `json:"id"`
-	Title       string    `json:"title"`
-	Content     string    `json:"content"`
-	PublishedAt int       `json:"published_at"`
-	AuthorID    int       `json:"author_id"`
-	Commentable bool      `json:"commentable"`
-	Comments    []Comment `json:"comments"`
+	ID            int           `json:"id"`
+	Title         string        `json:"title"`
+	Slug          string        `json:"slug"` // Current slug, earlier slugs of the post still lead to it.
+	Content       string        `json:"content"`
+	ContentFormat ContentFormat `json:"content_format"`
+	PublishedAt   int           `json:"published_at"`
+	AuthorID      int           `json:"author_id"`
+	Commentable   bool          `json:"commentable"`
+	Status        PostStatus    `json:"status"`
+	PublishAt     int           `json:"publish_at"` // Set for scheduled posts only.
+	Revision      int           `json:"revision"`   // Version of the current title and content.
+	Comments      []Comment     `json:"comments"`
 }
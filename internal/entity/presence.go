@@ -0,0 +1,27 @@
+package entity
+
+// Presence describes who is reading a post and who is typing a comment to it at the moment.
+type Presence struct {
+	PostID    int   `json:"post_id"`
+	Viewers   int   `json:"viewers"`
+	ViewerIDs []int `json:"viewer_ids"`
+	TypingIDs []int `json:"typing_ids"`
+}
+
+// PresenceEventKind is a kind of presence change.
+type PresenceEventKind string
+
+const (
+	PresenceViewing PresenceEventKind = "viewing"
+	PresenceLeft    PresenceEventKind = "left"
+	PresenceTyping  PresenceEventKind = "typing"
+)
+
+// PresenceEvent is a change of a user's presence on a post.
+// Viewing and left events are sent for a session, so a user can read a post from several devices.
+type PresenceEvent struct {
+	PostID    int               `json:"post_id"`
+	UserID    int               `json:"user_id"`
+	SessionID string            `json:"session_id"`
+	Kind      PresenceEventKind `json:"kind"`
+}
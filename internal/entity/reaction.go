@@ -0,0 +1,31 @@
+package entity
+
+// ReactionTargetType is a type of entity a reaction is left on.
+type ReactionTargetType string
+
+const (
+	ReactionTargetPost    ReactionTargetType = "post"
+	ReactionTargetComment ReactionTargetType = "comment"
+)
+
+// Reaction is an emoji left by a user on a post or a comment. A user can leave each emoji once.
+type Reaction struct {
+	TargetType ReactionTargetType `json:"target_type"`
+	TargetID   int                `json:"target_id"`
+	UserID     int                `json:"user_id"`
+	Emoji      string             `json:"emoji"`
+	CreatedAt  int                `json:"created_at"`
+}
+
+// ReactionCount is an amount of reactions with the same emoji.
+type ReactionCount struct {
+	Emoji string `json:"emoji"`
+	Count int    `json:"count"`
+}
+
+// ReactionsChange is an updated list of reaction counts of a post or a comment.
+type ReactionsChange struct {
+	TargetType ReactionTargetType `json:"target_type"`
+	TargetID   int                `json:"target_id"`
+	Reactions  []ReactionCount    `json:"reactions"`
+}
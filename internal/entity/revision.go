@@ -0,0 +1,43 @@
+package entity
+
+// PostRevision is a saved version of the title and the content of a post.
+type PostRevision struct {
+	PostID    int    `json:"post_id"`
+	Version   int    `json:"version"` // Starts from 1 for the created post.
+	Title     string `json:"title"`
+	Content   string `json:"content"`
+	EditorID  int    `json:"editor_id"`
+	CreatedAt int    `json:"created_at"`
+}
+
+// DiffMode is a granularity of a diff between revisions.
+type DiffMode string
+
+const (
+	DiffModeLine DiffMode = "line"
+	DiffModeWord DiffMode = "word"
+)
+
+// DiffOperation is a kind of change of a diff chunk.
+type DiffOperation string
+
+const (
+	DiffEqual  DiffOperation = "equal"
+	DiffInsert DiffOperation = "insert"
+	DiffDelete DiffOperation = "delete"
+)
+
+// DiffChunk is a piece of text with the same kind of change.
+type DiffChunk struct {
+	Operation DiffOperation `json:"operation"`
+	Text      string        `json:"text"`
+}
+
+// PostRevisionDiff is a difference between two revisions of a post.
+type PostRevisionDiff struct {
+	PostID  int         `json:"post_id"`
+	From    int         `json:"from"`
+	To      int         `json:"to"`
+	Title   []DiffChunk `json:"title"`
+	Content []DiffChunk `json:"content"`
+}
@@ -2,6 +2,7 @@ package internal
 
 import (
 	"context"
+	"io"
 
 	"github.com/google/uuid"
 	"github.com/oustrix/ozon_journal/internal/entity"
@@ -10,27 +11,114 @@ import (
 // PostRepository is an interface of a post repository layer.
 type PostRepository interface {
 	GetPosts(ctx context.Context, page uint, amount uint) (*[]entity.Post, error)
+	GetUnpublishedPosts(ctx context.Context, authorID int, page uint, amount uint) (*[]entity.Post, error)
+	GetPostsByAuthorID(ctx context.Context, authorID int, page uint, amount uint) (*[]entity.Post, error)
 	GetPostByID(ctx context.Context, id int) (*entity.Post, error)
+	GetPostBySlug(ctx context.Context, slug string) (*entity.Post, error)
 	CreatePost(ctx context.Context, post *entity.Post) (*entity.Post, error)
+	UpdatePostStatus(ctx context.Context, post *entity.Post) (*entity.Post, error)
+	PublishDuePosts(ctx context.Context, now int) ([]entity.Post, error)
+	UpdatePost(ctx context.Context, revision *entity.PostRevision, slug string) (*entity.Post, error)
+	GetPostRevisions(ctx context.Context, postID int) ([]entity.PostRevision, error)
+	GetPostRevision(ctx context.Context, postID int, version int) (*entity.PostRevision, error)
+	ListPosts(ctx context.Context, afterID int, amount uint) ([]entity.Post, error)
+	GetPostSlugs(ctx context.Context, postID int) ([]string, error)
+	ImportPost(ctx context.Context, post *entity.Post, revisions []entity.PostRevision, slugs []string) error
 }
 
 // PostService is an interface of a post service layer.
 type PostService interface {
 	GetPosts(ctx context.Context, page int, amount int) (*[]entity.Post, error)
-	GetPostByID(ctx context.Context, id int) (*entity.Post, error)
+	GetUnpublishedPosts(ctx context.Context, authorID int, page int, amount int) (*[]entity.Post, error)
+	GetPostsByAuthorID(ctx context.Context, authorID int, page int, amount int) (*[]entity.Post, error)
+	GetPostByID(ctx context.Context, id int, viewerID int) (*entity.Post, error)
+	GetPostBySlug(ctx context.Context, slug string, viewerID int) (*entity.Post, error)
 	CreatePost(ctx context.Context, post *entity.Post) (*entity.Post, error)
+	PublishPost(ctx context.Context, id int, authorID int, publishAt int) (*entity.Post, error)
+	PublishDuePosts(ctx context.Context) ([]entity.Post, error)
+	UpdatePost(ctx context.Context, id int, editorID int, title *string, content *string) (*entity.Post, error)
+	GetPostRevisions(ctx context.Context, postID int) ([]entity.PostRevision, error)
+	DiffPostRevisions(ctx context.Context, postID int, from int, to int, mode entity.DiffMode, viewerID int) (*entity.PostRevisionDiff, error)
+	RestorePostRevision(ctx context.Context, postID int, version int, editorID int) (*entity.Post, error)
+	AttachFile(ctx context.Context, postID int, uploaderID int, upload *entity.Upload) (*entity.Attachment, error)
+	GetAttachments(ctx context.Context, postID int) ([]entity.Attachment, error)
+	GetAttachment(ctx context.Context, id int, viewerID int) (*entity.Attachment, io.ReadCloser, error)
+	SubscribePosts(ctx context.Context) (<-chan *entity.Post, uuid.UUID, error)
+	UnsubscribePosts(ctx context.Context, subscriptionID uuid.UUID)
+}
+
+// PersistedQueryRepository is an interface of a repository of queries registered by their SHA-256 hashes.
+type PersistedQueryRepository interface {
+	// GetPersistedQuery returns a query by its hash or an empty string if it isn't registered.
+	GetPersistedQuery(ctx context.Context, hash string) (string, error)
+	SavePersistedQuery(ctx context.Context, hash string, query string) error
+}
+
+// AttachmentRepository is an interface of an attachment metadata repository layer.
+type AttachmentRepository interface {
+	CreateAttachment(ctx context.Context, attachment *entity.Attachment) (*entity.Attachment, error)
+	GetAttachmentByID(ctx context.Context, id int) (*entity.Attachment, error)
+	GetAttachmentsByPostID(ctx context.Context, postID int) ([]entity.Attachment, error)
+}
+
+// BlobStore is an interface of a storage of attachment contents.
+type BlobStore interface {
+	PutBlob(ctx context.Context, key string, content io.Reader, size int64, contentType string) error
+	GetBlob(ctx context.Context, key string) (io.ReadCloser, error)
+	DeleteBlob(ctx context.Context, key string) error
 }
 
 // CommentRepository is an interface of a comment repository layer.
 type CommentRepository interface {
 	GetCommentsByPostID(ctx context.Context, postID int, page uint, amount uint) (*[]entity.Comment, error)
+	GetCommentByID(ctx context.Context, id int) (*entity.Comment, error)
 	CreateComment(ctx context.Context, comment *entity.Comment) (*entity.Comment, error)
+	ListComments(ctx context.Context, afterID int, amount uint) ([]entity.Comment, error)
+	ImportComment(ctx context.Context, comment *entity.Comment) error
 }
 
 // CommentService is an interface of a comment service layer.
 type CommentService interface {
-	GetCommentsByPostID(ctx context.Context, postID int, page int, amount int) (*[]entity.Comment, error)
+	GetCommentsByPostID(ctx context.Context, postID int, viewerID int, page int, amount int) (*[]entity.Comment, error)
 	CreateComment(ctx context.Context, comment *entity.Comment) (*entity.Comment, error)
-	SubscribeComments(ctx context.Context, postID int) (<-chan *entity.Comment, uuid.UUID, error)
+	SubscribeComments(ctx context.Context, postIDs []int) (<-chan *entity.Comment, uuid.UUID, error)
+	SubscribeReplies(ctx context.Context, commentID int) (<-chan *entity.Comment, uuid.UUID, error)
 	UnsubscribeComments(ctx context.Context, subscriptionID uuid.UUID)
+	SubscribePresence(ctx context.Context, postID int, userID int) (<-chan *entity.Presence, uuid.UUID, error)
+	UnsubscribePresence(ctx context.Context, subscriptionID uuid.UUID)
+	SetTyping(ctx context.Context, postID int, userID int) error
+}
+
+// PresenceRepository is an interface of a presence events transport between application instances.
+type PresenceRepository interface {
+	PublishPresence(ctx context.Context, event *entity.PresenceEvent) error
+	ListenPresence(ctx context.Context) <-chan *entity.PresenceEvent
+}
+
+// ReactionRepository is an interface of a reaction repository layer.
+type ReactionRepository interface {
+	AddReaction(ctx context.Context, reaction *entity.Reaction) ([]entity.ReactionCount, bool, error)
+	RemoveReaction(ctx context.Context, reaction *entity.Reaction) ([]entity.ReactionCount, bool, error)
+	GetReactionCounts(ctx context.Context, targetType entity.ReactionTargetType, targetID int) ([]entity.ReactionCount, error)
+}
+
+// ReactionService is an interface of a reaction service layer.
+type ReactionService interface {
+	React(ctx context.Context, reaction *entity.Reaction) ([]entity.ReactionCount, error)
+	Unreact(ctx context.Context, reaction *entity.Reaction) ([]entity.ReactionCount, error)
+	GetReactionCounts(ctx context.Context, targetType entity.ReactionTargetType, targetID int) ([]entity.ReactionCount, error)
+	SubscribeReactions(ctx context.Context, targetType entity.ReactionTargetType, targetID int) (<-chan *entity.ReactionsChange, uuid.UUID, error)
+	UnsubscribeReactions(ctx context.Context, subscriptionID uuid.UUID)
+}
+
+// DumpService is an interface of a service exporting and importing all data of the journal.
+type DumpService interface {
+	Export(ctx context.Context, w io.Writer) (*entity.DumpStats, error)
+	Import(ctx context.Context, r io.Reader, dryRun bool) (*entity.DumpStats, error)
+}
+
+// RenderService is an interface of a content rendering service layer.
+type RenderService interface {
+	RenderPost(ctx context.Context, post *entity.Post) (string, error)
+	RenderComment(ctx context.Context, comment *entity.Comment) (string, error)
 }
@@ -0,0 +1,106 @@
+package journalctl
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/oustrix/ozon_journal/internal/service"
+	"github.com/urfave/cli/v2"
+)
+
+func (c *ctl) exportCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "export",
+		Usage: "write all posts and comments to a JSON Lines dump",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "file of the dump, stdout if not set",
+			},
+		},
+		Action: c.export,
+	}
+}
+
+func (c *ctl) importCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "import",
+		Usage:     "read posts and comments from a JSON Lines dump into the empty storage",
+		ArgsUsage: "[FILE]",
+		Description: "Reads the dump from FILE, or from stdin if FILE is \"-\" or not set. " +
+			"IDs and timestamps of the dump are kept.",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "only validate the dump",
+			},
+		},
+		Action: c.importDump,
+	}
+}
+
+func (c *ctl) export(cctx *cli.Context) error {
+	s, err := c.openStorage()
+	if err != nil {
+		return err
+	}
+	defer s.close()
+
+	var w io.Writer = cctx.App.Writer
+	if path := cctx.String("output"); path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create output: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	stats, err := service.NewDumpService(s.posts, s.comments, c.log).Export(cctx.Context, w)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cctx.App.ErrWriter, "exported %d posts and %d comments\n", stats.Posts, stats.Comments)
+
+	return nil
+}
+
+func (c *ctl) importDump(cctx *cli.Context) error {
+	if cctx.NArg() > 1 {
+		return fmt.Errorf("expected at most one file")
+	}
+
+	var r io.Reader = os.Stdin
+	if path := cctx.Args().First(); path != "" && path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open input: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	s, err := c.openStorage()
+	if err != nil {
+		return err
+	}
+	defer s.close()
+
+	dryRun := cctx.Bool("dry-run")
+
+	stats, err := service.NewDumpService(s.posts, s.comments, c.log).Import(cctx.Context, r, dryRun)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		fmt.Fprintf(cctx.App.Writer, "dump is valid: %d posts and %d comments\n", stats.Posts, stats.Comments)
+	} else {
+		fmt.Fprintf(cctx.App.Writer, "imported %d posts and %d comments\n", stats.Posts, stats.Comments)
+	}
+
+	return nil
+}
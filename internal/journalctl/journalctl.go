@@ -0,0 +1,76 @@
+package journalctl
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/oustrix/ozon_journal/config"
+	"github.com/oustrix/ozon_journal/internal/migration"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+	"github.com/urfave/cli/v2"
+)
+
+// ctl holds dependencies of commands. They are created after flags are parsed, before a command runs.
+type ctl struct {
+	cfg *config.Config
+	log *logger.Logger
+	db  *migration.Database // database of migrate commands
+}
+
+// New creates the CLI. It reads the same configuration as the server, so it must be run
+// from the same working directory and with the same environment.
+func New() *cli.App {
+	c := &ctl{}
+
+	return &cli.App{
+		Name:  "journalctl",
+		Usage: "manage the database and data of the journal",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "log-level",
+				Usage: "level of logs written to stderr",
+				Value: "warn",
+			},
+		},
+		Before: c.before,
+		Commands: []*cli.Command{
+			c.migrateCommand(),
+			c.seedCommand(),
+			c.exportCommand(),
+			c.importCommand(),
+		},
+	}
+}
+
+// before reads the configuration and creates the logger. Logs are written to stderr,
+// so they don't mix with output of commands.
+func (c *ctl) before(cctx *cli.Context) error {
+	cfg, err := config.NewConfig()
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	c.cfg = cfg
+
+	level := cctx.String("log-level")
+	if _, err := logger.ParseLevel(level); err != nil {
+		return err
+	}
+	c.log = logger.New(level, logger.Format(logger.FormatText), logger.Output(os.Stderr))
+
+	return nil
+}
+
+// database returns the database of the configured storage for running migrations.
+func (c *ctl) database() (*migration.Database, error) {
+	switch c.cfg.Storage.Type {
+	case "postgres":
+		if c.cfg.Postgres.DSN == "" {
+			return nil, fmt.Errorf("postgres DSN isn't set")
+		}
+		return migration.Postgres(&c.cfg.Postgres), nil
+	case "sqlite":
+		return migration.SQLite(&c.cfg.SQLite), nil
+	default:
+		return nil, fmt.Errorf("storage %q has no database", c.cfg.Storage.Type)
+	}
+}
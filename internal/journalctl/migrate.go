@@ -0,0 +1,135 @@
+package journalctl
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/oustrix/ozon_journal/internal/migration"
+	"github.com/urfave/cli/v2"
+)
+
+func (c *ctl) migrateCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "migrate",
+		Usage: "manage the database schema",
+		Before: func(*cli.Context) error {
+			db, err := c.database()
+			c.db = db
+			return err
+		},
+		Subcommands: []*cli.Command{
+			{
+				Name:   "up",
+				Usage:  "apply all migrations",
+				Action: c.migrateUp,
+			},
+			{
+				Name:  "down",
+				Usage: "revert migrations",
+				Flags: []cli.Flag{
+					&cli.UintFlag{
+						Name:  "steps",
+						Usage: "number of migrations to revert",
+						Value: 1,
+					},
+					&cli.BoolFlag{
+						Name:  "all",
+						Usage: "revert all migrations, dropping all data",
+					},
+				},
+				Action: c.migrateDown,
+			},
+			{
+				Name:   "status",
+				Usage:  "print the version of the database and the latest migration",
+				Action: c.migrateStatus,
+			},
+			{
+				Name:      "force",
+				Usage:     "set the version of the database without running migrations, e.g. after fixing a failed one by hand",
+				ArgsUsage: "VERSION",
+				Action:    c.migrateForce,
+			},
+		},
+	}
+}
+
+func (c *ctl) migrateUp(cctx *cli.Context) error {
+	err := migration.Up(c.db)
+	if err != nil {
+		return err
+	}
+
+	return c.migrateStatus(cctx)
+}
+
+func (c *ctl) migrateDown(cctx *cli.Context) error {
+	m, err := migration.New(c.db)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if cctx.Bool("all") {
+		err = m.Down()
+	} else {
+		err = m.Steps(-int(cctx.Uint("steps")))
+	}
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed to revert migrations: %w", err)
+	}
+
+	return c.migrateStatus(cctx)
+}
+
+func (c *ctl) migrateStatus(cctx *cli.Context) error {
+	m, err := migration.New(c.db)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	latest, err := migration.Latest(c.db)
+	if err != nil {
+		return err
+	}
+
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		fmt.Fprintf(cctx.App.Writer, "version: none\nlatest: %d\n", latest)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get version: %w", err)
+	}
+
+	fmt.Fprintf(cctx.App.Writer, "version: %d\nlatest: %d\ndirty: %t\n", version, latest, dirty)
+
+	return nil
+}
+
+func (c *ctl) migrateForce(cctx *cli.Context) error {
+	if cctx.NArg() != 1 {
+		return fmt.Errorf("expected a version")
+	}
+
+	version, err := strconv.Atoi(cctx.Args().First())
+	if err != nil || version < 0 {
+		return fmt.Errorf("version must be a non-negative integer")
+	}
+
+	m, err := migration.New(c.db)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	err = m.Force(version)
+	if err != nil {
+		return fmt.Errorf("failed to force version: %w", err)
+	}
+
+	return c.migrateStatus(cctx)
+}
@@ -0,0 +1,114 @@
+package journalctl
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/oustrix/ozon_journal/internal/entity"
+	"github.com/oustrix/ozon_journal/pkg/slug"
+	"github.com/urfave/cli/v2"
+)
+
+// seedWords are words of generated titles and contents.
+var seedWords = strings.Fields(`lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod tempor
+	incididunt ut labore et dolore magna aliqua enim ad minim veniam quis nostrud exercitation ullamco laboris
+	nisi aliquip ex ea commodo consequat duis aute irure in reprehenderit voluptate velit esse cillum fugiat`)
+
+func (c *ctl) seedCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "seed",
+		Usage: "create published posts and comments with generated contents, e.g. for local development",
+		Flags: []cli.Flag{
+			&cli.UintFlag{
+				Name:  "posts",
+				Usage: "number of posts",
+				Value: 10,
+			},
+			&cli.UintFlag{
+				Name:  "comments",
+				Usage: "number of comments of every post, about a third of them are replies",
+				Value: 5,
+			},
+			&cli.UintFlag{
+				Name:  "authors",
+				Usage: "number of authors, they get IDs starting from 1",
+				Value: 10,
+			},
+		},
+		Action: c.seed,
+	}
+}
+
+func (c *ctl) seed(cctx *cli.Context) error {
+	posts, comments, authors := int(cctx.Uint("posts")), int(cctx.Uint("comments")), int(cctx.Uint("authors"))
+	if authors == 0 {
+		return fmt.Errorf("authors must be positive")
+	}
+
+	s, err := c.openStorage()
+	if err != nil {
+		return err
+	}
+	defer s.close()
+
+	// Posts are published an hour apart, the last one an hour ago.
+	start := time.Now().Add(-time.Duration(posts) * time.Hour)
+
+	for i := 0; i < posts; i++ {
+		publishedAt := start.Add(time.Duration(i) * time.Hour)
+		title := seedText(3, 8)
+
+		post, err := s.posts.CreatePost(cctx.Context, &entity.Post{
+			Title:         title,
+			Slug:          slug.Make(title),
+			Content:       seedText(40, 200),
+			ContentFormat: entity.ContentFormatPlain,
+			PublishedAt:   int(publishedAt.Unix()),
+			AuthorID:      rand.IntN(authors) + 1,
+			Commentable:   true,
+			Status:        entity.PostStatusPublished,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create post: %w", err)
+		}
+
+		ids := make([]int, 0, comments)
+		for j := 0; j < comments; j++ {
+			// Root comments have ParentCommentID set to -1.
+			parentID := -1
+			if len(ids) > 0 && rand.IntN(3) == 0 {
+				parentID = ids[rand.IntN(len(ids))]
+			}
+
+			comment, err := s.comments.CreateComment(cctx.Context, &entity.Comment{
+				Content:         seedText(5, 40),
+				ContentFormat:   entity.ContentFormatPlain,
+				AuthorID:        rand.IntN(authors) + 1,
+				PostID:          post.ID,
+				PublishedAt:     int(publishedAt.Add(time.Duration(j+1) * time.Minute).Unix()),
+				ParentCommentID: parentID,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create comment: %w", err)
+			}
+			ids = append(ids, comment.ID)
+		}
+	}
+
+	fmt.Fprintf(cctx.App.Writer, "created %d posts and %d comments\n", posts, posts*comments)
+
+	return nil
+}
+
+// seedText returns from min to max random words, starting with a capital letter.
+func seedText(min, max int) string {
+	words := make([]string, min+rand.IntN(max-min+1))
+	for i := range words {
+		words[i] = seedWords[rand.IntN(len(seedWords))]
+	}
+
+	text := strings.Join(words, " ")
+	return strings.ToUpper(text[:1]) + text[1:]
+}
@@ -0,0 +1,54 @@
+package journalctl
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/oustrix/ozon_journal/internal"
+	postgresRepository "github.com/oustrix/ozon_journal/internal/repository/postgres"
+	sqliteRepository "github.com/oustrix/ozon_journal/internal/repository/sqlite"
+	"github.com/oustrix/ozon_journal/pkg/postgres"
+	"github.com/oustrix/ozon_journal/pkg/sqlite"
+)
+
+// storage is the storage of the server opened by a command.
+type storage struct {
+	posts    internal.PostRepository
+	comments internal.CommentRepository
+	close    func()
+}
+
+// openStorage opens the storage the server is configured with. Data of the in-memory storage
+// is lost with the process, so commands can't work with it.
+func (c *ctl) openStorage() (*storage, error) {
+	switch c.cfg.Storage.Type {
+	case "postgres":
+		pg, err := postgres.New(c.cfg.Postgres.DSN,
+			postgres.MaxPoolSize(int(c.cfg.Postgres.MaxPoolSize)),
+			postgres.ConnAttempts(int(c.cfg.Postgres.ConnAttempts)),
+			postgres.ConnTimeout(time.Duration(c.cfg.Postgres.ConnTimeout)*time.Second))
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+		}
+
+		return &storage{
+			posts:    postgresRepository.NewPostRepository(pg, c.log),
+			comments: postgresRepository.NewCommentRepository(pg, c.log),
+			close:    pg.Close,
+		}, nil
+	case "sqlite":
+		db, err := sqlite.New(c.cfg.SQLite.Path,
+			sqlite.BusyTimeout(time.Duration(c.cfg.SQLite.BusyTimeout)*time.Second))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open sqlite: %w", err)
+		}
+
+		return &storage{
+			posts:    sqliteRepository.NewPostRepository(db, c.log),
+			comments: sqliteRepository.NewCommentRepository(db, c.log),
+			close:    db.Close,
+		}, nil
+	default:
+		return nil, fmt.Errorf("storage %q isn't persistent", c.cfg.Storage.Type)
+	}
+}
@@ -0,0 +1,186 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/oustrix/ozon_journal/config"
+	"github.com/oustrix/ozon_journal/pkg/health"
+	"github.com/oustrix/ozon_journal/pkg/postgres"
+	"github.com/oustrix/ozon_journal/pkg/sqlite"
+	// migrate tools
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+)
+
+// Sources of migrations of the databases, relative to the working directory.
+const (
+	SourceURL       = "file://migrations"
+	SQLiteSourceURL = "file://migrations/sqlite"
+)
+
+// versionQuery selects the version of the database from the table of golang-migrate.
+const versionQuery = "SELECT version, dirty FROM schema_migrations"
+
+// Database is a database migrations are applied to.
+type Database struct {
+	Name         string // used in errors
+	SourceURL    string
+	URL          string
+	ConnAttempts uint
+	ConnTimeout  uint   // in seconds
+	Directory    string // created before connecting if set, for databases in files
+}
+
+// Postgres returns the postgres database of the configuration.
+func Postgres(cfg *config.Postgres) *Database {
+	return &Database{
+		Name:         "postgres",
+		SourceURL:    SourceURL,
+		URL:          cfg.DSN,
+		ConnAttempts: cfg.ConnAttempts,
+		ConnTimeout:  cfg.ConnTimeout,
+	}
+}
+
+// SQLite returns the sqlite database of the configuration. Opening of the file isn't retried.
+func SQLite(cfg *config.SQLite) *Database {
+	return &Database{
+		Name:         "sqlite",
+		SourceURL:    SQLiteSourceURL,
+		URL:          "sqlite://" + cfg.Path + "?" + sqlite.Params(time.Duration(cfg.BusyTimeout)*time.Second),
+		ConnAttempts: 1,
+		Directory:    filepath.Dir(cfg.Path),
+	}
+}
+
+// New connects to the database for running migrations. Connecting is retried like connecting of the pool.
+// The returned Migrate must be closed.
+func New(db *Database) (*migrate.Migrate, error) {
+	var (
+		attempts = db.ConnAttempts
+		err      error
+		m        *migrate.Migrate
+	)
+
+	if db.Directory != "" {
+		err = os.MkdirAll(db.Directory, 0o755)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create directory of %s: %w", db.Name, err)
+		}
+	}
+
+	// Try to connect to the database.
+	for attempts > 0 {
+		m, err = migrate.New(db.SourceURL, db.URL)
+		if err == nil {
+			break
+		}
+
+		time.Sleep(time.Duration(db.ConnTimeout) * time.Second)
+		attempts--
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", db.Name, err)
+	}
+
+	if m == nil {
+		return nil, fmt.Errorf("migration is nil. check attempts to connect to %s", db.Name)
+	}
+
+	return m, nil
+}
+
+// Up applies all migrations to the database
+func Up(db *Database) error {
+	m, err := New(db)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	// Apply migrations.
+	err = m.Up()
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+
+	return nil
+}
+
+// Latest returns the version of the last migration, which the database must be at.
+func Latest(db *Database) (uint, error) {
+	src, err := source.Open(db.SourceURL)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open migrations: %w", err)
+	}
+	defer src.Close()
+
+	version, err := src.First()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read migrations: %w", err)
+		}
+		version = next
+	}
+}
+
+// Check returns a readiness check failing if the database isn't at the expected version,
+// e.g. when a migration failed halfway or the database was migrated down.
+func Check(pg *postgres.Postgres, expected uint) health.Check {
+	return func(ctx context.Context) error {
+		var version uint
+		var dirty bool
+
+		err := pg.Pool.QueryRow(ctx, versionQuery).Scan(&version, &dirty)
+		if err != nil {
+			return fmt.Errorf("failed to get migration version: %w", err)
+		}
+
+		return checkVersion(version, dirty, expected)
+	}
+}
+
+// CheckSQLite is Check of the sqlite database.
+func CheckSQLite(s *sqlite.SQLite, expected uint) health.Check {
+	return func(ctx context.Context) error {
+		var version uint
+		var dirty bool
+
+		err := s.DB.QueryRowContext(ctx, versionQuery).Scan(&version, &dirty)
+		if err != nil {
+			return fmt.Errorf("failed to get migration version: %w", err)
+		}
+
+		return checkVersion(version, dirty, expected)
+	}
+}
+
+// checkVersion returns an error if the version isn't the expected one or is dirty.
+func checkVersion(version uint, dirty bool, expected uint) error {
+	if dirty {
+		return fmt.Errorf("migration %d is dirty", version)
+	}
+	if version != expected {
+		return fmt.Errorf("database is at migration %d, expected %d", version, expected)
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/oustrix/ozon_journal/internal"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+)
+
+// Ensure BlobStore implements internal.BlobStore.
+var _ internal.BlobStore = &BlobStore{}
+
+// BlobStore is a struct that keeps blobs as files in a directory. Keys are relative paths of the files.
+type BlobStore struct {
+	dir string
+	log *logger.Logger
+}
+
+// NewBlobStore creates a new BlobStore instance, creating the directory if it doesn't exist.
+func NewBlobStore(dir string, log *logger.Logger) (*BlobStore, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	return &BlobStore{dir: dir, log: log.With("layer", "repository", "storage", "filesystem")}, nil
+}
+
+// PutBlob writes a blob. The content is written to a temporary file first, so a failed upload
+// doesn't leave a partial blob behind.
+func (s *BlobStore) PutBlob(ctx context.Context, key string, content io.Reader, size int64, contentType string) error {
+	s.log.DebugContext(
+		ctx,
+		"PutBlob",
+		"key", key,
+		"size", size,
+	)
+
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	written, err := io.Copy(tmp, content)
+	if err != nil {
+		return fmt.Errorf("failed to write blob: %w", err)
+	}
+
+	if written != size {
+		return fmt.Errorf("blob size is %d bytes, expected %d", written, size)
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return fmt.Errorf("failed to write blob: %w", err)
+	}
+
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		return fmt.Errorf("failed to move blob: %w", err)
+	}
+
+	return nil
+}
+
+// GetBlob opens a blob for reading. The caller must close it.
+func (s *BlobStore) GetBlob(ctx context.Context, key string) (io.ReadCloser, error) {
+	s.log.DebugContext(
+		ctx,
+		"GetBlob",
+		"key", key,
+	)
+
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("blob %q not found", key)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to open blob: %w", err)
+	}
+
+	return file, nil
+}
+
+// DeleteBlob deletes a blob. Deleting a missing blob isn't an error.
+func (s *BlobStore) DeleteBlob(ctx context.Context, key string) error {
+	s.log.DebugContext(
+		ctx,
+		"DeleteBlob",
+		"key", key,
+	)
+
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete blob: %w", err)
+	}
+
+	return nil
+}
+
+// path returns the file path of a key, making sure it doesn't point outside the directory.
+func (s *BlobStore) path(key string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if key == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+
+	return filepath.Join(s.dir, clean), nil
+}
@@ -0,0 +1,114 @@
+package inmemory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/oustrix/ozon_journal/internal"
+	"github.com/oustrix/ozon_journal/internal/entity"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+)
+
+// Ensure AttachmentRepository implements internal.AttachmentRepository.
+var _ internal.AttachmentRepository = &AttachmentRepository{}
+
+// AttachmentRepository is a struct that manages attachment metadata in memory.
+type AttachmentRepository struct {
+	idCounter   int
+	attachments map[int]entity.Attachment
+	byPost      map[int][]int // IDs of attachments of a post in the order they were created.
+	mu          sync.RWMutex
+	persistence *Persistence // Set if changes are written to the WAL.
+	log         *logger.Logger
+}
+
+// NewAttachmentRepository creates a new AttachmentRepository instance.
+func NewAttachmentRepository(log *logger.Logger) *AttachmentRepository {
+	return &AttachmentRepository{
+		attachments: make(map[int]entity.Attachment),
+		byPost:      make(map[int][]int),
+		log:         log.With("layer", "repository", "storage", "inmemory"),
+	}
+}
+
+// CreateAttachment saves metadata of an attachment.
+func (r *AttachmentRepository) CreateAttachment(ctx context.Context, attachment *entity.Attachment) (*entity.Attachment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Assign the next ID to the attachment, the counter is moved when the attachment is stored
+	attachment.ID = r.idCounter + 1
+
+	err := r.persistence.write(&walRecord{Op: opCreateAttachment, Attachment: attachment})
+	if err != nil {
+		return nil, err
+	}
+
+	r.store(attachment)
+
+	r.log.DebugContext(
+		ctx,
+		"CreateAttachment",
+		"attachmentID", attachment.ID,
+		"postID", attachment.PostID,
+	)
+
+	return attachment, nil
+}
+
+// store stores an attachment and moves the ID counter past its ID. It must be called with r.mu held.
+func (r *AttachmentRepository) store(attachment *entity.Attachment) {
+	r.attachments[attachment.ID] = *attachment
+	r.byPost[attachment.PostID] = append(r.byPost[attachment.PostID], attachment.ID)
+
+	if attachment.ID > r.idCounter {
+		r.idCounter = attachment.ID
+	}
+}
+
+// GetAttachmentByID returns an attachment by its ID.
+func (r *AttachmentRepository) GetAttachmentByID(ctx context.Context, id int) (*entity.Attachment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	r.log.DebugContext(
+		ctx,
+		"GetAttachmentByID",
+		"id", id,
+	)
+
+	attachment, ok := r.attachments[id]
+	if !ok {
+		return nil, entity.NotFoundError("attachment with ID %d not found", id)
+	}
+
+	return &attachment, nil
+}
+
+// GetAttachmentsByPostID returns attachments of a post, the oldest first.
+func (r *AttachmentRepository) GetAttachmentsByPostID(ctx context.Context, postID int) ([]entity.Attachment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	r.log.DebugContext(
+		ctx,
+		"GetAttachmentsByPostID",
+		"postID", postID,
+	)
+
+	ids := r.byPost[postID]
+	attachments := make([]entity.Attachment, 0, len(ids))
+	for _, id := range ids {
+		attachments = append(attachments, r.attachments[id])
+	}
+
+	return attachments, nil
+}
+
+// Len returns the number of attachments.
+func (r *AttachmentRepository) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.attachments)
+}
@@ -3,6 +3,7 @@ package inmemory
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/oustrix/ozon_journal/internal"
@@ -14,15 +15,16 @@ var _ internal.CommentRepository = &CommentRepository{}
 
 // CommentRepository is a repository for managing comments in memory
 type CommentRepository struct {
-	idCounter int
-	mu        sync.Mutex
-	log       *logger.Logger
+	idCounter   int
+	mu          sync.Mutex
+	persistence *Persistence // Set if changes are written to the WAL.
+	log         *logger.Logger
 }
 
 // NewCommentRepository creates a new instance of CommentRepository
 func NewCommentRepository(log *logger.Logger) *CommentRepository {
 	return &CommentRepository{
-		log: log,
+		log: log.With("layer", "repository", "storage", "inmemory"),
 	}
 }
 
@@ -30,7 +32,7 @@ func NewCommentRepository(log *logger.Logger) *CommentRepository {
 func (r *CommentRepository) GetCommentsByPostID(ctx context.Context, postID int, page uint, amount uint) (*[]entity.Comment, error) {
 	value, ok := postsStorage.Load(postID)
 	if !ok {
-		return nil, fmt.Errorf("post with ID %d not found", postID)
+		return nil, entity.NotFoundError("post with ID %d not found", postID)
 	}
 
 	// Extract post from sync.Map and type assert
@@ -43,19 +45,20 @@ func (r *CommentRepository) GetCommentsByPostID(ctx context.Context, postID int,
 	start := page * amount
 	end := start + amount
 
-	// Check if the end index is greater than the length of the comments slice
+	// Check if the indexes are greater than the length of the comments slice
+	if start > uint(len(post.Comments)) {
+		start = uint(len(post.Comments))
+	}
 	if end > uint(len(post.Comments)) {
 		end = uint(len(post.Comments))
 	}
 
-	r.log.Debug(
+	r.log.DebugContext(
+		ctx,
 		"GetCommentsByPostID",
-		"layer", "repository",
-		"store", "inmemory",
 		"post_id", postID,
 		"limit", end-start,
 		"offset", start,
-		"requestID", ctx.Value("requestID"),
 	)
 
 	posts := post.Comments[start:end]
@@ -64,15 +67,50 @@ func (r *CommentRepository) GetCommentsByPostID(ctx context.Context, postID int,
 	return &posts, nil
 }
 
+// GetCommentByID returns a comment with the specified ID
+func (r *CommentRepository) GetCommentByID(ctx context.Context, id int) (*entity.Comment, error) {
+	r.log.DebugContext(
+		ctx,
+		"GetCommentByID",
+		"comment_id", id,
+	)
+
+	var comment *entity.Comment
+
+	// Look for the comment in all posts
+	postsStorage.Range(func(key, value interface{}) bool {
+		post, ok := value.(entity.Post)
+		if !ok {
+			return true
+		}
+
+		for _, c := range post.Comments {
+			if c.ID == id {
+				comment = &c
+				return false
+			}
+		}
+		return true
+	})
+
+	if comment == nil {
+		return nil, entity.NotFoundError("comment with ID %d not found", id)
+	}
+
+	return comment, nil
+}
+
 // CreateComment creates a new comment for a post with the specified ID
 func (r *CommentRepository) CreateComment(ctx context.Context, comment *entity.Comment) (*entity.Comment, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	postsLock.Lock()
+	defer postsLock.Unlock()
 
 	// Load post from sync.Map
 	value, ok := postsStorage.Load(comment.PostID)
 	if !ok {
-		return nil, fmt.Errorf("post with ID %d not found", comment.PostID)
+		return nil, entity.NotFoundError("post with ID %d not found", comment.PostID)
 	}
 
 	// Extract post from sync.Map and type assert
@@ -81,26 +119,119 @@ func (r *CommentRepository) CreateComment(ctx context.Context, comment *entity.C
 		return nil, fmt.Errorf("failed to convert post with ID %d", comment.PostID)
 	}
 
+	// Don't reveal that an unpublished post exists.
+	if post.Status != entity.PostStatusPublished {
+		return nil, entity.NotFoundError("post with ID %d not found", comment.PostID)
+	}
+
 	if post.Commentable == false {
-		return nil, fmt.Errorf("post with ID %d is not commentable", comment.PostID)
+		return nil, entity.InvalidArgumentError("post with ID %d is not commentable", comment.PostID)
 	}
 
-	// Add comment to the post
-	r.idCounter++
-	comment.ID = r.idCounter
-	post.Comments = append(post.Comments, *comment)
+	// Assign the next ID to the comment, the counter is moved when the comment is stored
+	comment.ID = r.idCounter + 1
 
-	// Store the updated post back in the sync.Map
-	postsStorage.Store(post.ID, post)
+	err := r.persistence.write(&walRecord{Op: opCreateComment, Comment: comment})
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.storeComment(comment)
+	if err != nil {
+		return nil, err
+	}
 
-	r.log.Debug(
+	r.log.DebugContext(
+		ctx,
 		"CreateComment",
-		"layer", "repository",
-		"store", "inmemory",
 		"comment_id", comment.ID,
 		"post_id", comment.PostID,
-		"requestID", ctx.Value("requestID"),
 	)
 
 	return comment, nil
 }
+
+// ListComments returns comments of all posts with IDs greater than afterID, ordered by ID.
+// Pages are read by passing the ID of the last comment of the previous page.
+func (r *CommentRepository) ListComments(ctx context.Context, afterID int, amount uint) ([]entity.Comment, error) {
+	r.log.DebugContext(
+		ctx,
+		"ListComments",
+		"after_id", afterID,
+		"limit", amount,
+	)
+
+	// Collect comments after the ID from all posts
+	comments := make([]entity.Comment, 0)
+	postsStorage.Range(func(key, value interface{}) bool {
+		post, ok := value.(entity.Post)
+		if !ok {
+			return true
+		}
+
+		for _, c := range post.Comments {
+			if c.ID > afterID {
+				comments = append(comments, c)
+			}
+		}
+		return true
+	})
+
+	sort.Slice(comments, func(i, j int) bool {
+		return comments[i].ID < comments[j].ID
+	})
+
+	if uint(len(comments)) > amount {
+		comments = comments[:amount]
+	}
+
+	return comments, nil
+}
+
+// ImportComment saves a comment exported from another storage with its ID as it is. Imported comments
+// may belong to posts that aren't commentable anymore. The ID counter is moved past the ID,
+// so comments created later don't collide with imported ones.
+func (r *CommentRepository) ImportComment(ctx context.Context, comment *entity.Comment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	postsLock.Lock()
+	defer postsLock.Unlock()
+
+	r.log.DebugContext(
+		ctx,
+		"ImportComment",
+		"comment_id", comment.ID,
+		"post_id", comment.PostID,
+	)
+
+	_, err := loadPost(comment.PostID)
+	if err != nil {
+		return err
+	}
+
+	err = r.persistence.write(&walRecord{Op: opCreateComment, Comment: comment})
+	if err != nil {
+		return err
+	}
+
+	return r.storeComment(comment)
+}
+
+// storeComment adds a comment to its post and moves the ID counter past its ID.
+// It must be called with r.mu and postsLock held.
+func (r *CommentRepository) storeComment(comment *entity.Comment) error {
+	post, err := loadPost(comment.PostID)
+	if err != nil {
+		return err
+	}
+
+	// Copy comments, so slices returned earlier aren't changed.
+	post.Comments = append(post.Comments[:len(post.Comments):len(post.Comments)], *comment)
+	postsStorage.Store(post.ID, *post)
+
+	if comment.ID > r.idCounter {
+		r.idCounter = comment.ID
+	}
+
+	return nil
+}
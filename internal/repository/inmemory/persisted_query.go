@@ -0,0 +1,60 @@
+package inmemory
+
+import (
+	"context"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/oustrix/ozon_journal/internal"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+)
+
+// Ensure PersistedQueryRepository implements internal.PersistedQueryRepository.
+var _ internal.PersistedQueryRepository = &PersistedQueryRepository{}
+
+// PersistedQueryRepository is a struct that keeps recently used persisted queries in memory.
+// Queries used least recently are evicted when the size is exceeded, clients register them again.
+type PersistedQueryRepository struct {
+	queries *lru.Cache[string, string]
+	log     *logger.Logger
+}
+
+// NewPersistedQueryRepository creates a new PersistedQueryRepository instance keeping up to size queries.
+func NewPersistedQueryRepository(size int, log *logger.Logger) (*PersistedQueryRepository, error) {
+	queries, err := lru.New[string, string](size)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PersistedQueryRepository{queries: queries, log: log.With("layer", "repository", "storage", "inmemory")}, nil
+}
+
+// GetPersistedQuery returns a query by its hash or an empty string if it isn't registered.
+func (r *PersistedQueryRepository) GetPersistedQuery(ctx context.Context, hash string) (string, error) {
+	r.log.DebugContext(
+		ctx,
+		"GetPersistedQuery",
+		"hash", hash,
+	)
+
+	query, _ := r.queries.Get(hash)
+
+	return query, nil
+}
+
+// SavePersistedQuery registers a query by its hash.
+func (r *PersistedQueryRepository) SavePersistedQuery(ctx context.Context, hash string, query string) error {
+	r.log.DebugContext(
+		ctx,
+		"SavePersistedQuery",
+		"hash", hash,
+	)
+
+	r.queries.Add(hash, query)
+
+	return nil
+}
+
+// Len returns the number of cached queries.
+func (r *PersistedQueryRepository) Len() int {
+	return r.queries.Len()
+}
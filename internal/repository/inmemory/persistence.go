@@ -0,0 +1,404 @@
+package inmemory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/oustrix/ozon_journal/internal/entity"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+	"github.com/oustrix/ozon_journal/pkg/wal"
+)
+
+// Operations written to the WAL.
+const (
+	opCreatePost       = "create_post"        // Post, Revisions and Slugs, for created and imported posts.
+	opUpdatePost       = "update_post"        // Revision and Slug, if it was changed.
+	opUpdatePostStatus = "update_post_status" // Posts with ID, Status, PublishAt and PublishedAt.
+	opCreateComment    = "create_comment"     // Comment, for created and imported comments.
+	opAddReaction      = "add_reaction"       // Reaction.
+	opRemoveReaction   = "remove_reaction"    // Reaction.
+	opCreateAttachment = "create_attachment"  // Attachment.
+)
+
+const (
+	snapshotFile    = "snapshot.json"
+	snapshotVersion = 1
+)
+
+// walRecord is a change of the storage. It holds the result of the change rather than its arguments,
+// e.g. IDs and slugs assigned to created posts, so replaying it doesn't depend on the time or the state.
+type walRecord struct {
+	Op         string                `json:"op"`
+	Post       *entity.Post          `json:"post,omitempty"`
+	Posts      []entity.Post         `json:"posts,omitempty"`
+	Revision   *entity.PostRevision  `json:"revision,omitempty"`
+	Revisions  []entity.PostRevision `json:"revisions,omitempty"`
+	Slug       string                `json:"slug,omitempty"`
+	Slugs      []string              `json:"slugs,omitempty"`
+	Comment    *entity.Comment       `json:"comment,omitempty"`
+	Reaction   *entity.Reaction      `json:"reaction,omitempty"`
+	Attachment *entity.Attachment    `json:"attachment,omitempty"`
+}
+
+// snapshot is the whole state of the storage. Changes made after it are in WAL segments starting from Segment.
+type snapshot struct {
+	Version      int                           `json:"version"`
+	Segment      uint64                        `json:"segment"`
+	CreatedAt    int                           `json:"created_at"`
+	PostID       int                           `json:"post_id"` // ID counters of repositories.
+	CommentID    int                           `json:"comment_id"`
+	AttachmentID int                           `json:"attachment_id"`
+	Posts        []entity.Post                 `json:"posts"` // With comments.
+	Revisions    map[int][]entity.PostRevision `json:"revisions"`
+	Slugs        map[string]int                `json:"slugs"`
+	Reactions    []entity.Reaction             `json:"reactions"`
+	Attachments  []entity.Attachment           `json:"attachments"`
+}
+
+// Persistence keeps the in-memory storage in a directory, so data survives restarts.
+// Every change is appended to a write-ahead log before it is applied, and the whole storage is saved
+// to a snapshot periodically, after which older WAL segments are removed. On start the snapshot is loaded
+// and the WAL is replayed. Posts, comments, reactions and attachments are kept, presence isn't.
+// The directory must not be used by several processes at once.
+type Persistence struct {
+	dir         string
+	wal         *wal.WAL
+	posts       *PostRepository
+	comments    *CommentRepository
+	reactions   *ReactionRepository
+	attachments *AttachmentRepository
+
+	snapshotMu sync.Mutex   // Snapshots are taken one at a time.
+	changes    atomic.Int64 // Records written since the last snapshot.
+	log        *logger.Logger
+}
+
+// NewPersistence restores the storage from the directory into the empty repositories and makes them write
+// changes to the WAL. It must be called before the repositories are used.
+func NewPersistence(dir string, posts *PostRepository, comments *CommentRepository, reactions *ReactionRepository,
+	attachments *AttachmentRepository, log *logger.Logger, opts ...wal.Option) (*Persistence, error) {
+	p := &Persistence{
+		dir:         dir,
+		posts:       posts,
+		comments:    comments,
+		reactions:   reactions,
+		attachments: attachments,
+		log:         log.With("layer", "repository", "storage", "inmemory"),
+	}
+
+	started := time.Now()
+
+	from, err := p.loadSnapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	replayed := 0
+	p.wal, err = wal.Open(dir, from, func(data []byte) error {
+		replayed++
+		return p.replay(data)
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if truncated := p.wal.Truncated(); truncated > 0 {
+		p.log.Warn("Dropped incomplete last WAL record", "bytes", truncated)
+	}
+
+	// Replayed records are compacted by the first snapshot.
+	p.changes.Store(int64(replayed))
+
+	posts.persistence = p
+	comments.persistence = p
+	reactions.persistence = p
+	attachments.persistence = p
+
+	p.log.Info("In-memory storage restored", "directory", dir, "segment", from, "records", replayed,
+		"duration", time.Since(started).String())
+
+	return p, nil
+}
+
+// write appends a change to the WAL. It does nothing if the storage isn't persisted.
+func (p *Persistence) write(record *walRecord) error {
+	if p == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode WAL record: %w", err)
+	}
+
+	err = p.wal.Append(data)
+	if err != nil {
+		return fmt.Errorf("failed to write WAL record: %w", err)
+	}
+
+	p.changes.Add(1)
+
+	return nil
+}
+
+// replay applies a change read from the WAL. Records were validated before they were written,
+// so a change that can't be applied means the WAL doesn't match the snapshot.
+func (p *Persistence) replay(data []byte) error {
+	var record walRecord
+	err := json.Unmarshal(data, &record)
+	if err != nil {
+		return fmt.Errorf("failed to decode record: %w", err)
+	}
+
+	switch {
+	case record.Op == opCreatePost && record.Post != nil:
+		p.posts.storePost(record.Post, record.Revisions, record.Slugs)
+	case record.Op == opUpdatePost && record.Revision != nil:
+		_, err = addRevision(record.Revision, record.Slug)
+	case record.Op == opUpdatePostStatus:
+		for i := range record.Posts {
+			_, err = setPostStatus(&record.Posts[i])
+			if err != nil {
+				break
+			}
+		}
+	case record.Op == opCreateComment && record.Comment != nil:
+		err = p.comments.storeComment(record.Comment)
+	case record.Op == opAddReaction && record.Reaction != nil:
+		p.reactions.add(record.Reaction)
+	case record.Op == opRemoveReaction && record.Reaction != nil:
+		p.reactions.remove(record.Reaction)
+	case record.Op == opCreateAttachment && record.Attachment != nil:
+		p.attachments.store(record.Attachment)
+	default:
+		return fmt.Errorf("invalid record of operation %q", record.Op)
+	}
+
+	return err
+}
+
+// loadSnapshot loads the snapshot if the directory has one and returns the first WAL segment to replay.
+func (p *Persistence) loadSnapshot() (uint64, error) {
+	data, err := os.ReadFile(filepath.Join(p.dir, snapshotFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var s snapshot
+	err = json.Unmarshal(data, &s)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+
+	if s.Version != snapshotVersion {
+		return 0, fmt.Errorf("unsupported snapshot version %d, expected %d", s.Version, snapshotVersion)
+	}
+
+	for slug, postID := range s.Slugs {
+		slugsStorage.Store(slug, postID)
+	}
+	for postID, revisions := range s.Revisions {
+		revisionsStorage.Store(postID, revisions)
+	}
+	for _, post := range s.Posts {
+		postsStorage.Store(post.ID, post)
+	}
+	for i := range s.Reactions {
+		p.reactions.add(&s.Reactions[i])
+	}
+	for i := range s.Attachments {
+		p.attachments.store(&s.Attachments[i])
+	}
+
+	p.posts.idCounter = s.PostID
+	p.comments.idCounter = s.CommentID
+	p.attachments.idCounter = s.AttachmentID
+
+	return s.Segment, nil
+}
+
+// Snapshot saves the whole storage to the snapshot and removes WAL segments it covers.
+// Changes are blocked only while the storage is copied, the snapshot is written after that.
+// It does nothing if there were no changes since the last snapshot.
+func (p *Persistence) Snapshot() error {
+	p.snapshotMu.Lock()
+	defer p.snapshotMu.Unlock()
+
+	if p.changes.Load() == 0 {
+		return nil
+	}
+
+	started := time.Now()
+
+	s, err := p.copyStorage()
+	if err != nil {
+		return err
+	}
+
+	err = p.writeSnapshot(s)
+	if err != nil {
+		// Segments of the copied changes are kept, the next snapshot must cover them.
+		p.changes.Add(1)
+		return err
+	}
+
+	// The snapshot replaces older segments only after it is on the disk.
+	err = p.wal.Remove(s.Segment)
+	if err != nil {
+		return err
+	}
+
+	p.log.Info("In-memory storage snapshot saved", "segment", s.Segment, "posts", len(s.Posts),
+		"duration", time.Since(started).String())
+
+	return nil
+}
+
+// copyStorage copies the storage and starts a new WAL segment for changes made after the copy.
+// Locks of all repositories are taken in the order changes take them, so no change is in progress.
+func (p *Persistence) copyStorage() (*snapshot, error) {
+	p.posts.mu.Lock()
+	defer p.posts.mu.Unlock()
+	p.comments.mu.Lock()
+	defer p.comments.mu.Unlock()
+	postsLock.Lock()
+	defer postsLock.Unlock()
+	p.reactions.mu.RLock()
+	defer p.reactions.mu.RUnlock()
+	p.attachments.mu.RLock()
+	defer p.attachments.mu.RUnlock()
+
+	s := &snapshot{
+		Version:      snapshotVersion,
+		CreatedAt:    int(time.Now().Unix()),
+		PostID:       p.posts.idCounter,
+		CommentID:    p.comments.idCounter,
+		AttachmentID: p.attachments.idCounter,
+		Posts:        make([]entity.Post, 0),
+		Revisions:    make(map[int][]entity.PostRevision),
+		Slugs:        make(map[string]int),
+		Reactions:    make([]entity.Reaction, 0),
+		Attachments:  make([]entity.Attachment, 0, len(p.attachments.attachments)),
+	}
+
+	// Stored slices aren't changed in place, so they are shared with the storage instead of copied.
+	postsStorage.Range(func(key, value interface{}) bool {
+		if post, ok := value.(entity.Post); ok {
+			s.Posts = append(s.Posts, post)
+		}
+		return true
+	})
+	revisionsStorage.Range(func(key, value interface{}) bool {
+		postID, ok := key.(int)
+		revisions, ok2 := value.([]entity.PostRevision)
+		if ok && ok2 {
+			s.Revisions[postID] = revisions
+		}
+		return true
+	})
+	slugsStorage.Range(func(key, value interface{}) bool {
+		slug, ok := key.(string)
+		postID, ok2 := value.(int)
+		if ok && ok2 {
+			s.Slugs[slug] = postID
+		}
+		return true
+	})
+
+	// Reactions are kept in maps changed in place, so they are copied.
+	for target, emojis := range p.reactions.reactions {
+		for emoji, users := range emojis {
+			for userID := range users {
+				s.Reactions = append(s.Reactions, entity.Reaction{
+					TargetType: target.targetType,
+					TargetID:   target.targetID,
+					UserID:     userID,
+					Emoji:      emoji,
+				})
+			}
+		}
+	}
+	for _, attachment := range p.attachments.attachments {
+		s.Attachments = append(s.Attachments, attachment)
+	}
+
+	segment, err := p.wal.Rotate()
+	if err != nil {
+		return nil, err
+	}
+	s.Segment = segment
+	p.changes.Store(0)
+
+	return s, nil
+}
+
+// writeSnapshot replaces the snapshot file atomically, so a crash leaves either the old or the new snapshot.
+func (p *Persistence) writeSnapshot(s *snapshot) error {
+	path := filepath.Join(p.dir, snapshotFile)
+	tmp := path + ".tmp"
+
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+
+	err = json.NewEncoder(f).Encode(s)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	err = os.Rename(tmp, path)
+	if err != nil {
+		return fmt.Errorf("failed to replace snapshot: %w", err)
+	}
+
+	return wal.SyncDir(p.dir)
+}
+
+// Run saves snapshots every interval until the context is cancelled.
+func (p *Persistence) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := p.Snapshot()
+			if err != nil {
+				p.log.Error("Failed to save in-memory storage snapshot", "error", err.Error())
+			}
+		}
+	}
+}
+
+// Check returns an error if changes can't be written to the WAL anymore, e.g. after a failed fsync.
+func (p *Persistence) Check(context.Context) error {
+	return p.wal.Err()
+}
+
+// Close saves a snapshot, so the next start doesn't replay the WAL, and closes the WAL.
+// Repositories must not be changed after Close.
+func (p *Persistence) Close() error {
+	snapshotErr := p.Snapshot()
+	err := p.wal.Close()
+
+	return errors.Join(snapshotErr, err)
+}
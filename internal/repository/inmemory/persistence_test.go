@@ -0,0 +1,273 @@
+package inmemory
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/oustrix/ozon_journal/internal/entity"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+	"github.com/oustrix/ozon_journal/pkg/wal"
+)
+
+// testRepositories are repositories of a storage restored from a directory.
+type testRepositories struct {
+	posts       *PostRepository
+	comments    *CommentRepository
+	reactions   *ReactionRepository
+	attachments *AttachmentRepository
+	persistence *Persistence
+}
+
+// openStorage clears the storage, as on a restart, and restores it from dir.
+func openStorage(t *testing.T, dir string) *testRepositories {
+	t.Helper()
+
+	for _, storage := range []*sync.Map{&postsStorage, &revisionsStorage, &slugsStorage} {
+		storage.Range(func(key, _ any) bool {
+			storage.Delete(key)
+			return true
+		})
+	}
+
+	log := logger.New("error", logger.Output(io.Discard))
+	r := &testRepositories{
+		posts:       NewPostRepository(log),
+		comments:    NewCommentRepository(log),
+		reactions:   NewReactionRepository(log),
+		attachments: NewAttachmentRepository(log),
+	}
+
+	var err error
+	r.persistence, err = NewPersistence(dir, r.posts, r.comments, r.reactions, r.attachments, log, wal.Sync(wal.SyncNever))
+	if err != nil {
+		t.Fatalf("NewPersistence: %v", err)
+	}
+
+	return r
+}
+
+// crash closes the WAL without saving a snapshot.
+func (r *testRepositories) crash(t *testing.T) {
+	t.Helper()
+
+	err := r.persistence.wal.Close()
+	if err != nil {
+		t.Fatalf("failed to close WAL: %v", err)
+	}
+}
+
+func (r *testRepositories) createPost(t *testing.T, title string) *entity.Post {
+	t.Helper()
+
+	post, err := r.posts.CreatePost(context.Background(), &entity.Post{
+		Title:         title,
+		Slug:          title,
+		Content:       "Content",
+		ContentFormat: entity.ContentFormatPlain,
+		AuthorID:      1,
+		Commentable:   true,
+		Status:        entity.PostStatusPublished,
+		PublishedAt:   1700000000,
+	})
+	if err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+
+	return post
+}
+
+func (r *testRepositories) createComment(t *testing.T, postID int) *entity.Comment {
+	t.Helper()
+
+	comment, err := r.comments.CreateComment(context.Background(), &entity.Comment{
+		PostID:          postID,
+		ParentCommentID: -1,
+		Content:         "Comment",
+		ContentFormat:   entity.ContentFormatPlain,
+		AuthorID:        2,
+	})
+	if err != nil {
+		t.Fatalf("CreateComment: %v", err)
+	}
+
+	return comment
+}
+
+func (r *testRepositories) react(t *testing.T, postID int) {
+	t.Helper()
+
+	_, _, err := r.reactions.AddReaction(context.Background(), &entity.Reaction{
+		TargetType: entity.ReactionTargetPost,
+		TargetID:   postID,
+		UserID:     3,
+		Emoji:      "👍",
+	})
+	if err != nil {
+		t.Fatalf("AddReaction: %v", err)
+	}
+}
+
+// checkStorage checks that the storage has the posts with a comment each and that the first post has a reaction.
+func (r *testRepositories) checkStorage(t *testing.T, titles ...string) {
+	t.Helper()
+
+	ctx := context.Background()
+	for i, title := range titles {
+		post, err := r.posts.GetPostBySlug(ctx, title)
+		if err != nil {
+			t.Fatalf("GetPostBySlug(%q): %v", title, err)
+		}
+		if post.ID != i+1 || post.Title != title {
+			t.Fatalf("post = %+v, want ID %d", post, i+1)
+		}
+
+		comments, err := r.comments.GetCommentsByPostID(ctx, post.ID, 0, 10)
+		if err != nil {
+			t.Fatalf("GetCommentsByPostID(%d): %v", post.ID, err)
+		}
+		if len(*comments) != 1 || (*comments)[0].ID != i+1 {
+			t.Fatalf("comments of post %d = %+v", post.ID, *comments)
+		}
+	}
+
+	counts, err := r.reactions.GetReactionCounts(ctx, entity.ReactionTargetPost, 1)
+	if err != nil {
+		t.Fatalf("GetReactionCounts: %v", err)
+	}
+	if len(counts) != 1 || counts[0].Count != 1 {
+		t.Fatalf("reaction counts = %+v", counts)
+	}
+
+	// ID counters continue after the restored data.
+	post := r.createPost(t, "next")
+	if post.ID != len(titles)+1 {
+		t.Fatalf("ID of a new post = %d, want %d", post.ID, len(titles)+1)
+	}
+}
+
+// segments returns paths of WAL segments in dir in the order they were written.
+func segments(t *testing.T, dir string) []string {
+	t.Helper()
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.wal"))
+	if err != nil {
+		t.Fatalf("failed to list segments: %v", err)
+	}
+	sort.Strings(paths)
+
+	return paths
+}
+
+func TestPersistenceReplay(t *testing.T) {
+	dir := t.TempDir()
+
+	r := openStorage(t, dir)
+	post := r.createPost(t, "first")
+	r.createComment(t, post.ID)
+	r.react(t, post.ID)
+	r.crash(t)
+
+	if _, err := os.Stat(filepath.Join(dir, snapshotFile)); !os.IsNotExist(err) {
+		t.Fatalf("snapshot is saved before a restart: %v", err)
+	}
+
+	r = openStorage(t, dir)
+	defer r.crash(t)
+	r.checkStorage(t, "first")
+}
+
+func TestPersistenceSnapshotAndReplay(t *testing.T) {
+	dir := t.TempDir()
+
+	r := openStorage(t, dir)
+	post := r.createPost(t, "first")
+	r.createComment(t, post.ID)
+	r.react(t, post.ID)
+
+	err := r.persistence.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	if paths := segments(t, dir); len(paths) != 1 {
+		t.Fatalf("segments after a snapshot = %q, want only the current one", paths)
+	}
+
+	// Changes after the snapshot are only in the WAL.
+	post = r.createPost(t, "second")
+	r.createComment(t, post.ID)
+	r.crash(t)
+
+	r = openStorage(t, dir)
+	defer r.crash(t)
+	r.checkStorage(t, "first", "second")
+}
+
+func TestPersistenceClose(t *testing.T) {
+	dir := t.TempDir()
+
+	r := openStorage(t, dir)
+	post := r.createPost(t, "first")
+	r.createComment(t, post.ID)
+	r.react(t, post.ID)
+
+	err := r.persistence.Close()
+	if err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// Nothing is left to replay after the snapshot saved by Close.
+	r = openStorage(t, dir)
+	defer r.crash(t)
+	if changes := r.persistence.changes.Load(); changes != 0 {
+		t.Fatalf("replayed %d records, want 0", changes)
+	}
+	r.checkStorage(t, "first")
+}
+
+func TestPersistenceTornTail(t *testing.T) {
+	dir := t.TempDir()
+
+	r := openStorage(t, dir)
+	post := r.createPost(t, "first")
+	r.createComment(t, post.ID)
+	r.react(t, post.ID)
+	r.crash(t)
+
+	// A write interrupted by a crash leaves a part of a record at the end of the last segment.
+	paths := segments(t, dir)
+	f, err := os.OpenFile(paths[len(paths)-1], os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("failed to open segment: %v", err)
+	}
+	_, err = f.Write([]byte{42, 0, 0, 0, 1, 2})
+	if err != nil {
+		t.Fatalf("failed to write segment: %v", err)
+	}
+	err = f.Close()
+	if err != nil {
+		t.Fatalf("failed to close segment: %v", err)
+	}
+
+	r = openStorage(t, dir)
+	if r.persistence.wal.Truncated() != 6 {
+		t.Fatalf("Truncated = %d, want 6", r.persistence.wal.Truncated())
+	}
+	r.checkStorage(t, "first")
+	r.crash(t)
+
+	// Changes written after the truncation are restored too.
+	r = openStorage(t, dir)
+	defer r.crash(t)
+	post, err = r.posts.GetPostBySlug(context.Background(), "next")
+	if err != nil {
+		t.Fatalf("GetPostBySlug: %v", err)
+	}
+	if post.ID != 2 {
+		t.Fatalf("post = %+v, want ID 2", post)
+	}
+}
@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/oustrix/ozon_journal/internal"
 	"github.com/oustrix/ozon_journal/internal/entity"
 	"github.com/oustrix/ozon_journal/pkg/logger"
+	"github.com/oustrix/ozon_journal/pkg/slug"
 )
 
 // Ensure PostRepository implements internal.PostRepository.
@@ -16,19 +18,20 @@ var _ internal.PostRepository = &PostRepository{}
 
 // PostRepository is a struct that manages posts in the in-memory database.
 type PostRepository struct {
-	idCounter int
-	mu        sync.Mutex
-	log       *logger.Logger
+	idCounter   int
+	mu          sync.Mutex
+	persistence *Persistence // Set if changes are written to the WAL.
+	log         *logger.Logger
 }
 
 // NewPostRepository creates a new PostRepository instance.
 func NewPostRepository(log *logger.Logger) *PostRepository {
 	return &PostRepository{
-		log: log,
+		log: log.With("layer", "repository", "storage", "inmemory"),
 	}
 }
 
-// GetPosts returns a list of posts.
+// GetPosts returns a list of published posts without comments.
 func (r *PostRepository) GetPosts(ctx context.Context, page uint, amount uint) (*[]entity.Post, error) {
 	offset := int((page - 1) * amount)
 	limit := int(amount)
@@ -36,10 +39,11 @@ func (r *PostRepository) GetPosts(ctx context.Context, page uint, amount uint) (
 	// Create a slice to hold the posts
 	posts := make([]entity.Post, 0)
 
-	// Collect all posts from sync.Map
+	// Collect all published posts from sync.Map
 	postsStorage.Range(func(key, value interface{}) bool {
 		post, ok := value.(entity.Post)
-		if ok {
+		if ok && post.Status == entity.PostStatusPublished {
+			post.Comments = nil
 			posts = append(posts, post)
 		}
 		return true
@@ -60,13 +64,11 @@ func (r *PostRepository) GetPosts(ctx context.Context, page uint, amount uint) (
 		end = len(posts)
 	}
 
-	r.log.Debug(
+	r.log.DebugContext(
+		ctx,
 		"GetPosts",
-		"layer", "repository",
-		"storage", "inmemory",
 		"limit", end-start,
 		"offset", start,
-		"requestID", ctx.Value("requestID"),
 	)
 
 	paginatedPosts := posts[start:end]
@@ -74,20 +76,22 @@ func (r *PostRepository) GetPosts(ctx context.Context, page uint, amount uint) (
 	return &paginatedPosts, nil
 }
 
-// GetPostByID returns a post by its ID.
+// GetPostByID returns a post by its ID without comments.
 func (r *PostRepository) GetPostByID(ctx context.Context, id int) (*entity.Post, error) {
-	r.log.Debug(
+	r.log.DebugContext(
+		ctx,
 		"GetPostByID",
-		"layer", "repository",
-		"storage", "inmemory",
 		"id", id,
-		"requestID", ctx.Value("requestID"),
 	)
 
-	// Load post from sync.Map
+	return loadPost(id)
+}
+
+// loadPost loads a post from postsStorage.
+func loadPost(id int) (*entity.Post, error) {
 	value, ok := postsStorage.Load(id)
 	if !ok {
-		return nil, fmt.Errorf("post with ID %d not found", id)
+		return nil, entity.NotFoundError("post with ID %d not found", id)
 	}
 
 	// Check if the value is a post
@@ -95,29 +99,490 @@ func (r *PostRepository) GetPostByID(ctx context.Context, id int) (*entity.Post,
 	if !ok {
 		return nil, fmt.Errorf("failed to convert post with ID %d", id)
 	}
+	post.Comments = nil
 
 	return &post, nil
 }
 
-// CreatePost creates a new post.
+// GetUnpublishedPosts returns drafts and scheduled posts of an author, the most recently created first.
+func (r *PostRepository) GetUnpublishedPosts(ctx context.Context, authorID int, page uint, amount uint) (*[]entity.Post, error) {
+	offset := int((page - 1) * amount)
+	limit := int(amount)
+
+	// Collect unpublished posts of the author from sync.Map
+	posts := make([]entity.Post, 0)
+	postsStorage.Range(func(key, value interface{}) bool {
+		post, ok := value.(entity.Post)
+		if ok && post.AuthorID == authorID && post.Status != entity.PostStatusPublished {
+			posts = append(posts, post)
+		}
+		return true
+	})
+
+	// Sort posts by ID DESC, unpublished posts have no publication time
+	sort.Slice(posts, func(i, j int) bool {
+		return posts[i].ID > posts[j].ID
+	})
+
+	// Apply pagination
+	start := offset
+	end := offset + limit
+	if start > len(posts) {
+		start = len(posts)
+	}
+	if end > len(posts) {
+		end = len(posts)
+	}
+
+	r.log.DebugContext(
+		ctx,
+		"GetUnpublishedPosts",
+		"authorID", authorID,
+		"limit", end-start,
+		"offset", start,
+	)
+
+	paginatedPosts := posts[start:end]
+
+	return &paginatedPosts, nil
+}
+
+// GetPostsByAuthorID returns published posts of an author without comments.
+func (r *PostRepository) GetPostsByAuthorID(ctx context.Context, authorID int, page uint, amount uint) (*[]entity.Post, error) {
+	offset := int((page - 1) * amount)
+	limit := int(amount)
+
+	// Collect published posts of the author from sync.Map
+	posts := make([]entity.Post, 0)
+	postsStorage.Range(func(key, value interface{}) bool {
+		post, ok := value.(entity.Post)
+		if ok && post.AuthorID == authorID && post.Status == entity.PostStatusPublished {
+			post.Comments = nil
+			posts = append(posts, post)
+		}
+		return true
+	})
+
+	// Sort posts by PublishedAt DESC
+	sort.Slice(posts, func(i, j int) bool {
+		return posts[i].PublishedAt > posts[j].PublishedAt
+	})
+
+	// Apply pagination
+	start := offset
+	end := offset + limit
+	if start > len(posts) {
+		start = len(posts)
+	}
+	if end > len(posts) {
+		end = len(posts)
+	}
+
+	r.log.DebugContext(
+		ctx,
+		"GetPostsByAuthorID",
+		"authorID", authorID,
+		"limit", end-start,
+		"offset", start,
+	)
+
+	paginatedPosts := posts[start:end]
+
+	return &paginatedPosts, nil
+}
+
+// UpdatePostStatus updates the status and publication times of a post.
+func (r *PostRepository) UpdatePostStatus(ctx context.Context, post *entity.Post) (*entity.Post, error) {
+	postsLock.Lock()
+	defer postsLock.Unlock()
+
+	r.log.DebugContext(
+		ctx,
+		"UpdatePostStatus",
+		"postID", post.ID,
+		"status", post.Status,
+	)
+
+	_, err := loadPost(post.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := postStatus(post)
+	err = r.persistence.write(&walRecord{Op: opUpdatePostStatus, Posts: []entity.Post{status}})
+	if err != nil {
+		return nil, err
+	}
+
+	return setPostStatus(&status)
+}
+
+// PublishDuePosts publishes scheduled posts with publication time not later than now and returns them.
+func (r *PostRepository) PublishDuePosts(ctx context.Context, now int) ([]entity.Post, error) {
+	postsLock.Lock()
+	defer postsLock.Unlock()
+
+	statuses := make([]entity.Post, 0)
+	postsStorage.Range(func(key, value interface{}) bool {
+		post, ok := value.(entity.Post)
+		if ok && post.Status == entity.PostStatusScheduled && post.PublishAt <= now {
+			post.Status = entity.PostStatusPublished
+			post.PublishedAt = post.PublishAt
+			post.PublishAt = 0
+			statuses = append(statuses, postStatus(&post))
+		}
+		return true
+	})
+
+	r.log.DebugContext(
+		ctx,
+		"PublishDuePosts",
+		"amount", len(statuses),
+	)
+
+	if len(statuses) == 0 {
+		return []entity.Post{}, nil
+	}
+
+	// All due posts are published with a single record.
+	err := r.persistence.write(&walRecord{Op: opUpdatePostStatus, Posts: statuses})
+	if err != nil {
+		return nil, err
+	}
+
+	published := make([]entity.Post, 0, len(statuses))
+	for i := range statuses {
+		post, err := setPostStatus(&statuses[i])
+		if err != nil {
+			return nil, err
+		}
+		published = append(published, *post)
+	}
+
+	return published, nil
+}
+
+// postStatus returns the fields of a post changed by status updates. They are written to the WAL.
+func postStatus(post *entity.Post) entity.Post {
+	return entity.Post{
+		ID:          post.ID,
+		Status:      post.Status,
+		PublishAt:   post.PublishAt,
+		PublishedAt: post.PublishedAt,
+	}
+}
+
+// setPostStatus sets the status and publication times of a stored post. It must be called with postsLock held.
+func setPostStatus(status *entity.Post) (*entity.Post, error) {
+	post, err := loadPost(status.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	post.Status = status.Status
+	post.PublishAt = status.PublishAt
+	post.PublishedAt = status.PublishedAt
+	postsStorage.Store(post.ID, *post)
+
+	return post, nil
+}
+
+// UpdatePost sets the title and the content of a post from the revision and saves the revision
+// as the next version of the post.
+// If slug isn't empty, the post gets a new unique slug based on it, and the old one keeps leading to the post.
+func (r *PostRepository) UpdatePost(ctx context.Context, revision *entity.PostRevision, slug string) (*entity.Post, error) {
+	postsLock.Lock()
+	defer postsLock.Unlock()
+
+	r.log.DebugContext(
+		ctx,
+		"UpdatePost",
+		"postID", revision.PostID,
+	)
+
+	post, err := loadPost(revision.PostID)
+	if err != nil {
+		return nil, err
+	}
+
+	revision.Version = post.Revision + 1
+	if slug != "" {
+		slug = freeSlug(post.ID, slug)
+	}
+
+	err = r.persistence.write(&walRecord{Op: opUpdatePost, Revision: revision, Slug: slug})
+	if err != nil {
+		return nil, err
+	}
+
+	return addRevision(revision, slug)
+}
+
+// addRevision sets the title and the content of a stored post from the revision and appends the revision.
+// If slug isn't empty, it becomes the current slug of the post. It must be called with postsLock held.
+func addRevision(revision *entity.PostRevision, slug string) (*entity.Post, error) {
+	post, err := loadPost(revision.PostID)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions, err := loadRevisions(revision.PostID)
+	if err != nil {
+		return nil, err
+	}
+
+	post.Title = revision.Title
+	post.Content = revision.Content
+	post.Revision = revision.Version
+	if slug != "" {
+		slugsStorage.Store(slug, post.ID)
+		post.Slug = slug
+	}
+
+	// Copy revisions, so slices returned earlier aren't changed.
+	revisions = append(revisions[:len(revisions):len(revisions)], *revision)
+
+	postsStorage.Store(post.ID, *post)
+	revisionsStorage.Store(post.ID, revisions)
+
+	return post, nil
+}
+
+// GetPostRevisions returns all revisions of a post, the oldest first.
+func (r *PostRepository) GetPostRevisions(ctx context.Context, postID int) ([]entity.PostRevision, error) {
+	r.log.DebugContext(
+		ctx,
+		"GetPostRevisions",
+		"postID", postID,
+	)
+
+	revisions, err := loadRevisions(postID)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]entity.PostRevision(nil), revisions...), nil
+}
+
+// GetPostRevision returns a revision of a post by its version.
+func (r *PostRepository) GetPostRevision(ctx context.Context, postID int, version int) (*entity.PostRevision, error) {
+	r.log.DebugContext(
+		ctx,
+		"GetPostRevision",
+		"postID", postID,
+		"version", version,
+	)
+
+	revisions, err := loadRevisions(postID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Versions go in order from 1 without gaps.
+	if version < 1 || version > len(revisions) {
+		return nil, entity.NotFoundError("revision %d of post with ID %d not found", version, postID)
+	}
+
+	revision := revisions[version-1]
+	return &revision, nil
+}
+
+// loadRevisions loads revisions of a post from revisionsStorage.
+func loadRevisions(postID int) ([]entity.PostRevision, error) {
+	value, ok := revisionsStorage.Load(postID)
+	if !ok {
+		return nil, entity.NotFoundError("post with ID %d not found", postID)
+	}
+
+	revisions, ok := value.([]entity.PostRevision)
+	if !ok {
+		return nil, fmt.Errorf("failed to convert revisions of post with ID %d", postID)
+	}
+
+	return revisions, nil
+}
+
+// GetPostBySlug returns a post by its current or earlier slug.
+func (r *PostRepository) GetPostBySlug(ctx context.Context, slug string) (*entity.Post, error) {
+	r.log.DebugContext(
+		ctx,
+		"GetPostBySlug",
+		"slug", slug,
+	)
+
+	value, ok := slugsStorage.Load(slug)
+	if !ok {
+		return nil, entity.NotFoundError("post with slug %q not found", slug)
+	}
+
+	id, ok := value.(int)
+	if !ok {
+		return nil, fmt.Errorf("failed to convert post ID of slug %q", slug)
+	}
+
+	return r.GetPostByID(ctx, id)
+}
+
+// freeSlug returns the first free slug of the candidates based on the slug for the post.
+// Slugs the post had before are free for it. It must be called with postsLock held,
+// and the slug is reserved by storing it in slugsStorage under the same lock.
+func freeSlug(postID int, base string) string {
+	for n := 1; ; n++ {
+		candidate := slug.WithSuffix(base, n)
+
+		owner, taken := slugsStorage.Load(candidate)
+		if !taken || owner == postID {
+			return candidate
+		}
+	}
+}
+
+// CreatePost creates a new post with a unique slug based on post.Slug.
 func (r *PostRepository) CreatePost(ctx context.Context, post *entity.Post) (*entity.Post, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	// Increment ID counter and assign to the post
-	r.idCounter++
-	post.ID = r.idCounter
+	postsLock.Lock()
+	defer postsLock.Unlock()
 
-	// Store the post in the sync.Map
-	postsStorage.Store(post.ID, *post)
+	// Assign the next ID to the post, the counter is moved when the post is stored
+	post.ID = r.idCounter + 1
+	post.Revision = 1
+	post.Slug = freeSlug(post.ID, post.Slug)
 
-	r.log.Debug(
+	revision := entity.PostRevision{
+		PostID:    post.ID,
+		Version:   post.Revision,
+		Title:     post.Title,
+		Content:   post.Content,
+		EditorID:  post.AuthorID,
+		CreatedAt: int(time.Now().Unix()),
+	}
+
+	revisions, slugs := []entity.PostRevision{revision}, []string{post.Slug}
+
+	err := r.persistence.write(&walRecord{Op: opCreatePost, Post: post, Revisions: revisions, Slugs: slugs})
+	if err != nil {
+		return nil, err
+	}
+
+	r.storePost(post, revisions, slugs)
+
+	r.log.DebugContext(
+		ctx,
 		"CreatePost",
-		"layer", "repository",
-		"storage", "inmemory",
 		"postID", post.ID,
-		"requestID", ctx.Value("requestID"),
 	)
 
 	return post, nil
 }
+
+// storePost stores a new post without comments with its revisions and slugs and moves the ID counter past its ID.
+// Slugs and revisions go first, so a visible post always has them. It must be called with r.mu and postsLock held.
+func (r *PostRepository) storePost(post *entity.Post, revisions []entity.PostRevision, slugs []string) {
+	for _, s := range slugs {
+		slugsStorage.Store(s, post.ID)
+	}
+	revisionsStorage.Store(post.ID, append([]entity.PostRevision(nil), revisions...))
+
+	stored := *post
+	stored.Comments = nil
+	postsStorage.Store(stored.ID, stored)
+
+	if post.ID > r.idCounter {
+		r.idCounter = post.ID
+	}
+}
+
+// ListPosts returns posts of all statuses with IDs greater than afterID, ordered by ID.
+// Pages are read by passing the ID of the last post of the previous page.
+func (r *PostRepository) ListPosts(ctx context.Context, afterID int, amount uint) ([]entity.Post, error) {
+	r.log.DebugContext(
+		ctx,
+		"ListPosts",
+		"afterID", afterID,
+		"limit", amount,
+	)
+
+	// Collect posts after the ID from sync.Map without comments, like the postgres repository does
+	posts := make([]entity.Post, 0)
+	postsStorage.Range(func(key, value interface{}) bool {
+		post, ok := value.(entity.Post)
+		if ok && post.ID > afterID {
+			post.Comments = nil
+			posts = append(posts, post)
+		}
+		return true
+	})
+
+	sort.Slice(posts, func(i, j int) bool {
+		return posts[i].ID < posts[j].ID
+	})
+
+	if uint(len(posts)) > amount {
+		posts = posts[:amount]
+	}
+
+	return posts, nil
+}
+
+// GetPostSlugs returns the current and earlier slugs of a post. Creation times of slugs aren't kept in memory,
+// so earlier slugs are sorted alphabetically, and the current slug goes last.
+func (r *PostRepository) GetPostSlugs(ctx context.Context, postID int) ([]string, error) {
+	r.log.DebugContext(
+		ctx,
+		"GetPostSlugs",
+		"postID", postID,
+	)
+
+	post, err := r.GetPostByID(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	slugs := make([]string, 0)
+	slugsStorage.Range(func(key, value interface{}) bool {
+		if s, ok := key.(string); ok && value == postID && s != post.Slug {
+			slugs = append(slugs, s)
+		}
+		return true
+	})
+	sort.Strings(slugs)
+
+	return append(slugs, post.Slug), nil
+}
+
+// ImportPost saves a post exported from another storage with its ID, revisions and slugs as they are.
+// The ID counter is moved past the ID, so posts created later don't collide with imported ones.
+func (r *PostRepository) ImportPost(ctx context.Context, post *entity.Post, revisions []entity.PostRevision, slugs []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	postsLock.Lock()
+	defer postsLock.Unlock()
+
+	r.log.DebugContext(
+		ctx,
+		"ImportPost",
+		"postID", post.ID,
+	)
+
+	if _, ok := postsStorage.Load(post.ID); ok {
+		return fmt.Errorf("post with ID %d already exists", post.ID)
+	}
+
+	for _, s := range slugs {
+		if owner, ok := slugsStorage.Load(s); ok {
+			return fmt.Errorf("slug %q is taken by post with ID %v", s, owner)
+		}
+	}
+
+	err := r.persistence.write(&walRecord{Op: opCreatePost, Post: post, Revisions: revisions, Slugs: slugs})
+	if err != nil {
+		return err
+	}
+
+	r.storePost(post, revisions, slugs)
+
+	return nil
+}
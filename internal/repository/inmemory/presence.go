@@ -0,0 +1,97 @@
+package inmemory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/oustrix/ozon_journal/internal"
+	"github.com/oustrix/ozon_journal/internal/entity"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+)
+
+// listenerBufferSize is a size of a listener channel buffer, so publishers don't wait for slow listeners.
+const listenerBufferSize = 64
+
+// Ensure PresenceRepository implements internal.PresenceRepository.
+var _ internal.PresenceRepository = &PresenceRepository{}
+
+// PresenceRepository delivers presence events to listeners in the same process.
+type PresenceRepository struct {
+	listeners map[*presenceListener]struct{}
+	mu        sync.Mutex
+	log       *logger.Logger
+}
+
+type presenceListener struct {
+	ch      chan *entity.PresenceEvent
+	done    <-chan struct{}
+	sending sync.WaitGroup // Publishers sending to ch, which is closed after they finish.
+}
+
+// NewPresenceRepository creates a new PresenceRepository instance.
+func NewPresenceRepository(log *logger.Logger) *PresenceRepository {
+	return &PresenceRepository{
+		listeners: make(map[*presenceListener]struct{}),
+		log:       log.With("layer", "repository", "storage", "inmemory"),
+	}
+}
+
+// PublishPresence sends a presence event to all listeners. The event is sent without holding the lock,
+// so a full listener doesn't block listening and publishing to others.
+func (r *PresenceRepository) PublishPresence(ctx context.Context, event *entity.PresenceEvent) error {
+	r.log.DebugContext(
+		ctx,
+		"PublishPresence",
+		"postID", event.PostID,
+		"kind", event.Kind,
+	)
+
+	r.mu.Lock()
+	listeners := make([]*presenceListener, 0, len(r.listeners))
+	for listener := range r.listeners {
+		listener.sending.Add(1)
+		listeners = append(listeners, listener)
+	}
+	r.mu.Unlock()
+
+	var err error
+	for _, listener := range listeners {
+		if err == nil {
+			select {
+			case listener.ch <- event:
+			case <-listener.done:
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+		}
+		listener.sending.Done()
+	}
+
+	return err
+}
+
+// ListenPresence returns a channel of presence events. The channel is closed when the context is done.
+func (r *PresenceRepository) ListenPresence(ctx context.Context) <-chan *entity.PresenceEvent {
+	listener := &presenceListener{
+		ch:   make(chan *entity.PresenceEvent, listenerBufferSize),
+		done: ctx.Done(),
+	}
+
+	r.mu.Lock()
+	r.listeners[listener] = struct{}{}
+	r.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		// Publishers that have taken the listener stop waiting for it once the context is done.
+		r.mu.Lock()
+		delete(r.listeners, listener)
+		r.mu.Unlock()
+
+		listener.sending.Wait()
+		close(listener.ch)
+	}()
+
+	return listener.ch
+}
@@ -0,0 +1,175 @@
+package inmemory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/oustrix/ozon_journal/internal"
+	"github.com/oustrix/ozon_journal/internal/entity"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+)
+
+// Ensure ReactionRepository implements internal.ReactionRepository.
+var _ internal.ReactionRepository = &ReactionRepository{}
+
+// reactionTarget identifies a post or a comment.
+type reactionTarget struct {
+	targetType entity.ReactionTargetType
+	targetID   int
+}
+
+// ReactionRepository is a struct that manages reactions in memory.
+type ReactionRepository struct {
+	// reactions stores IDs of users who left an emoji, grouped by target and emoji.
+	reactions   map[reactionTarget]map[string]map[int]struct{}
+	mu          sync.RWMutex
+	persistence *Persistence // Set if changes are written to the WAL.
+	log         *logger.Logger
+}
+
+// NewReactionRepository creates a new ReactionRepository instance.
+func NewReactionRepository(log *logger.Logger) *ReactionRepository {
+	return &ReactionRepository{
+		reactions: make(map[reactionTarget]map[string]map[int]struct{}),
+		log:       log.With("layer", "repository", "storage", "inmemory"),
+	}
+}
+
+// AddReaction adds a reaction and returns reaction counts of its target right after the change.
+// It returns false if the user has already left the same emoji.
+func (r *ReactionRepository) AddReaction(ctx context.Context, reaction *entity.Reaction) ([]entity.ReactionCount, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.log.DebugContext(
+		ctx,
+		"AddReaction",
+		"targetType", reaction.TargetType,
+		"targetID", reaction.TargetID,
+	)
+
+	target := reactionTarget{targetType: reaction.TargetType, targetID: reaction.TargetID}
+	if _, ok := r.reactions[target][reaction.Emoji][reaction.UserID]; ok {
+		return r.counts(target), false, nil
+	}
+
+	err := r.persistence.write(&walRecord{Op: opAddReaction, Reaction: reaction})
+	if err != nil {
+		return nil, false, err
+	}
+
+	r.add(reaction)
+
+	return r.counts(target), true, nil
+}
+
+// add stores a reaction. It must be called with r.mu held.
+func (r *ReactionRepository) add(reaction *entity.Reaction) {
+	target := reactionTarget{targetType: reaction.TargetType, targetID: reaction.TargetID}
+	emojis, ok := r.reactions[target]
+	if !ok {
+		emojis = make(map[string]map[int]struct{})
+		r.reactions[target] = emojis
+	}
+
+	users, ok := emojis[reaction.Emoji]
+	if !ok {
+		users = make(map[int]struct{})
+		emojis[reaction.Emoji] = users
+	}
+
+	users[reaction.UserID] = struct{}{}
+}
+
+// RemoveReaction removes a reaction and returns reaction counts of its target right after the change.
+// It returns false if there was no such reaction.
+func (r *ReactionRepository) RemoveReaction(ctx context.Context, reaction *entity.Reaction) ([]entity.ReactionCount, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.log.DebugContext(
+		ctx,
+		"RemoveReaction",
+		"targetType", reaction.TargetType,
+		"targetID", reaction.TargetID,
+	)
+
+	target := reactionTarget{targetType: reaction.TargetType, targetID: reaction.TargetID}
+	if _, ok := r.reactions[target][reaction.Emoji][reaction.UserID]; !ok {
+		return r.counts(target), false, nil
+	}
+
+	err := r.persistence.write(&walRecord{Op: opRemoveReaction, Reaction: reaction})
+	if err != nil {
+		return nil, false, err
+	}
+
+	r.remove(reaction)
+
+	return r.counts(target), true, nil
+}
+
+// remove deletes a reaction. It must be called with r.mu held.
+func (r *ReactionRepository) remove(reaction *entity.Reaction) {
+	target := reactionTarget{targetType: reaction.TargetType, targetID: reaction.TargetID}
+	users := r.reactions[target][reaction.Emoji]
+
+	// Remove empty maps, so removed reactions don't take memory.
+	delete(users, reaction.UserID)
+	if len(users) == 0 {
+		delete(r.reactions[target], reaction.Emoji)
+	}
+	if len(r.reactions[target]) == 0 {
+		delete(r.reactions, target)
+	}
+}
+
+// GetReactionCounts returns amounts of reactions of a post or a comment, most popular first.
+func (r *ReactionRepository) GetReactionCounts(ctx context.Context, targetType entity.ReactionTargetType, targetID int) ([]entity.ReactionCount, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	r.log.DebugContext(
+		ctx,
+		"GetReactionCounts",
+		"targetType", targetType,
+		"targetID", targetID,
+	)
+
+	return r.counts(reactionTarget{targetType: targetType, targetID: targetID}), nil
+}
+
+// counts returns amounts of reactions of the target, most popular first. It must be called with r.mu held.
+func (r *ReactionRepository) counts(target reactionTarget) []entity.ReactionCount {
+	emojis := r.reactions[target]
+	counts := make([]entity.ReactionCount, 0, len(emojis))
+	for emoji, users := range emojis {
+		counts = append(counts, entity.ReactionCount{Emoji: emoji, Count: len(users)})
+	}
+
+	// Sort the same way as the postgres repository does.
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Emoji < counts[j].Emoji
+	})
+
+	return counts
+}
+
+// Len returns the number of reactions of all targets.
+func (r *ReactionRepository) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	n := 0
+	for _, emojis := range r.reactions {
+		for _, users := range emojis {
+			n += len(users)
+		}
+	}
+
+	return n
+}
@@ -2,7 +2,48 @@ package inmemory
 
 import (
 	"sync"
+
+	"github.com/oustrix/ozon_journal/internal/entity"
 )
 
 // postsStorage is a sync.Map that stores posts and comments.
 var postsStorage = sync.Map{}
+
+// postsLock guards updates of posts in postsStorage, so concurrent updates of the same post aren't lost.
+var postsLock = sync.Mutex{}
+
+// revisionsStorage is a sync.Map that stores revisions of posts keyed by post ID.
+// Revisions are appended under postsLock together with the post update.
+var revisionsStorage = sync.Map{}
+
+// slugsStorage is a sync.Map that maps current and earlier slugs to IDs of their posts.
+// Slugs are assigned under postsLock.
+var slugsStorage = sync.Map{}
+
+// StorageSizes returns the numbers of posts, comments, revisions and slugs kept in memory.
+// Maps are ranged over without postsLock, so the numbers may be slightly off during updates.
+func StorageSizes() map[string]int {
+	sizes := map[string]int{"posts": 0, "comments": 0, "revisions": 0, "slugs": 0}
+
+	postsStorage.Range(func(_, value interface{}) bool {
+		if post, ok := value.(entity.Post); ok {
+			sizes["posts"]++
+			sizes["comments"] += len(post.Comments)
+		}
+		return true
+	})
+
+	revisionsStorage.Range(func(_, value interface{}) bool {
+		if revisions, ok := value.([]entity.PostRevision); ok {
+			sizes["revisions"] += len(revisions)
+		}
+		return true
+	})
+
+	slugsStorage.Range(func(_, _ interface{}) bool {
+		sizes["slugs"]++
+		return true
+	})
+
+	return sizes
+}
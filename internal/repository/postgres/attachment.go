@@ -0,0 +1,143 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/oustrix/ozon_journal/internal"
+	"github.com/oustrix/ozon_journal/internal/entity"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+	"github.com/oustrix/ozon_journal/pkg/postgres"
+)
+
+// Ensure AttachmentRepository implements internal.AttachmentRepository.
+var _ internal.AttachmentRepository = &AttachmentRepository{}
+
+// AttachmentRepository is a struct that manages attachment metadata in the database.
+type AttachmentRepository struct {
+	*postgres.Postgres
+	log *logger.Logger
+}
+
+// NewAttachmentRepository creates a new AttachmentRepository instance.
+func NewAttachmentRepository(postgres *postgres.Postgres, log *logger.Logger) *AttachmentRepository {
+	return &AttachmentRepository{Postgres: postgres, log: log.With("layer", "repository", "storage", "postgres")}
+}
+
+// attachmentColumns are columns of the attachments table in the order scanAttachment scans them.
+var attachmentColumns = []string{"id", "post_id", "key", "file_name", "content_type", "size", "uploader_id", "created_at"}
+
+// CreateAttachment saves metadata of an attachment.
+func (r *AttachmentRepository) CreateAttachment(ctx context.Context, attachment *entity.Attachment) (*entity.Attachment, error) {
+	ctx, span := tracer.Start(ctx, "AttachmentRepository.CreateAttachment")
+	defer span.End()
+
+	sql, args, err := r.Builder.Insert("attachments").
+		Columns(attachmentColumns[1:]...).
+		Values(attachment.PostID, attachment.Key, attachment.FileName, attachment.ContentType, attachment.Size,
+			attachment.UploaderID, attachment.CreatedAt).
+		Suffix("RETURNING id").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	err = r.Pool.QueryRow(ctx, sql, args...).Scan(&attachment.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	r.log.DebugContext(
+		ctx,
+		"CreateAttachment",
+		"attachmentID", attachment.ID,
+		"postID", attachment.PostID,
+	)
+
+	return attachment, nil
+}
+
+// GetAttachmentByID returns an attachment by its ID.
+func (r *AttachmentRepository) GetAttachmentByID(ctx context.Context, id int) (*entity.Attachment, error) {
+	ctx, span := tracer.Start(ctx, "AttachmentRepository.GetAttachmentByID")
+	defer span.End()
+
+	r.log.DebugContext(
+		ctx,
+		"GetAttachmentByID",
+		"id", id,
+	)
+
+	sql, args, err := r.Builder.Select(attachmentColumns...).
+		From("attachments").
+		Where("id = ?", id).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	attachment, err := scanAttachment(r.Pool.QueryRow(ctx, sql, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, entity.NotFoundError("attachment with id %d not found", id)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+// GetAttachmentsByPostID returns attachments of a post, the oldest first.
+func (r *AttachmentRepository) GetAttachmentsByPostID(ctx context.Context, postID int) ([]entity.Attachment, error) {
+	ctx, span := tracer.Start(ctx, "AttachmentRepository.GetAttachmentsByPostID")
+	defer span.End()
+
+	r.log.DebugContext(
+		ctx,
+		"GetAttachmentsByPostID",
+		"postID", postID,
+	)
+
+	sql, args, err := r.Builder.Select(attachmentColumns...).
+		From("attachments").
+		Where("post_id = ?", postID).
+		OrderBy("id").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	attachments := make([]entity.Attachment, 0)
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, *attachment)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", rows.Err())
+	}
+
+	return attachments, nil
+}
+
+// scanAttachment scans a row of attachmentColumns.
+func scanAttachment(row pgx.Row) (*entity.Attachment, error) {
+	var attachment entity.Attachment
+	err := row.Scan(&attachment.ID, &attachment.PostID, &attachment.Key, &attachment.FileName, &attachment.ContentType,
+		&attachment.Size, &attachment.UploaderID, &attachment.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan row: %w", err)
+	}
+
+	return &attachment, nil
+}
@@ -2,8 +2,10 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"github.com/jackc/pgx/v4"
 	"github.com/oustrix/ozon_journal/internal"
 	"github.com/oustrix/ozon_journal/internal/entity"
 	"github.com/oustrix/ozon_journal/internal/repository/postgres/model"
@@ -22,26 +24,28 @@ type CommentRepository struct {
 
 // NewCommentRepository creates a new CommentRepository instance.
 func NewCommentRepository(postgres *postgres.Postgres, log *logger.Logger) *CommentRepository {
-	return &CommentRepository{Postgres: postgres, log: log}
+	return &CommentRepository{Postgres: postgres, log: log.With("layer", "repository", "storage", "postgres")}
 }
 
-// GetCommentsByPostID returns all comments for a post.
+// GetCommentsByPostID returns comments of a post, the oldest first. Pages are numbered from 0.
 func (r *CommentRepository) GetCommentsByPostID(ctx context.Context, postID int, page uint, amount uint) (*[]entity.Comment, error) {
+	ctx, span := tracer.Start(ctx, "CommentRepository.GetCommentsByPostID")
+	defer span.End()
+
 	offset := page * amount
 
-	r.log.Debug(
+	r.log.DebugContext(
+		ctx,
 		"GetCommentsByPostID",
-		"layer", "repository",
-		"storage", "postgres",
 		"postID", postID,
 		"limit", amount,
 		"offset", offset,
-		"requestID", ctx.Value("requestID"),
 	)
 
-	sql, args, err := r.Builder.Select("id", "content", "author_id", "published_at", "parent_comment_id").
+	sql, args, err := r.Builder.Select("id", "content", "content_format", "author_id", "published_at", "parent_comment_id").
 		From("comments").
 		Where("post_id = ?", postID).
+		OrderBy("id").
 		Offset(uint64(offset)).
 		Limit(uint64(amount)).
 		ToSql()
@@ -58,7 +62,8 @@ func (r *CommentRepository) GetCommentsByPostID(ctx context.Context, postID int,
 	comments := make([]entity.Comment, 0)
 	for rows.Next() {
 		comment := &model.Comment{}
-		err = rows.Scan(&comment.ID, &comment.Content, &comment.AuthorID, &comment.PublishedAt, &comment.ParentCommentID)
+		err = rows.Scan(&comment.ID, &comment.Content, &comment.ContentFormat, &comment.AuthorID, &comment.PublishedAt,
+			&comment.ParentCommentID)
 		if err != nil {
 			return nil, err
 		}
@@ -68,9 +73,44 @@ func (r *CommentRepository) GetCommentsByPostID(ctx context.Context, postID int,
 	return &comments, nil
 }
 
+// GetCommentByID returns a comment by its ID.
+func (r *CommentRepository) GetCommentByID(ctx context.Context, id int) (*entity.Comment, error) {
+	ctx, span := tracer.Start(ctx, "CommentRepository.GetCommentByID")
+	defer span.End()
+
+	r.log.DebugContext(
+		ctx,
+		"GetCommentByID",
+		"id", id,
+	)
+
+	sql, args, err := r.Builder.Select("id", "content", "content_format", "author_id", "post_id", "published_at",
+		"parent_comment_id").
+		From("comments").
+		Where("id = ?", id).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	comment := &model.Comment{}
+	err = r.Pool.QueryRow(ctx, sql, args...).Scan(&comment.ID, &comment.Content, &comment.ContentFormat,
+		&comment.AuthorID, &comment.PostID, &comment.PublishedAt, &comment.ParentCommentID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, entity.NotFoundError("comment with id %d not found", id)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	return comment.ToEntity(), nil
+}
+
 // CreateComment creates a new comment.
 func (r *CommentRepository) CreateComment(ctx context.Context, comment *entity.Comment) (*entity.Comment, error) {
-	sql, args, err := r.Builder.Select("commentable").
+	ctx, span := tracer.Start(ctx, "CommentRepository.CreateComment")
+	defer span.End()
+
+	sql, args, err := r.Builder.Select("commentable", "status").
 		From("posts").
 		Where("id = ?", comment.PostID).
 		ToSql()
@@ -79,18 +119,26 @@ func (r *CommentRepository) CreateComment(ctx context.Context, comment *entity.C
 	}
 
 	var commentable bool
-	err = r.Pool.QueryRow(ctx, sql, args...).Scan(&commentable)
-	if err != nil {
-		return nil, err
+	var status string
+	err = r.Pool.QueryRow(ctx, sql, args...).Scan(&commentable, &status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, entity.NotFoundError("post with id %d not found", comment.PostID)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	// Don't reveal that an unpublished post exists.
+	if entity.PostStatus(status) != entity.PostStatusPublished {
+		return nil, entity.NotFoundError("post with id %d not found", comment.PostID)
 	}
 
 	if !commentable {
-		return nil, fmt.Errorf("post with id %d is not commentable", comment.PostID)
+		return nil, entity.InvalidArgumentError("post with id %d is not commentable", comment.PostID)
 	}
 
 	sql, args, err = r.Builder.Insert("comments").
-		Columns("content", "post_id", "author_id", "published_at", "parent_comment_id").
-		Values(comment.Content, comment.PostID, comment.AuthorID, comment.PublishedAt, comment.ParentCommentID).
+		Columns("content", "content_format", "post_id", "author_id", "published_at", "parent_comment_id").
+		Values(comment.Content, comment.ContentFormat, comment.PostID, comment.AuthorID, comment.PublishedAt, comment.ParentCommentID).
 		Suffix("RETURNING id").
 		ToSql()
 	if err != nil {
@@ -102,14 +150,97 @@ func (r *CommentRepository) CreateComment(ctx context.Context, comment *entity.C
 		return nil, err
 	}
 
-	r.log.Debug(
+	r.log.DebugContext(
+		ctx,
 		"CreateComment",
-		"layer", "repository",
-		"storage", "postgres",
 		"commentable", commentable,
 		"commentID", comment.ID,
-		"requestID", ctx.Value("requestID"),
 	)
 
 	return comment, nil
 }
+
+// ListComments returns comments of all posts with IDs greater than afterID, ordered by ID.
+// Pages are read by passing the ID of the last comment of the previous page.
+func (r *CommentRepository) ListComments(ctx context.Context, afterID int, amount uint) ([]entity.Comment, error) {
+	ctx, span := tracer.Start(ctx, "CommentRepository.ListComments")
+	defer span.End()
+
+	r.log.DebugContext(
+		ctx,
+		"ListComments",
+		"afterID", afterID,
+		"limit", amount,
+	)
+
+	sql, args, err := r.Builder.Select("id", "content", "content_format", "author_id", "post_id", "published_at",
+		"parent_comment_id").
+		From("comments").
+		Where("id > ?", afterID).
+		OrderBy("id").
+		Limit(uint64(amount)).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	comments := make([]entity.Comment, 0)
+	for rows.Next() {
+		comment := &model.Comment{}
+		err = rows.Scan(&comment.ID, &comment.Content, &comment.ContentFormat, &comment.AuthorID, &comment.PostID,
+			&comment.PublishedAt, &comment.ParentCommentID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		comments = append(comments, *comment.ToEntity())
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", rows.Err())
+	}
+
+	return comments, nil
+}
+
+// ImportComment saves a comment exported from another storage with its ID as it is. Imported comments
+// may belong to posts that aren't commentable anymore. The ID sequence is moved past the ID,
+// so comments created later don't collide with imported ones.
+func (r *CommentRepository) ImportComment(ctx context.Context, comment *entity.Comment) error {
+	ctx, span := tracer.Start(ctx, "CommentRepository.ImportComment")
+	defer span.End()
+
+	r.log.DebugContext(
+		ctx,
+		"ImportComment",
+		"commentID", comment.ID,
+	)
+
+	sql, args, err := r.Builder.Insert("comments").
+		Columns("id", "content", "content_format", "post_id", "author_id", "published_at", "parent_comment_id").
+		Values(comment.ID, comment.Content, comment.ContentFormat, comment.PostID, comment.AuthorID, comment.PublishedAt,
+			comment.ParentCommentID).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	return r.Pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, sql, args...)
+		if err != nil {
+			return fmt.Errorf("failed to execute query: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, "SELECT setval(pg_get_serial_sequence('comments', 'id'), (SELECT MAX(id) FROM comments))")
+		if err != nil {
+			return fmt.Errorf("failed to move id sequence: %w", err)
+		}
+
+		return nil
+	})
+}
@@ -10,6 +10,7 @@ import (
 type Comment struct {
 	ID              sql.NullInt32  `json:"id"`
 	Content         sql.NullString `json:"content"`
+	ContentFormat   sql.NullString `json:"content_format"`
 	AuthorID        sql.NullInt32  `json:"author_id"`
 	PostID          sql.NullInt32  `json:"post_id"`
 	PublishedAt     sql.NullInt64  `json:"published_at"`
@@ -21,6 +22,7 @@ func (c *Comment) ToEntity() *entity.Comment {
 	return &entity.Comment{
 		ID:              int(c.ID.Int32),
 		Content:         c.Content.String,
+		ContentFormat:   entity.ContentFormat(c.ContentFormat.String),
 		AuthorID:        int(c.AuthorID.Int32),
 		PostID:          int(c.PostID.Int32),
 		PublishedAt:     int(c.PublishedAt.Int64),
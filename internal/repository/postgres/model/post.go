@@ -8,13 +8,18 @@ import (
 
 // Post is a struct that represents a post in database.
 type Post struct {
-	ID          sql.NullInt32  `json:"id"`
-	Title       sql.NullString `json:"title"`
-	Content     sql.NullString `json:"content"`
-	PublishedAt sql.NullInt64  `json:"published_at"`
-	AuthorID    sql.NullInt32  `json:"author_id"`
-	Commentable sql.NullBool   `json:"commentable"`
-	Comments    []Comment      `json:"comments"`
+	ID            sql.NullInt32  `json:"id"`
+	Title         sql.NullString `json:"title"`
+	Slug          sql.NullString `json:"slug"`
+	Content       sql.NullString `json:"content"`
+	ContentFormat sql.NullString `json:"content_format"`
+	PublishedAt   sql.NullInt64  `json:"published_at"`
+	AuthorID      sql.NullInt32  `json:"author_id"`
+	Commentable   sql.NullBool   `json:"commentable"`
+	Status        sql.NullString `json:"status"`
+	PublishAt     sql.NullInt64  `json:"publish_at"`
+	Revision      sql.NullInt32  `json:"revision"`
+	Comments      []Comment      `json:"comments"`
 }
 
 // ToEntity converts a Post to an entity.Post.
@@ -25,12 +30,17 @@ func (p *Post) ToEntity() *entity.Post {
 	}
 
 	return &entity.Post{
-		ID:          int(p.ID.Int32),
-		Title:       p.Title.String,
-		Content:     p.Content.String,
-		PublishedAt: int(p.PublishedAt.Int64),
-		AuthorID:    int(p.AuthorID.Int32),
-		Commentable: p.Commentable.Bool,
-		Comments:    comments,
+		ID:            int(p.ID.Int32),
+		Title:         p.Title.String,
+		Slug:          p.Slug.String,
+		Content:       p.Content.String,
+		ContentFormat: entity.ContentFormat(p.ContentFormat.String),
+		PublishedAt:   int(p.PublishedAt.Int64),
+		AuthorID:      int(p.AuthorID.Int32),
+		Commentable:   p.Commentable.Bool,
+		Status:        entity.PostStatus(p.Status.String),
+		PublishAt:     int(p.PublishAt.Int64),
+		Revision:      int(p.Revision.Int32),
+		Comments:      comments,
 	}
 }
@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/oustrix/ozon_journal/internal"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+	"github.com/oustrix/ozon_journal/pkg/postgres"
+)
+
+// Ensure PersistedQueryRepository implements internal.PersistedQueryRepository.
+var _ internal.PersistedQueryRepository = &PersistedQueryRepository{}
+
+// PersistedQueryRepository is a struct that keeps persisted queries in the database,
+// so they are shared by all instances of the application and survive restarts.
+// Queries registered earliest are deleted when the size is exceeded, clients register them again.
+type PersistedQueryRepository struct {
+	*postgres.Postgres
+	size int
+	log  *logger.Logger
+}
+
+// NewPersistedQueryRepository creates a new PersistedQueryRepository instance keeping up to size queries.
+func NewPersistedQueryRepository(postgres *postgres.Postgres, size int, log *logger.Logger) *PersistedQueryRepository {
+	return &PersistedQueryRepository{
+		Postgres: postgres,
+		size:     size,
+		log:      log.With("layer", "repository", "storage", "postgres"),
+	}
+}
+
+// GetPersistedQuery returns a query by its hash or an empty string if it isn't registered.
+func (r *PersistedQueryRepository) GetPersistedQuery(ctx context.Context, hash string) (string, error) {
+	ctx, span := tracer.Start(ctx, "PersistedQueryRepository.GetPersistedQuery")
+	defer span.End()
+
+	r.log.DebugContext(
+		ctx,
+		"GetPersistedQuery",
+		"hash", hash,
+	)
+
+	sql, args, err := r.Builder.Select("query").
+		From("persisted_queries").
+		Where("hash = ?", hash).
+		ToSql()
+	if err != nil {
+		return "", fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	var query string
+	err = r.Pool.QueryRow(ctx, sql, args...).Scan(&query)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	return query, nil
+}
+
+// SavePersistedQuery registers a query by its hash and deletes the earliest queries exceeding the size.
+// Registering a query again does nothing.
+func (r *PersistedQueryRepository) SavePersistedQuery(ctx context.Context, hash string, query string) error {
+	ctx, span := tracer.Start(ctx, "PersistedQueryRepository.SavePersistedQuery")
+	defer span.End()
+
+	r.log.DebugContext(
+		ctx,
+		"SavePersistedQuery",
+		"hash", hash,
+	)
+
+	sql, args, err := r.Builder.Insert("persisted_queries").
+		Columns("hash", "query", "created_at").
+		Values(hash, query, time.Now().Unix()).
+		Suffix("ON CONFLICT (hash) DO NOTHING").
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	evictSQL, evictArgs, err := r.Builder.Delete("persisted_queries").
+		Where("hash IN (SELECT hash FROM persisted_queries ORDER BY created_at DESC, hash OFFSET ?)", r.size).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	return r.Pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, sql, args...)
+		if err != nil {
+			return fmt.Errorf("failed to execute query: %w", err)
+		}
+
+		// The size can be exceeded only by a new query.
+		if tag.RowsAffected() == 0 {
+			return nil
+		}
+
+		_, err = tx.Exec(ctx, evictSQL, evictArgs...)
+		if err != nil {
+			return fmt.Errorf("failed to evict queries: %w", err)
+		}
+
+		return nil
+	})
+}
@@ -2,13 +2,19 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
 	"github.com/oustrix/ozon_journal/internal"
 	"github.com/oustrix/ozon_journal/internal/entity"
 	"github.com/oustrix/ozon_journal/internal/repository/postgres/model"
 	"github.com/oustrix/ozon_journal/pkg/logger"
 	"github.com/oustrix/ozon_journal/pkg/postgres"
+	"github.com/oustrix/ozon_journal/pkg/slug"
 )
 
 // Ensure PostRepository implements internal.PostRepository.
@@ -22,38 +28,27 @@ type PostRepository struct {
 
 // NewPostRepository creates a new PostRepository instance.
 func NewPostRepository(postgres *postgres.Postgres, log *logger.Logger) *PostRepository {
-	return &PostRepository{Postgres: postgres, log: log}
+	return &PostRepository{Postgres: postgres, log: log.With("layer", "repository", "storage", "postgres")}
 }
 
-// GetPosts returns a list of posts.
+// GetPosts returns a page of published posts without comments, the latest first.
 func (r *PostRepository) GetPosts(ctx context.Context, page uint, amount uint) (*[]entity.Post, error) {
+	ctx, span := tracer.Start(ctx, "PostRepository.GetPosts")
+	defer span.End()
+
 	offset := int(page-1) * int(amount)
 
-	r.log.Debug(
+	r.log.DebugContext(
+		ctx,
 		"GetPosts",
-		"layer", "repository",
-		"storage", "postgres",
 		"limit", amount,
 		"offset", offset,
-		"requestID", ctx.Value("requestID"),
 	)
 
-	sql, args, err := r.Builder.Select(
-		"post.id",
-		"post.title",
-		"post.content",
-		"post.published_at",
-		"post.author_id",
-		"post.commentable",
-		"comment.id",
-		"comment.content",
-		"comment.post_id",
-		"comment.author_id",
-		"comment.published_at",
-		"comment.parent_comment_id").
-		From("posts post").
-		LeftJoin("comments comment ON post.id = comment.post_id").
-		OrderBy("post.published_at DESC").
+	sql, args, err := r.Builder.Select(postColumns...).
+		From("posts").
+		Where("status = ?", entity.PostStatusPublished).
+		OrderBy("published_at DESC", "id DESC").
 		Limit(uint64(amount)).
 		Offset(uint64(offset)).
 		ToSql()
@@ -61,86 +56,414 @@ func (r *PostRepository) GetPosts(ctx context.Context, page uint, amount uint) (
 		return nil, fmt.Errorf("failed to build sql: %w", err)
 	}
 
+	posts, err := r.queryPosts(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &posts, nil
+}
+
+// GetPostByID returns a post by its ID without comments.
+func (r *PostRepository) GetPostByID(ctx context.Context, id int) (*entity.Post, error) {
+	ctx, span := tracer.Start(ctx, "PostRepository.GetPostByID")
+	defer span.End()
+
+	r.log.DebugContext(
+		ctx,
+		"GetPostByID",
+		"id", id,
+	)
+
+	sql, args, err := r.Builder.Select(postColumns...).
+		From("posts").
+		Where("id = ?", id).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	post, err := scanPost(r.Pool.QueryRow(ctx, sql, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, entity.NotFoundError("post with id %d not found", id)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return post, nil
+}
+
+// GetUnpublishedPosts returns drafts and scheduled posts of an author, the most recently created first.
+func (r *PostRepository) GetUnpublishedPosts(ctx context.Context, authorID int, page uint, amount uint) (*[]entity.Post, error) {
+	ctx, span := tracer.Start(ctx, "PostRepository.GetUnpublishedPosts")
+	defer span.End()
+
+	offset := int(page-1) * int(amount)
+
+	r.log.DebugContext(
+		ctx,
+		"GetUnpublishedPosts",
+		"authorID", authorID,
+		"limit", amount,
+		"offset", offset,
+	)
+
+	sql, args, err := r.Builder.Select(postColumns...).
+		From("posts").
+		Where("author_id = ? AND status <> ?", authorID, entity.PostStatusPublished).
+		OrderBy("id DESC").
+		Limit(uint64(amount)).
+		Offset(uint64(offset)).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	posts, err := r.queryPosts(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &posts, nil
+}
+
+// GetPostsByAuthorID returns published posts of an author without comments.
+func (r *PostRepository) GetPostsByAuthorID(ctx context.Context, authorID int, page uint, amount uint) (*[]entity.Post, error) {
+	ctx, span := tracer.Start(ctx, "PostRepository.GetPostsByAuthorID")
+	defer span.End()
+
+	offset := int(page-1) * int(amount)
+
+	r.log.DebugContext(
+		ctx,
+		"GetPostsByAuthorID",
+		"authorID", authorID,
+		"limit", amount,
+		"offset", offset,
+	)
+
+	sql, args, err := r.Builder.Select(postColumns...).
+		From("posts").
+		Where("author_id = ? AND status = ?", authorID, entity.PostStatusPublished).
+		OrderBy("published_at DESC").
+		Limit(uint64(amount)).
+		Offset(uint64(offset)).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	posts, err := r.queryPosts(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &posts, nil
+}
+
+// UpdatePostStatus updates the status and publication times of a post.
+func (r *PostRepository) UpdatePostStatus(ctx context.Context, post *entity.Post) (*entity.Post, error) {
+	ctx, span := tracer.Start(ctx, "PostRepository.UpdatePostStatus")
+	defer span.End()
+
+	r.log.DebugContext(
+		ctx,
+		"UpdatePostStatus",
+		"id", post.ID,
+		"status", post.Status,
+	)
+
+	sql, args, err := r.Builder.Update("posts").
+		Set("status", post.Status).
+		Set("publish_at", nullInt(post.PublishAt)).
+		Set("published_at", post.PublishedAt).
+		Where("id = ?", post.ID).
+		Suffix("RETURNING " + strings.Join(postColumns, ", ")).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	posts, err := r.queryPosts(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(posts) == 0 {
+		return nil, entity.NotFoundError("post with id %d not found", post.ID)
+	}
+
+	return &posts[0], nil
+}
+
+// PublishDuePosts publishes scheduled posts with publication time not later than now and returns them.
+func (r *PostRepository) PublishDuePosts(ctx context.Context, now int) ([]entity.Post, error) {
+	ctx, span := tracer.Start(ctx, "PostRepository.PublishDuePosts")
+	defer span.End()
+
+	sql, args, err := r.Builder.Update("posts").
+		Set("status", entity.PostStatusPublished).
+		Set("published_at", squirrel.Expr("publish_at")).
+		Set("publish_at", nil).
+		Where("status = ? AND publish_at <= ?", entity.PostStatusScheduled, now).
+		Suffix("RETURNING " + strings.Join(postColumns, ", ")).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	posts, err := r.queryPosts(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	r.log.DebugContext(
+		ctx,
+		"PublishDuePosts",
+		"amount", len(posts),
+	)
+
+	return posts, nil
+}
+
+// postColumns are columns of the posts table in the order queryPosts scans them.
+var postColumns = []string{"id", "title", "slug", "content", "content_format", "published_at", "author_id", "commentable", "status", "publish_at",
+	"revision"}
+
+// queryPosts executes a query returning postColumns and returns the posts without comments.
+func (r *PostRepository) queryPosts(ctx context.Context, sql string, args ...interface{}) ([]entity.Post, error) {
 	rows, err := r.Pool.Query(ctx, sql, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
 	defer rows.Close()
 
-	posts := make([]entity.Post, 0, amount)
-	postsComments := make(map[int][]entity.Comment)
-
+	posts := make([]entity.Post, 0)
 	for rows.Next() {
-		var postRaw model.Post
-		var commentRaw model.Comment
-		var post entity.Post
-
-		err = rows.Scan(
-			&postRaw.ID,
-			&postRaw.Title,
-			&postRaw.Content,
-			&postRaw.PublishedAt,
-			&postRaw.AuthorID,
-			&postRaw.Commentable,
-			&commentRaw.ID,
-			&commentRaw.Content,
-			&commentRaw.PostID,
-			&commentRaw.AuthorID,
-			&commentRaw.PublishedAt,
-			&commentRaw.ParentCommentID)
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, *post)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", rows.Err())
+	}
+
+	return posts, nil
+}
+
+// scanPost scans a row of postColumns.
+func scanPost(row pgx.Row) (*entity.Post, error) {
+	var post model.Post
+	err := row.Scan(&post.ID, &post.Title, &post.Slug, &post.Content, &post.ContentFormat, &post.PublishedAt, &post.AuthorID, &post.Commentable,
+		&post.Status, &post.PublishAt, &post.Revision)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan row: %w", err)
+	}
 
+	return post.ToEntity(), nil
+}
+
+// nullInt converts zero to NULL.
+func nullInt(value int) interface{} {
+	if value == 0 {
+		return nil
+	}
+
+	return value
+}
+
+// CreatePost creates a new post with its first revision and a unique slug based on post.Slug.
+func (r *PostRepository) CreatePost(ctx context.Context, post *entity.Post) (*entity.Post, error) {
+	ctx, span := tracer.Start(ctx, "PostRepository.CreatePost")
+	defer span.End()
+
+	sql, args, err := r.Builder.Insert("posts").
+		Columns("title", "content", "content_format", "published_at", "author_id", "commentable", "status", "publish_at").
+		Values(post.Title, post.Content, post.ContentFormat, post.PublishedAt, post.AuthorID, post.Commentable, post.Status,
+			nullInt(post.PublishAt)).
+		Suffix("RETURNING id, revision").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	err = r.Pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, sql, args...).Scan(&post.ID, &post.Revision)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
+			return fmt.Errorf("failed to execute query: %w", err)
 		}
 
-		if postRaw.ID.Valid {
-			post = *postRaw.ToEntity()
-			_, ok := postsComments[post.ID]
-			if !ok {
-				posts = append(posts, post)
-				postsComments[post.ID] = make([]entity.Comment, 0)
-			}
+		post.Slug, err = r.assignSlug(ctx, tx, post.ID, post.Slug)
+		if err != nil {
+			return err
 		}
 
-		if commentRaw.ID.Valid {
-			postsComments[post.ID] = append(postsComments[post.ID], *commentRaw.ToEntity())
+		return r.insertRevision(ctx, tx, &entity.PostRevision{
+			PostID:    post.ID,
+			Version:   post.Revision,
+			Title:     post.Title,
+			Content:   post.Content,
+			EditorID:  post.AuthorID,
+			CreatedAt: int(time.Now().Unix()),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.log.DebugContext(
+		ctx,
+		"CreatePost",
+		"id", post.ID,
+	)
+
+	return post, nil
+}
+
+// UpdatePost sets the title and the content of a post from the revision and saves the revision
+// as the next version of the post. If slug isn't empty, the post gets a new unique slug based on it,
+// and the old one keeps leading to the post.
+func (r *PostRepository) UpdatePost(ctx context.Context, revision *entity.PostRevision, slug string) (*entity.Post, error) {
+	ctx, span := tracer.Start(ctx, "PostRepository.UpdatePost")
+	defer span.End()
+
+	r.log.DebugContext(
+		ctx,
+		"UpdatePost",
+		"postID", revision.PostID,
+	)
+
+	sql, args, err := r.Builder.Update("posts").
+		Set("title", revision.Title).
+		Set("content", revision.Content).
+		Set("revision", squirrel.Expr("revision + 1")).
+		Where("id = ?", revision.PostID).
+		Suffix("RETURNING " + strings.Join(postColumns, ", ")).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	var post *entity.Post
+	err = r.Pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		// The row stays locked until the end of the transaction, so versions of concurrent updates don't collide.
+		post, err = scanPost(tx.QueryRow(ctx, sql, args...))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.NotFoundError("post with id %d not found", revision.PostID)
+		} else if err != nil {
+			return err
 		}
 
+		if slug != "" {
+			post.Slug, err = r.assignSlug(ctx, tx, post.ID, slug)
+			if err != nil {
+				return err
+			}
+		}
+
+		revision.Version = post.Revision
+		return r.insertRevision(ctx, tx, revision)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	for i, post := range posts {
-		posts[i].Comments = postsComments[post.ID]
+	return post, nil
+}
+
+// GetPostBySlug returns a post by its current or earlier slug.
+func (r *PostRepository) GetPostBySlug(ctx context.Context, slug string) (*entity.Post, error) {
+	ctx, span := tracer.Start(ctx, "PostRepository.GetPostBySlug")
+	defer span.End()
+
+	r.log.DebugContext(
+		ctx,
+		"GetPostBySlug",
+		"slug", slug,
+	)
+
+	sql, args, err := r.Builder.Select("post_id").
+		From("post_slugs").
+		Where("slug = ?", slug).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sql: %w", err)
 	}
 
-	return &posts, nil
+	var id int
+	err = r.Pool.QueryRow(ctx, sql, args...).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, entity.NotFoundError("post with slug %q not found", slug)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	return r.GetPostByID(ctx, id)
 }
 
-// GetPostByID returns a post by its ID.
-func (r *PostRepository) GetPostByID(ctx context.Context, id int) (*entity.Post, error) {
-	r.log.Debug(
-		"GetPostByID",
-		"layer", "repository",
-		"storage", "postgres",
-		"id", id,
-		"requestID", ctx.Value("requestID"),
+// assignSlug reserves the first free slug of the candidates based on the slug for the post within a transaction,
+// sets it as the current slug of the post and returns it. Slugs the post had before are free for it.
+func (r *PostRepository) assignSlug(ctx context.Context, tx pgx.Tx, postID int, base string) (string, error) {
+	now := time.Now().Unix()
+
+	for n := 1; ; n++ {
+		candidate := slug.WithSuffix(base, n)
+
+		// A row is returned if the slug is new or already belongs to the post. A concurrent transaction
+		// reserving the same slug blocks this one until it ends, so a slug can't be given out twice.
+		sql, args, err := r.Builder.Insert("post_slugs").
+			Columns("slug", "post_id", "created_at").
+			Values(candidate, postID, now).
+			Suffix("ON CONFLICT (slug) DO UPDATE SET post_id = post_slugs.post_id " +
+				"WHERE post_slugs.post_id = EXCLUDED.post_id RETURNING slug").
+			ToSql()
+		if err != nil {
+			return "", fmt.Errorf("failed to build sql: %w", err)
+		}
+
+		var reserved string
+		err = tx.QueryRow(ctx, sql, args...).Scan(&reserved)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		} else if err != nil {
+			return "", fmt.Errorf("failed to execute query: %w", err)
+		}
+
+		sql, args, err = r.Builder.Update("posts").
+			Set("slug", reserved).
+			Where("id = ?", postID).
+			ToSql()
+		if err != nil {
+			return "", fmt.Errorf("failed to build sql: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, sql, args...)
+		if err != nil {
+			return "", fmt.Errorf("failed to execute query: %w", err)
+		}
+
+		return reserved, nil
+	}
+}
+
+// GetPostRevisions returns all revisions of a post, the oldest first.
+func (r *PostRepository) GetPostRevisions(ctx context.Context, postID int) ([]entity.PostRevision, error) {
+	ctx, span := tracer.Start(ctx, "PostRepository.GetPostRevisions")
+	defer span.End()
+
+	r.log.DebugContext(
+		ctx,
+		"GetPostRevisions",
+		"postID", postID,
 	)
 
-	sql, args, err := r.Builder.Select(
-		"post.id",
-		"post.title",
-		"post.content",
-		"post.published_at",
-		"post.author_id",
-		"post.commentable",
-		"comment.id",
-		"comment.content",
-		"comment.post_id",
-		"comment.author_id",
-		"comment.published_at",
-		"comment.parent_comment_id").
-		From("posts AS post").
-		LeftJoin("comments AS comment ON post.id = comment.post_id").
-		Where("post.id = ?", id).
+	sql, args, err := r.Builder.Select(revisionColumns...).
+		From("post_revisions").
+		Where("post_id = ?", postID).
+		OrderBy("version").
 		ToSql()
 	if err != nil {
 		return nil, fmt.Errorf("failed to build sql: %w", err)
@@ -152,48 +475,215 @@ func (r *PostRepository) GetPostByID(ctx context.Context, id int) (*entity.Post,
 	}
 	defer rows.Close()
 
-	var post model.Post
+	revisions := make([]entity.PostRevision, 0)
 	for rows.Next() {
-		var comment model.Comment
-
-		err = rows.Scan(&post.ID, &post.Title, &post.Content, &post.PublishedAt, &post.AuthorID, &post.Commentable,
-			&comment.ID, &comment.Content, &comment.PostID, &comment.AuthorID, &comment.PublishedAt,
-			&comment.ParentCommentID)
+		revision, err := scanRevision(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
+			return nil, err
 		}
+		revisions = append(revisions, *revision)
+	}
 
-		if comment.ID.Valid {
-			post.Comments = append(post.Comments, comment)
-		}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", rows.Err())
 	}
 
-	return post.ToEntity(), nil
+	if len(revisions) == 0 {
+		return nil, entity.NotFoundError("post with id %d not found", postID)
+	}
+
+	return revisions, nil
 }
 
-// CreatePost creates a new post.
-func (r *PostRepository) CreatePost(ctx context.Context, post *entity.Post) (*entity.Post, error) {
-	sql, args, err := r.Builder.Insert("posts").
-		Columns("title", "content", "published_at", "author_id", "commentable").
-		Values(post.Title, post.Content, post.PublishedAt, post.AuthorID, post.Commentable).
-		Suffix("RETURNING id").
+// GetPostRevision returns a revision of a post by its version.
+func (r *PostRepository) GetPostRevision(ctx context.Context, postID int, version int) (*entity.PostRevision, error) {
+	ctx, span := tracer.Start(ctx, "PostRepository.GetPostRevision")
+	defer span.End()
+
+	r.log.DebugContext(
+		ctx,
+		"GetPostRevision",
+		"postID", postID,
+		"version", version,
+	)
+
+	sql, args, err := r.Builder.Select(revisionColumns...).
+		From("post_revisions").
+		Where("post_id = ? AND version = ?", postID, version).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	revision, err := scanRevision(r.Pool.QueryRow(ctx, sql, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, entity.NotFoundError("revision %d of post with id %d not found", version, postID)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return revision, nil
+}
+
+// revisionColumns are columns of the post_revisions table in the order scanRevision scans them.
+var revisionColumns = []string{"post_id", "version", "title", "content", "editor_id", "created_at"}
+
+// scanRevision scans a row of revisionColumns.
+func scanRevision(row pgx.Row) (*entity.PostRevision, error) {
+	var revision entity.PostRevision
+	err := row.Scan(&revision.PostID, &revision.Version, &revision.Title, &revision.Content, &revision.EditorID,
+		&revision.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan row: %w", err)
+	}
+
+	return &revision, nil
+}
+
+// insertRevision saves a revision within a transaction.
+func (r *PostRepository) insertRevision(ctx context.Context, tx pgx.Tx, revision *entity.PostRevision) error {
+	sql, args, err := r.Builder.Insert("post_revisions").
+		Columns(revisionColumns...).
+		Values(revision.PostID, revision.Version, revision.Title, revision.Content, revision.EditorID,
+			revision.CreatedAt).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	return nil
+}
+
+// ListPosts returns posts of all statuses with IDs greater than afterID, ordered by ID.
+// Pages are read by passing the ID of the last post of the previous page.
+func (r *PostRepository) ListPosts(ctx context.Context, afterID int, amount uint) ([]entity.Post, error) {
+	ctx, span := tracer.Start(ctx, "PostRepository.ListPosts")
+	defer span.End()
+
+	r.log.DebugContext(
+		ctx,
+		"ListPosts",
+		"afterID", afterID,
+		"limit", amount,
+	)
+
+	sql, args, err := r.Builder.Select(postColumns...).
+		From("posts").
+		Where("id > ?", afterID).
+		OrderBy("id").
+		Limit(uint64(amount)).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	return r.queryPosts(ctx, sql, args...)
+}
+
+// GetPostSlugs returns the current and earlier slugs of a post, the oldest first.
+func (r *PostRepository) GetPostSlugs(ctx context.Context, postID int) ([]string, error) {
+	ctx, span := tracer.Start(ctx, "PostRepository.GetPostSlugs")
+	defer span.End()
+
+	r.log.DebugContext(
+		ctx,
+		"GetPostSlugs",
+		"postID", postID,
+	)
+
+	sql, args, err := r.Builder.Select("slug").
+		From("post_slugs").
+		Where("post_id = ?", postID).
+		OrderBy("created_at", "slug").
 		ToSql()
 	if err != nil {
 		return nil, fmt.Errorf("failed to build sql: %w", err)
 	}
 
-	err = r.Pool.QueryRow(ctx, sql, args...).Scan(&post.ID)
+	rows, err := r.Pool.Query(ctx, sql, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
+	defer rows.Close()
+
+	slugs := make([]string, 0)
+	for rows.Next() {
+		var s string
+		err = rows.Scan(&s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		slugs = append(slugs, s)
+	}
 
-	r.log.Debug(
-		"CreatePost",
-		"layer", "repository",
-		"storage", "postgres",
-		"id", post.ID,
-		"requestID", ctx.Value("requestID"),
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", rows.Err())
+	}
+
+	return slugs, nil
+}
+
+// ImportPost saves a post exported from another storage with its ID, revisions and slugs as they are.
+// The ID sequence is moved past the ID, so posts created later don't collide with imported ones.
+func (r *PostRepository) ImportPost(ctx context.Context, post *entity.Post, revisions []entity.PostRevision, slugs []string) error {
+	ctx, span := tracer.Start(ctx, "PostRepository.ImportPost")
+	defer span.End()
+
+	r.log.DebugContext(
+		ctx,
+		"ImportPost",
+		"postID", post.ID,
 	)
 
-	return post, nil
+	sql, args, err := r.Builder.Insert("posts").
+		Columns(postColumns...).
+		Values(post.ID, post.Title, post.Slug, post.Content, post.ContentFormat, post.PublishedAt, post.AuthorID,
+			post.Commentable, post.Status, nullInt(post.PublishAt), post.Revision).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	return r.Pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, sql, args...)
+		if err != nil {
+			return fmt.Errorf("failed to execute query: %w", err)
+		}
+
+		for i := range revisions {
+			err = r.insertRevision(ctx, tx, &revisions[i])
+			if err != nil {
+				return err
+			}
+		}
+
+		// Earlier slugs get earlier creation times, so they keep their order.
+		now := time.Now().Unix()
+		for i, s := range slugs {
+			sql, args, err := r.Builder.Insert("post_slugs").
+				Columns("slug", "post_id", "created_at").
+				Values(s, post.ID, now-int64(len(slugs)-1-i)).
+				ToSql()
+			if err != nil {
+				return fmt.Errorf("failed to build sql: %w", err)
+			}
+
+			_, err = tx.Exec(ctx, sql, args...)
+			if err != nil {
+				return fmt.Errorf("failed to execute query: %w", err)
+			}
+		}
+
+		_, err = tx.Exec(ctx, "SELECT setval(pg_get_serial_sequence('posts', 'id'), (SELECT MAX(id) FROM posts))")
+		if err != nil {
+			return fmt.Errorf("failed to move id sequence: %w", err)
+		}
+
+		return nil
+	})
 }
@@ -0,0 +1,122 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/oustrix/ozon_journal/internal"
+	"github.com/oustrix/ozon_journal/internal/entity"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+	"github.com/oustrix/ozon_journal/pkg/postgres"
+)
+
+const (
+	// presenceChannel is a name of the channel used for LISTEN/NOTIFY.
+	presenceChannel = "presence"
+	// presenceReconnectDelay is a delay before listening again after a connection error.
+	presenceReconnectDelay = time.Second
+)
+
+// Ensure PresenceRepository implements internal.PresenceRepository.
+var _ internal.PresenceRepository = &PresenceRepository{}
+
+// PresenceRepository delivers presence events between application instances with LISTEN/NOTIFY.
+type PresenceRepository struct {
+	*postgres.Postgres
+	log *logger.Logger
+}
+
+// NewPresenceRepository creates a new PresenceRepository instance.
+func NewPresenceRepository(postgres *postgres.Postgres, log *logger.Logger) *PresenceRepository {
+	return &PresenceRepository{Postgres: postgres, log: log.With("layer", "repository", "storage", "postgres")}
+}
+
+// PublishPresence sends a presence event to all instances, including the current one.
+func (r *PresenceRepository) PublishPresence(ctx context.Context, event *entity.PresenceEvent) error {
+	ctx, span := tracer.Start(ctx, "PresenceRepository.PublishPresence")
+	defer span.End()
+
+	r.log.DebugContext(
+		ctx,
+		"PublishPresence",
+		"postID", event.PostID,
+		"kind", event.Kind,
+	)
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	_, err = r.Pool.Exec(ctx, "SELECT pg_notify($1, $2)", presenceChannel, string(payload))
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	return nil
+}
+
+// ListenPresence returns a channel of presence events. The channel is closed when the context is done.
+// Connection errors are logged and listening is restarted, so the channel stays open until then.
+func (r *PresenceRepository) ListenPresence(ctx context.Context) <-chan *entity.PresenceEvent {
+	ch := make(chan *entity.PresenceEvent)
+
+	go func() {
+		defer close(ch)
+
+		for ctx.Err() == nil {
+			err := r.listen(ctx, ch)
+			if err != nil && ctx.Err() == nil {
+				r.log.Error(
+					"failed to listen presence events",
+					"error", err.Error(),
+				)
+				time.Sleep(presenceReconnectDelay)
+			}
+		}
+	}()
+
+	return ch
+}
+
+// listen sends notifications of the presence channel to ch until an error occurs.
+func (r *PresenceRepository) listen(ctx context.Context, ch chan<- *entity.PresenceEvent) error {
+	poolConn, err := r.Pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+
+	// The connection is taken out of the pool, so other queries don't receive notifications.
+	conn := poolConn.Hijack()
+	defer conn.Close(context.Background())
+
+	_, err = conn.Exec(ctx, "LISTEN "+presenceChannel)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to wait for notification: %w", err)
+		}
+
+		event := &entity.PresenceEvent{}
+		err = json.Unmarshal([]byte(notification.Payload), event)
+		if err != nil {
+			r.log.Warn(
+				"skipping malformed presence event",
+				"error", err.Error(),
+			)
+			continue
+		}
+
+		select {
+		case ch <- event:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,6 @@
+package postgres
+
+import "go.opentelemetry.io/otel"
+
+// tracer creates spans of repository methods with the global tracer provider. Spans of queries are created by pkg/postgres.
+var tracer = otel.Tracer("github.com/oustrix/ozon_journal/internal/repository/postgres")
@@ -0,0 +1,77 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/oustrix/ozon_journal/internal"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+	"github.com/oustrix/ozon_journal/pkg/s3"
+)
+
+// Ensure BlobStore implements internal.BlobStore.
+var _ internal.BlobStore = &BlobStore{}
+
+// BlobStore is a struct that keeps blobs as objects in an S3-compatible bucket.
+type BlobStore struct {
+	*s3.Client
+	log *logger.Logger
+}
+
+// NewBlobStore creates a new BlobStore instance.
+func NewBlobStore(client *s3.Client, log *logger.Logger) *BlobStore {
+	return &BlobStore{Client: client, log: log.With("layer", "repository", "storage", "s3")}
+}
+
+// PutBlob uploads a blob.
+func (s *BlobStore) PutBlob(ctx context.Context, key string, content io.Reader, size int64, contentType string) error {
+	s.log.DebugContext(
+		ctx,
+		"PutBlob",
+		"key", key,
+		"size", size,
+	)
+
+	err := s.PutObject(ctx, key, content, size, contentType)
+	if err != nil {
+		return fmt.Errorf("failed to put blob: %w", err)
+	}
+
+	return nil
+}
+
+// GetBlob downloads a blob. The caller must close it.
+func (s *BlobStore) GetBlob(ctx context.Context, key string) (io.ReadCloser, error) {
+	s.log.DebugContext(
+		ctx,
+		"GetBlob",
+		"key", key,
+	)
+
+	content, err := s.GetObject(ctx, key)
+	if errors.Is(err, s3.ErrNotFound) {
+		return nil, fmt.Errorf("blob %q not found", key)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get blob: %w", err)
+	}
+
+	return content, nil
+}
+
+// DeleteBlob deletes a blob.
+func (s *BlobStore) DeleteBlob(ctx context.Context, key string) error {
+	s.log.DebugContext(
+		ctx,
+		"DeleteBlob",
+		"key", key,
+	)
+
+	err := s.DeleteObject(ctx, key)
+	if err != nil && !errors.Is(err, s3.ErrNotFound) {
+		return fmt.Errorf("failed to delete blob: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,249 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/oustrix/ozon_journal/internal"
+	"github.com/oustrix/ozon_journal/internal/entity"
+	"github.com/oustrix/ozon_journal/internal/repository/postgres/model"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+	"github.com/oustrix/ozon_journal/pkg/sqlite"
+)
+
+// Ensure CommentRepository implements internal.CommentRepository.
+var _ internal.CommentRepository = &CommentRepository{}
+
+// CommentRepository is a struct that manages comments in the database.
+type CommentRepository struct {
+	*sqlite.SQLite
+	log *logger.Logger
+}
+
+// NewCommentRepository creates a new CommentRepository instance.
+func NewCommentRepository(sqlite *sqlite.SQLite, log *logger.Logger) *CommentRepository {
+	return &CommentRepository{SQLite: sqlite, log: log.With("layer", "repository", "storage", "sqlite")}
+}
+
+// commentColumns are columns of the comments table in the order scanComment scans them.
+var commentColumns = []string{"id", "content", "content_format", "author_id", "post_id", "published_at", "parent_comment_id"}
+
+// GetCommentsByPostID returns comments of a post, the oldest first. Pages are numbered from 0.
+func (r *CommentRepository) GetCommentsByPostID(ctx context.Context, postID int, page uint, amount uint) (*[]entity.Comment, error) {
+	ctx, span := tracer.Start(ctx, "CommentRepository.GetCommentsByPostID")
+	defer span.End()
+
+	offset := page * amount
+
+	r.log.DebugContext(
+		ctx,
+		"GetCommentsByPostID",
+		"postID", postID,
+		"limit", amount,
+		"offset", offset,
+	)
+
+	query, args, err := r.Builder.Select(commentColumns...).
+		From("comments").
+		Where("post_id = ?", postID).
+		OrderBy("id").
+		Offset(uint64(offset)).
+		Limit(uint64(amount)).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	comments, err := r.queryComments(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &comments, nil
+}
+
+// GetCommentByID returns a comment by its ID.
+func (r *CommentRepository) GetCommentByID(ctx context.Context, id int) (*entity.Comment, error) {
+	ctx, span := tracer.Start(ctx, "CommentRepository.GetCommentByID")
+	defer span.End()
+
+	r.log.DebugContext(
+		ctx,
+		"GetCommentByID",
+		"id", id,
+	)
+
+	query, args, err := r.Builder.Select(commentColumns...).
+		From("comments").
+		Where("id = ?", id).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	comment, err := scanComment(r.DB.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, entity.NotFoundError("comment with id %d not found", id)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+// CreateComment creates a new comment if its post is published and commentable.
+func (r *CommentRepository) CreateComment(ctx context.Context, comment *entity.Comment) (*entity.Comment, error) {
+	ctx, span := tracer.Start(ctx, "CommentRepository.CreateComment")
+	defer span.End()
+
+	postQuery, postArgs, err := r.Builder.Select("commentable", "status").
+		From("posts").
+		Where("id = ?", comment.PostID).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	query, args, err := r.Builder.Insert("comments").
+		Columns(commentColumns[1:]...).
+		Values(comment.Content, comment.ContentFormat, comment.AuthorID, comment.PostID, comment.PublishedAt,
+			comment.ParentCommentID).
+		Suffix("RETURNING id").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	// The post is checked in the transaction of the insert, so it can't change in between.
+	err = r.BeginFunc(ctx, func(tx *sql.Tx) error {
+		var commentable bool
+		var status string
+		err := tx.QueryRowContext(ctx, postQuery, postArgs...).Scan(&commentable, &status)
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.NotFoundError("post with id %d not found", comment.PostID)
+		} else if err != nil {
+			return fmt.Errorf("failed to execute query: %w", err)
+		}
+
+		// Don't reveal that an unpublished post exists.
+		if entity.PostStatus(status) != entity.PostStatusPublished {
+			return entity.NotFoundError("post with id %d not found", comment.PostID)
+		}
+
+		if !commentable {
+			return entity.InvalidArgumentError("post with id %d is not commentable", comment.PostID)
+		}
+
+		err = tx.QueryRowContext(ctx, query, args...).Scan(&comment.ID)
+		if err != nil {
+			return fmt.Errorf("failed to execute query: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.log.DebugContext(
+		ctx,
+		"CreateComment",
+		"commentID", comment.ID,
+	)
+
+	return comment, nil
+}
+
+// ListComments returns comments of all posts with IDs greater than afterID, ordered by ID.
+// Pages are read by passing the ID of the last comment of the previous page.
+func (r *CommentRepository) ListComments(ctx context.Context, afterID int, amount uint) ([]entity.Comment, error) {
+	ctx, span := tracer.Start(ctx, "CommentRepository.ListComments")
+	defer span.End()
+
+	r.log.DebugContext(
+		ctx,
+		"ListComments",
+		"afterID", afterID,
+		"limit", amount,
+	)
+
+	query, args, err := r.Builder.Select(commentColumns...).
+		From("comments").
+		Where("id > ?", afterID).
+		OrderBy("id").
+		Limit(uint64(amount)).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	return r.queryComments(ctx, query, args...)
+}
+
+// ImportComment saves a comment exported from another storage with its ID as it is. Imported comments
+// may belong to posts that aren't commentable anymore. AUTOINCREMENT IDs of comments created later are greater
+// than the largest ID ever saved, so they don't collide with imported ones.
+func (r *CommentRepository) ImportComment(ctx context.Context, comment *entity.Comment) error {
+	ctx, span := tracer.Start(ctx, "CommentRepository.ImportComment")
+	defer span.End()
+
+	r.log.DebugContext(
+		ctx,
+		"ImportComment",
+		"commentID", comment.ID,
+	)
+
+	query, args, err := r.Builder.Insert("comments").
+		Columns(commentColumns...).
+		Values(comment.ID, comment.Content, comment.ContentFormat, comment.AuthorID, comment.PostID, comment.PublishedAt,
+			comment.ParentCommentID).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	_, err = r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	return nil
+}
+
+// queryComments executes a query returning commentColumns and returns the comments.
+func (r *CommentRepository) queryComments(ctx context.Context, query string, args ...interface{}) ([]entity.Comment, error) {
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	comments := make([]entity.Comment, 0)
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, *comment)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", rows.Err())
+	}
+
+	return comments, nil
+}
+
+// scanComment scans a row of commentColumns.
+func scanComment(row row) (*entity.Comment, error) {
+	var comment model.Comment
+	err := row.Scan(&comment.ID, &comment.Content, &comment.ContentFormat, &comment.AuthorID, &comment.PostID,
+		&comment.PublishedAt, &comment.ParentCommentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan row: %w", err)
+	}
+
+	return comment.ToEntity(), nil
+}
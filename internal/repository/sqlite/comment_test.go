@@ -0,0 +1,105 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/oustrix/ozon_journal/internal/entity"
+)
+
+func TestCommentRepositoryGetCommentsByPostID(t *testing.T) {
+	db := newTestSQLite(t)
+	posts := NewPostRepository(db, newTestLogger())
+	comments := NewCommentRepository(db, newTestLogger())
+	ctx := context.Background()
+
+	createTestPosts(t, posts, 3)
+	// Comments of the first two posts alternate, so their IDs are 1, 3, 5 and 2, 4, 6.
+	for i := 0; i < 6; i++ {
+		_, err := comments.CreateComment(ctx, &entity.Comment{Content: "Comment", ContentFormat: entity.ContentFormatPlain,
+			AuthorID: 2, PostID: i%2 + 1, PublishedAt: 1700000100 + i, ParentCommentID: -1})
+		if err != nil {
+			t.Fatalf("CreateComment() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		postID int
+		page   uint
+		amount uint
+		want   []int
+	}{
+		{name: "first page", postID: 1, page: 0, amount: 2, want: []int{1, 3}},
+		{name: "second page", postID: 1, page: 1, amount: 2, want: []int{5}},
+		{name: "another post", postID: 2, page: 0, amount: 10, want: []int{2, 4, 6}},
+		{name: "post without comments", postID: 3, page: 0, amount: 10, want: []int{}},
+		{name: "missing post", postID: 4, page: 0, amount: 10, want: []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := comments.GetCommentsByPostID(ctx, tt.postID, tt.page, tt.amount)
+			if err != nil {
+				t.Fatalf("GetCommentsByPostID() error = %v", err)
+			}
+
+			ids := make([]int, 0, len(*got))
+			for _, comment := range *got {
+				ids = append(ids, comment.ID)
+				if comment.PostID != tt.postID {
+					t.Errorf("comment %d belongs to post %d, want %d", comment.ID, comment.PostID, tt.postID)
+				}
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.want) {
+				t.Errorf("GetCommentsByPostID() = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommentRepositoryGetCommentByID(t *testing.T) {
+	db := newTestSQLite(t)
+	posts := NewPostRepository(db, newTestLogger())
+	comments := NewCommentRepository(db, newTestLogger())
+	ctx := context.Background()
+
+	createTestPosts(t, posts, 1)
+	for _, parentID := range []int{-1, 1} {
+		_, err := comments.CreateComment(ctx, &entity.Comment{Content: "Comment", ContentFormat: entity.ContentFormatMarkdown,
+			AuthorID: 2, PostID: 1, PublishedAt: 1700000100, ParentCommentID: parentID})
+		if err != nil {
+			t.Fatalf("CreateComment() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		id         int
+		wantParent int
+		wantErr    error
+	}{
+		{name: "comment of the post", id: 1, wantParent: -1},
+		{name: "reply", id: 2, wantParent: 1},
+		{name: "missing comment", id: 3, wantErr: entity.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comment, err := comments.GetCommentByID(ctx, tt.id)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetCommentByID() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if comment.ID != tt.id || comment.PostID != 1 || comment.ParentCommentID != tt.wantParent ||
+				comment.ContentFormat != entity.ContentFormatMarkdown {
+				t.Errorf("GetCommentByID() = %+v", comment)
+			}
+		})
+	}
+}
@@ -0,0 +1,692 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/oustrix/ozon_journal/internal"
+	"github.com/oustrix/ozon_journal/internal/entity"
+	"github.com/oustrix/ozon_journal/internal/repository/postgres/model"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+	"github.com/oustrix/ozon_journal/pkg/slug"
+	"github.com/oustrix/ozon_journal/pkg/sqlite"
+)
+
+// Ensure PostRepository implements internal.PostRepository.
+var _ internal.PostRepository = &PostRepository{}
+
+// PostRepository is a struct that manages posts in the database.
+type PostRepository struct {
+	*sqlite.SQLite
+	log *logger.Logger
+}
+
+// NewPostRepository creates a new PostRepository instance.
+func NewPostRepository(sqlite *sqlite.SQLite, log *logger.Logger) *PostRepository {
+	return &PostRepository{SQLite: sqlite, log: log.With("layer", "repository", "storage", "sqlite")}
+}
+
+// row is a single row or the current row of rows.
+type row interface {
+	Scan(dest ...interface{}) error
+}
+
+// GetPosts returns a list of published posts without comments, the most recently published first.
+func (r *PostRepository) GetPosts(ctx context.Context, page uint, amount uint) (*[]entity.Post, error) {
+	ctx, span := tracer.Start(ctx, "PostRepository.GetPosts")
+	defer span.End()
+
+	offset := pageOffset(page, amount)
+
+	r.log.DebugContext(
+		ctx,
+		"GetPosts",
+		"limit", amount,
+		"offset", offset,
+	)
+
+	query, args, err := r.Builder.Select(postColumns...).
+		From("posts").
+		Where("status = ?", entity.PostStatusPublished).
+		OrderBy("published_at DESC", "id DESC").
+		Limit(uint64(amount)).
+		Offset(offset).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	posts, err := r.queryPosts(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &posts, nil
+}
+
+// GetPostByID returns a post by its ID without comments.
+func (r *PostRepository) GetPostByID(ctx context.Context, id int) (*entity.Post, error) {
+	ctx, span := tracer.Start(ctx, "PostRepository.GetPostByID")
+	defer span.End()
+
+	r.log.DebugContext(
+		ctx,
+		"GetPostByID",
+		"id", id,
+	)
+
+	query, args, err := r.Builder.Select(postColumns...).
+		From("posts").
+		Where("id = ?", id).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	post, err := scanPost(r.DB.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, entity.NotFoundError("post with id %d not found", id)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return post, nil
+}
+
+// GetUnpublishedPosts returns drafts and scheduled posts of an author, the most recently created first.
+func (r *PostRepository) GetUnpublishedPosts(ctx context.Context, authorID int, page uint, amount uint) (*[]entity.Post, error) {
+	ctx, span := tracer.Start(ctx, "PostRepository.GetUnpublishedPosts")
+	defer span.End()
+
+	offset := pageOffset(page, amount)
+
+	r.log.DebugContext(
+		ctx,
+		"GetUnpublishedPosts",
+		"authorID", authorID,
+		"limit", amount,
+		"offset", offset,
+	)
+
+	query, args, err := r.Builder.Select(postColumns...).
+		From("posts").
+		Where("author_id = ? AND status <> ?", authorID, entity.PostStatusPublished).
+		OrderBy("id DESC").
+		Limit(uint64(amount)).
+		Offset(offset).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	posts, err := r.queryPosts(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &posts, nil
+}
+
+// GetPostsByAuthorID returns published posts of an author without comments.
+func (r *PostRepository) GetPostsByAuthorID(ctx context.Context, authorID int, page uint, amount uint) (*[]entity.Post, error) {
+	ctx, span := tracer.Start(ctx, "PostRepository.GetPostsByAuthorID")
+	defer span.End()
+
+	offset := pageOffset(page, amount)
+
+	r.log.DebugContext(
+		ctx,
+		"GetPostsByAuthorID",
+		"authorID", authorID,
+		"limit", amount,
+		"offset", offset,
+	)
+
+	query, args, err := r.Builder.Select(postColumns...).
+		From("posts").
+		Where("author_id = ? AND status = ?", authorID, entity.PostStatusPublished).
+		OrderBy("published_at DESC", "id DESC").
+		Limit(uint64(amount)).
+		Offset(offset).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	posts, err := r.queryPosts(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &posts, nil
+}
+
+// UpdatePostStatus updates the status and publication times of a post.
+func (r *PostRepository) UpdatePostStatus(ctx context.Context, post *entity.Post) (*entity.Post, error) {
+	ctx, span := tracer.Start(ctx, "PostRepository.UpdatePostStatus")
+	defer span.End()
+
+	r.log.DebugContext(
+		ctx,
+		"UpdatePostStatus",
+		"id", post.ID,
+		"status", post.Status,
+	)
+
+	query, args, err := r.Builder.Update("posts").
+		Set("status", post.Status).
+		Set("publish_at", nullInt(post.PublishAt)).
+		Set("published_at", post.PublishedAt).
+		Where("id = ?", post.ID).
+		Suffix("RETURNING " + strings.Join(postColumns, ", ")).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	updated, err := scanPost(r.DB.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, entity.NotFoundError("post with id %d not found", post.ID)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// PublishDuePosts publishes scheduled posts with publication time not later than now and returns them.
+func (r *PostRepository) PublishDuePosts(ctx context.Context, now int) ([]entity.Post, error) {
+	ctx, span := tracer.Start(ctx, "PostRepository.PublishDuePosts")
+	defer span.End()
+
+	query, args, err := r.Builder.Update("posts").
+		Set("status", entity.PostStatusPublished).
+		Set("published_at", squirrel.Expr("publish_at")).
+		Set("publish_at", nil).
+		Where("status = ? AND publish_at <= ?", entity.PostStatusScheduled, now).
+		Suffix("RETURNING " + strings.Join(postColumns, ", ")).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	posts, err := r.queryPosts(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	r.log.DebugContext(
+		ctx,
+		"PublishDuePosts",
+		"amount", len(posts),
+	)
+
+	return posts, nil
+}
+
+// postColumns are columns of the posts table in the order queryPosts scans them.
+var postColumns = []string{"id", "title", "slug", "content", "content_format", "published_at", "author_id", "commentable", "status", "publish_at",
+	"revision"}
+
+// queryPosts executes a query returning postColumns and returns the posts without comments.
+func (r *PostRepository) queryPosts(ctx context.Context, query string, args ...interface{}) ([]entity.Post, error) {
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	posts := make([]entity.Post, 0)
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, *post)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", rows.Err())
+	}
+
+	return posts, nil
+}
+
+// scanPost scans a row of postColumns.
+func scanPost(row row) (*entity.Post, error) {
+	var post model.Post
+	err := row.Scan(&post.ID, &post.Title, &post.Slug, &post.Content, &post.ContentFormat, &post.PublishedAt, &post.AuthorID, &post.Commentable,
+		&post.Status, &post.PublishAt, &post.Revision)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan row: %w", err)
+	}
+
+	return post.ToEntity(), nil
+}
+
+// pageOffset returns the offset of a page of posts. Pages are numbered from 1, and page 0 is the first page too.
+func pageOffset(page uint, amount uint) uint64 {
+	return uint64(max(page, 1)-1) * uint64(amount)
+}
+
+// nullInt converts zero to NULL.
+func nullInt(value int) interface{} {
+	if value == 0 {
+		return nil
+	}
+
+	return value
+}
+
+// CreatePost creates a new post with its first revision and a unique slug based on post.Slug.
+func (r *PostRepository) CreatePost(ctx context.Context, post *entity.Post) (*entity.Post, error) {
+	ctx, span := tracer.Start(ctx, "PostRepository.CreatePost")
+	defer span.End()
+
+	query, args, err := r.Builder.Insert("posts").
+		Columns("title", "content", "content_format", "published_at", "author_id", "commentable", "status", "publish_at").
+		Values(post.Title, post.Content, post.ContentFormat, post.PublishedAt, post.AuthorID, post.Commentable, post.Status,
+			nullInt(post.PublishAt)).
+		Suffix("RETURNING id, revision").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	err = r.BeginFunc(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, query, args...).Scan(&post.ID, &post.Revision)
+		if err != nil {
+			return fmt.Errorf("failed to execute query: %w", err)
+		}
+
+		post.Slug, err = r.assignSlug(ctx, tx, post.ID, post.Slug)
+		if err != nil {
+			return err
+		}
+
+		return r.insertRevision(ctx, tx, &entity.PostRevision{
+			PostID:    post.ID,
+			Version:   post.Revision,
+			Title:     post.Title,
+			Content:   post.Content,
+			EditorID:  post.AuthorID,
+			CreatedAt: int(time.Now().Unix()),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.log.DebugContext(
+		ctx,
+		"CreatePost",
+		"id", post.ID,
+	)
+
+	return post, nil
+}
+
+// UpdatePost sets the title and the content of a post from the revision and saves the revision
+// as the next version of the post. If slug isn't empty, the post gets a new unique slug based on it,
+// and the old one keeps leading to the post.
+func (r *PostRepository) UpdatePost(ctx context.Context, revision *entity.PostRevision, slug string) (*entity.Post, error) {
+	ctx, span := tracer.Start(ctx, "PostRepository.UpdatePost")
+	defer span.End()
+
+	r.log.DebugContext(
+		ctx,
+		"UpdatePost",
+		"postID", revision.PostID,
+	)
+
+	query, args, err := r.Builder.Update("posts").
+		Set("title", revision.Title).
+		Set("content", revision.Content).
+		Set("revision", squirrel.Expr("revision + 1")).
+		Where("id = ?", revision.PostID).
+		Suffix("RETURNING " + strings.Join(postColumns, ", ")).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	var post *entity.Post
+	err = r.BeginFunc(ctx, func(tx *sql.Tx) error {
+		// Transactions hold the write lock of the database, so versions of concurrent updates don't collide.
+		post, err = scanPost(tx.QueryRowContext(ctx, query, args...))
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.NotFoundError("post with id %d not found", revision.PostID)
+		} else if err != nil {
+			return err
+		}
+
+		if slug != "" {
+			post.Slug, err = r.assignSlug(ctx, tx, post.ID, slug)
+			if err != nil {
+				return err
+			}
+		}
+
+		revision.Version = post.Revision
+		return r.insertRevision(ctx, tx, revision)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return post, nil
+}
+
+// GetPostBySlug returns a post by its current or earlier slug.
+func (r *PostRepository) GetPostBySlug(ctx context.Context, slug string) (*entity.Post, error) {
+	ctx, span := tracer.Start(ctx, "PostRepository.GetPostBySlug")
+	defer span.End()
+
+	r.log.DebugContext(
+		ctx,
+		"GetPostBySlug",
+		"slug", slug,
+	)
+
+	query, args, err := r.Builder.Select("post_id").
+		From("post_slugs").
+		Where("slug = ?", slug).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	var id int
+	err = r.DB.QueryRowContext(ctx, query, args...).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, entity.NotFoundError("post with slug %q not found", slug)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	return r.GetPostByID(ctx, id)
+}
+
+// assignSlug reserves the first free slug of the candidates based on the slug for the post within a transaction,
+// sets it as the current slug of the post and returns it. Slugs the post had before are free for it.
+func (r *PostRepository) assignSlug(ctx context.Context, tx *sql.Tx, postID int, base string) (string, error) {
+	now := time.Now().Unix()
+
+	for n := 1; ; n++ {
+		candidate := slug.WithSuffix(base, n)
+
+		// A row is returned if the slug is new or already belongs to the post.
+		query, args, err := r.Builder.Insert("post_slugs").
+			Columns("slug", "post_id", "created_at").
+			Values(candidate, postID, now).
+			Suffix("ON CONFLICT (slug) DO UPDATE SET post_id = post_slugs.post_id " +
+				"WHERE post_slugs.post_id = excluded.post_id RETURNING slug").
+			ToSql()
+		if err != nil {
+			return "", fmt.Errorf("failed to build sql: %w", err)
+		}
+
+		var reserved string
+		err = tx.QueryRowContext(ctx, query, args...).Scan(&reserved)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		} else if err != nil {
+			return "", fmt.Errorf("failed to execute query: %w", err)
+		}
+
+		query, args, err = r.Builder.Update("posts").
+			Set("slug", reserved).
+			Where("id = ?", postID).
+			ToSql()
+		if err != nil {
+			return "", fmt.Errorf("failed to build sql: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return "", fmt.Errorf("failed to execute query: %w", err)
+		}
+
+		return reserved, nil
+	}
+}
+
+// GetPostRevisions returns all revisions of a post, the oldest first.
+func (r *PostRepository) GetPostRevisions(ctx context.Context, postID int) ([]entity.PostRevision, error) {
+	ctx, span := tracer.Start(ctx, "PostRepository.GetPostRevisions")
+	defer span.End()
+
+	r.log.DebugContext(
+		ctx,
+		"GetPostRevisions",
+		"postID", postID,
+	)
+
+	query, args, err := r.Builder.Select(revisionColumns...).
+		From("post_revisions").
+		Where("post_id = ?", postID).
+		OrderBy("version").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := make([]entity.PostRevision, 0)
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, *revision)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", rows.Err())
+	}
+
+	if len(revisions) == 0 {
+		return nil, entity.NotFoundError("post with id %d not found", postID)
+	}
+
+	return revisions, nil
+}
+
+// GetPostRevision returns a revision of a post by its version.
+func (r *PostRepository) GetPostRevision(ctx context.Context, postID int, version int) (*entity.PostRevision, error) {
+	ctx, span := tracer.Start(ctx, "PostRepository.GetPostRevision")
+	defer span.End()
+
+	r.log.DebugContext(
+		ctx,
+		"GetPostRevision",
+		"postID", postID,
+		"version", version,
+	)
+
+	query, args, err := r.Builder.Select(revisionColumns...).
+		From("post_revisions").
+		Where("post_id = ? AND version = ?", postID, version).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	revision, err := scanRevision(r.DB.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, entity.NotFoundError("revision %d of post with id %d not found", version, postID)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return revision, nil
+}
+
+// revisionColumns are columns of the post_revisions table in the order scanRevision scans them.
+var revisionColumns = []string{"post_id", "version", "title", "content", "editor_id", "created_at"}
+
+// scanRevision scans a row of revisionColumns.
+func scanRevision(row row) (*entity.PostRevision, error) {
+	var revision entity.PostRevision
+	err := row.Scan(&revision.PostID, &revision.Version, &revision.Title, &revision.Content, &revision.EditorID,
+		&revision.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan row: %w", err)
+	}
+
+	return &revision, nil
+}
+
+// insertRevision saves a revision within a transaction.
+func (r *PostRepository) insertRevision(ctx context.Context, tx *sql.Tx, revision *entity.PostRevision) error {
+	query, args, err := r.Builder.Insert("post_revisions").
+		Columns(revisionColumns...).
+		Values(revision.PostID, revision.Version, revision.Title, revision.Content, revision.EditorID,
+			revision.CreatedAt).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	return nil
+}
+
+// ListPosts returns posts of all statuses with IDs greater than afterID, ordered by ID.
+// Pages are read by passing the ID of the last post of the previous page.
+func (r *PostRepository) ListPosts(ctx context.Context, afterID int, amount uint) ([]entity.Post, error) {
+	ctx, span := tracer.Start(ctx, "PostRepository.ListPosts")
+	defer span.End()
+
+	r.log.DebugContext(
+		ctx,
+		"ListPosts",
+		"afterID", afterID,
+		"limit", amount,
+	)
+
+	query, args, err := r.Builder.Select(postColumns...).
+		From("posts").
+		Where("id > ?", afterID).
+		OrderBy("id").
+		Limit(uint64(amount)).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	return r.queryPosts(ctx, query, args...)
+}
+
+// GetPostSlugs returns the current and earlier slugs of a post, the oldest first.
+func (r *PostRepository) GetPostSlugs(ctx context.Context, postID int) ([]string, error) {
+	ctx, span := tracer.Start(ctx, "PostRepository.GetPostSlugs")
+	defer span.End()
+
+	r.log.DebugContext(
+		ctx,
+		"GetPostSlugs",
+		"postID", postID,
+	)
+
+	query, args, err := r.Builder.Select("slug").
+		From("post_slugs").
+		Where("post_id = ?", postID).
+		OrderBy("created_at", "slug").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	slugs := make([]string, 0)
+	for rows.Next() {
+		var s string
+		err = rows.Scan(&s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		slugs = append(slugs, s)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", rows.Err())
+	}
+
+	return slugs, nil
+}
+
+// ImportPost saves a post exported from another storage with its ID, revisions and slugs as they are.
+// AUTOINCREMENT IDs of posts created later are greater than the largest ID ever saved, so they don't collide
+// with imported ones.
+func (r *PostRepository) ImportPost(ctx context.Context, post *entity.Post, revisions []entity.PostRevision, slugs []string) error {
+	ctx, span := tracer.Start(ctx, "PostRepository.ImportPost")
+	defer span.End()
+
+	r.log.DebugContext(
+		ctx,
+		"ImportPost",
+		"postID", post.ID,
+	)
+
+	query, args, err := r.Builder.Insert("posts").
+		Columns(postColumns...).
+		Values(post.ID, post.Title, post.Slug, post.Content, post.ContentFormat, post.PublishedAt, post.AuthorID,
+			post.Commentable, post.Status, nullInt(post.PublishAt), post.Revision).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	return r.BeginFunc(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to execute query: %w", err)
+		}
+
+		for i := range revisions {
+			err = r.insertRevision(ctx, tx, &revisions[i])
+			if err != nil {
+				return err
+			}
+		}
+
+		// Earlier slugs get earlier creation times, so they keep their order.
+		now := time.Now().Unix()
+		for i, s := range slugs {
+			query, args, err := r.Builder.Insert("post_slugs").
+				Columns("slug", "post_id", "created_at").
+				Values(s, post.ID, now-int64(len(slugs)-1-i)).
+				ToSql()
+			if err != nil {
+				return fmt.Errorf("failed to build sql: %w", err)
+			}
+
+			_, err = tx.ExecContext(ctx, query, args...)
+			if err != nil {
+				return fmt.Errorf("failed to execute query: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
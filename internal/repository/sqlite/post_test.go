@@ -0,0 +1,162 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/oustrix/ozon_journal/internal/entity"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+	"github.com/oustrix/ozon_journal/pkg/sqlite"
+)
+
+// newTestSQLite opens a database in a temporary directory with the schema of the migrations.
+func newTestSQLite(t *testing.T) *sqlite.SQLite {
+	t.Helper()
+
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "journal.db"))
+	if err != nil {
+		t.Fatalf("sqlite.New() error = %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	schema, err := os.ReadFile("../../../migrations/sqlite/000001_init.up.sql")
+	if err != nil {
+		t.Fatalf("failed to read migration: %v", err)
+	}
+	_, err = db.DB.Exec(string(schema))
+	if err != nil {
+		t.Fatalf("failed to apply migration: %v", err)
+	}
+
+	return db
+}
+
+func newTestLogger() *logger.Logger {
+	return logger.New("error", logger.Output(io.Discard))
+}
+
+// createTestPosts creates published posts with titles "Post 1", "Post 2", etc., the later ones published later.
+func createTestPosts(t *testing.T, r *PostRepository, n int) {
+	t.Helper()
+
+	for i := 1; i <= n; i++ {
+		_, err := r.CreatePost(context.Background(), &entity.Post{
+			Title:         fmt.Sprintf("Post %d", i),
+			Slug:          fmt.Sprintf("post-%d", i),
+			Content:       "Content",
+			ContentFormat: entity.ContentFormatPlain,
+			PublishedAt:   1700000000 + i,
+			AuthorID:      1,
+			Commentable:   true,
+			Status:        entity.PostStatusPublished,
+		})
+		if err != nil {
+			t.Fatalf("CreatePost() error = %v", err)
+		}
+	}
+}
+
+func TestPostRepositoryGetPosts(t *testing.T) {
+	db := newTestSQLite(t)
+	posts := NewPostRepository(db, newTestLogger())
+	comments := NewCommentRepository(db, newTestLogger())
+	ctx := context.Background()
+
+	createTestPosts(t, posts, 5)
+	_, err := posts.CreatePost(ctx, &entity.Post{Title: "Draft", Slug: "draft", Content: "Content",
+		ContentFormat: entity.ContentFormatPlain, AuthorID: 1, Status: entity.PostStatusDraft})
+	if err != nil {
+		t.Fatalf("CreatePost() error = %v", err)
+	}
+	// Comments don't change pages of posts.
+	for i := 0; i < 3; i++ {
+		_, err = comments.CreateComment(ctx, &entity.Comment{Content: "Comment", ContentFormat: entity.ContentFormatPlain,
+			AuthorID: 2, PostID: 5, PublishedAt: 1700000100, ParentCommentID: -1})
+		if err != nil {
+			t.Fatalf("CreateComment() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		page   uint
+		amount uint
+		want   []int
+	}{
+		{name: "first page", page: 1, amount: 2, want: []int{5, 4}},
+		{name: "second page", page: 2, amount: 2, want: []int{3, 2}},
+		{name: "last page", page: 3, amount: 2, want: []int{1}},
+		{name: "page after the last", page: 4, amount: 2, want: []int{}},
+		{name: "page 0 is the first page", page: 0, amount: 2, want: []int{5, 4}},
+		{name: "all posts", page: 1, amount: 10, want: []int{5, 4, 3, 2, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := posts.GetPosts(ctx, tt.page, tt.amount)
+			if err != nil {
+				t.Fatalf("GetPosts() error = %v", err)
+			}
+
+			ids := make([]int, 0, len(*got))
+			for _, post := range *got {
+				ids = append(ids, post.ID)
+				if len(post.Comments) != 0 {
+					t.Errorf("post %d has %d comments, want none", post.ID, len(post.Comments))
+				}
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.want) {
+				t.Errorf("GetPosts() = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostRepositoryGetPostByID(t *testing.T) {
+	db := newTestSQLite(t)
+	posts := NewPostRepository(db, newTestLogger())
+	comments := NewCommentRepository(db, newTestLogger())
+	ctx := context.Background()
+
+	createTestPosts(t, posts, 2)
+	_, err := comments.CreateComment(ctx, &entity.Comment{Content: "Comment", ContentFormat: entity.ContentFormatPlain,
+		AuthorID: 2, PostID: 1, PublishedAt: 1700000100, ParentCommentID: -1})
+	if err != nil {
+		t.Fatalf("CreateComment() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		id        int
+		wantTitle string
+		wantErr   error
+	}{
+		{name: "post with comments", id: 1, wantTitle: "Post 1"},
+		{name: "post without comments", id: 2, wantTitle: "Post 2"},
+		{name: "missing post", id: 3, wantErr: entity.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post, err := posts.GetPostByID(ctx, tt.id)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetPostByID() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if post.ID != tt.id || post.Title != tt.wantTitle || post.Slug != fmt.Sprintf("post-%d", tt.id) {
+				t.Errorf("GetPostByID() = %+v", post)
+			}
+			if len(post.Comments) != 0 {
+				t.Errorf("post has %d comments, want none", len(post.Comments))
+			}
+		})
+	}
+}
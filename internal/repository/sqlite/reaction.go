@@ -0,0 +1,156 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/oustrix/ozon_journal/internal"
+	"github.com/oustrix/ozon_journal/internal/entity"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+	"github.com/oustrix/ozon_journal/pkg/sqlite"
+)
+
+// Ensure ReactionRepository implements internal.ReactionRepository.
+var _ internal.ReactionRepository = &ReactionRepository{}
+
+// ReactionRepository is a struct that manages reactions in the database.
+type ReactionRepository struct {
+	*sqlite.SQLite
+	log *logger.Logger
+}
+
+// NewReactionRepository creates a new ReactionRepository instance.
+func NewReactionRepository(sqlite *sqlite.SQLite, log *logger.Logger) *ReactionRepository {
+	return &ReactionRepository{SQLite: sqlite, log: log.With("layer", "repository", "storage", "sqlite")}
+}
+
+// AddReaction adds a reaction and returns reaction counts of its target right after the change.
+// It returns false if the user has already left the same emoji.
+func (r *ReactionRepository) AddReaction(ctx context.Context, reaction *entity.Reaction) ([]entity.ReactionCount, bool, error) {
+	ctx, span := tracer.Start(ctx, "ReactionRepository.AddReaction")
+	defer span.End()
+
+	r.log.DebugContext(
+		ctx,
+		"AddReaction",
+		"targetType", reaction.TargetType,
+		"targetID", reaction.TargetID,
+	)
+
+	query, args, err := r.Builder.Insert("reactions").
+		Columns("target_type", "target_id", "user_id", "emoji", "created_at").
+		Values(reaction.TargetType, reaction.TargetID, reaction.UserID, reaction.Emoji, reaction.CreatedAt).
+		Suffix("ON CONFLICT DO NOTHING").
+		ToSql()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	return r.change(ctx, reaction, query, args)
+}
+
+// RemoveReaction removes a reaction and returns reaction counts of its target right after the change.
+// It returns false if there was no such reaction.
+func (r *ReactionRepository) RemoveReaction(ctx context.Context, reaction *entity.Reaction) ([]entity.ReactionCount, bool, error) {
+	ctx, span := tracer.Start(ctx, "ReactionRepository.RemoveReaction")
+	defer span.End()
+
+	r.log.DebugContext(
+		ctx,
+		"RemoveReaction",
+		"targetType", reaction.TargetType,
+		"targetID", reaction.TargetID,
+	)
+
+	query, args, err := r.Builder.Delete("reactions").
+		Where("target_type = ? AND target_id = ? AND user_id = ? AND emoji = ?",
+			reaction.TargetType, reaction.TargetID, reaction.UserID, reaction.Emoji).
+		ToSql()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	return r.change(ctx, reaction, query, args)
+}
+
+// change executes a query adding or removing the reaction and counts reactions of its target in the same transaction.
+// Transactions take the write lock when they begin, so the counts are exactly the ones after this change.
+func (r *ReactionRepository) change(ctx context.Context, reaction *entity.Reaction, query string,
+	args []interface{}) ([]entity.ReactionCount, bool, error) {
+	var counts []entity.ReactionCount
+	var changed bool
+
+	err := r.BeginFunc(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to execute query: %w", err)
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get affected rows: %w", err)
+		}
+		changed = affected == 1
+
+		counts, err = r.counts(ctx, tx, reaction.TargetType, reaction.TargetID)
+		return err
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return counts, changed, nil
+}
+
+// GetReactionCounts returns amounts of reactions of a post or a comment, most popular first.
+func (r *ReactionRepository) GetReactionCounts(ctx context.Context, targetType entity.ReactionTargetType, targetID int) ([]entity.ReactionCount, error) {
+	ctx, span := tracer.Start(ctx, "ReactionRepository.GetReactionCounts")
+	defer span.End()
+
+	r.log.DebugContext(
+		ctx,
+		"GetReactionCounts",
+		"targetType", targetType,
+		"targetID", targetID,
+	)
+
+	return r.counts(ctx, r.DB, targetType, targetID)
+}
+
+// querier is a database or a transaction.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// counts returns amounts of reactions of the target, most popular first.
+func (r *ReactionRepository) counts(ctx context.Context, q querier, targetType entity.ReactionTargetType,
+	targetID int) ([]entity.ReactionCount, error) {
+	query, args, err := r.Builder.Select("emoji", "COUNT(*)").
+		From("reactions").
+		Where("target_type = ? AND target_id = ?", targetType, targetID).
+		GroupBy("emoji").
+		OrderBy("COUNT(*) DESC", "emoji").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sql: %w", err)
+	}
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make([]entity.ReactionCount, 0)
+	for rows.Next() {
+		var count entity.ReactionCount
+		err = rows.Scan(&count.Emoji, &count.Count)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		counts = append(counts, count)
+	}
+
+	return counts, rows.Err()
+}
@@ -0,0 +1,80 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/oustrix/ozon_journal/internal/entity"
+)
+
+func TestReactionRepository(t *testing.T) {
+	r := NewReactionRepository(newTestSQLite(t), newTestLogger())
+	ctx := context.Background()
+
+	reaction := func(userID int, emoji string) *entity.Reaction {
+		return &entity.Reaction{TargetType: entity.ReactionTargetPost, TargetID: 1, UserID: userID, Emoji: emoji,
+			CreatedAt: 1700000000}
+	}
+
+	// Steps run in order against the same target.
+	steps := []struct {
+		name        string
+		add         bool
+		reaction    *entity.Reaction
+		wantChanged bool
+		want        string
+	}{
+		{name: "first reaction", add: true, reaction: reaction(1, "👍"), wantChanged: true, want: "[{👍 1}]"},
+		{name: "same reaction again", add: true, reaction: reaction(1, "👍"), want: "[{👍 1}]"},
+		{name: "another user", add: true, reaction: reaction(2, "👍"), wantChanged: true, want: "[{👍 2}]"},
+		{name: "another emoji", add: true, reaction: reaction(1, "🔥"), wantChanged: true, want: "[{👍 2} {🔥 1}]"},
+		{name: "remove", reaction: reaction(2, "👍"), wantChanged: true, want: "[{👍 1} {🔥 1}]"},
+		{name: "remove missing", reaction: reaction(2, "👍"), want: "[{👍 1} {🔥 1}]"},
+		{name: "remove the last of emoji", reaction: reaction(1, "🔥"), wantChanged: true, want: "[{👍 1}]"},
+	}
+
+	for _, tt := range steps {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				counts  []entity.ReactionCount
+				changed bool
+				err     error
+			)
+			if tt.add {
+				counts, changed, err = r.AddReaction(ctx, tt.reaction)
+			} else {
+				counts, changed, err = r.RemoveReaction(ctx, tt.reaction)
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+
+			if changed != tt.wantChanged {
+				t.Errorf("changed = %v, want %v", changed, tt.wantChanged)
+			}
+			if got := fmt.Sprint(counts); got != tt.want {
+				t.Errorf("counts = %s, want %s", got, tt.want)
+			}
+
+			// Counts read later match the ones returned with the change.
+			stored, err := r.GetReactionCounts(ctx, entity.ReactionTargetPost, 1)
+			if err != nil {
+				t.Fatalf("GetReactionCounts() error = %v", err)
+			}
+			if got := fmt.Sprint(stored); got != tt.want {
+				t.Errorf("GetReactionCounts() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("other target", func(t *testing.T) {
+		counts, err := r.GetReactionCounts(ctx, entity.ReactionTargetComment, 1)
+		if err != nil {
+			t.Fatalf("GetReactionCounts() error = %v", err)
+		}
+		if len(counts) != 0 {
+			t.Errorf("GetReactionCounts() = %v, want no counts", counts)
+		}
+	})
+}
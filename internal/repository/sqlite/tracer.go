@@ -0,0 +1,6 @@
+package sqlite
+
+import "go.opentelemetry.io/otel"
+
+// tracer creates spans of repository methods with the global tracer provider.
+var tracer = otel.Tracer("github.com/oustrix/ozon_journal/internal/repository/sqlite")
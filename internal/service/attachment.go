@@ -0,0 +1,148 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oustrix/ozon_journal/internal/entity"
+)
+
+// sniffLen is the number of bytes http.DetectContentType looks at.
+const sniffLen = 512
+
+// AttachFile stores a file and attaches it to a post. Files can be attached by authors of posts and moderators.
+// The content type is detected from the content, the one declared by the client isn't trusted.
+func (s *PostService) AttachFile(ctx context.Context, postID int, uploaderID int, upload *entity.Upload) (*entity.Attachment, error) {
+	ctx, span := tracer.Start(ctx, "PostService.AttachFile")
+	defer span.End()
+
+	s.log.DebugContext(
+		ctx,
+		"AttachFile",
+		"postID", postID,
+		"uploaderID", uploaderID,
+		"size", upload.Size,
+	)
+
+	post, err := s.editablePost(ctx, postID, uploaderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if upload.Size <= 0 {
+		return nil, entity.InvalidArgumentError("file is empty")
+	} else if uint64(upload.Size) > uint64(s.attachmentCfg.MaxSize) { // Safely cast size to uint64, it checked for sign.
+		return nil, entity.InvalidArgumentError("file is too large, max size is %d bytes", s.attachmentCfg.MaxSize)
+	}
+
+	content := bufio.NewReaderSize(upload.Content, sniffLen)
+	head, err := content.Peek(sniffLen)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if !s.contentTypes[contentType] {
+		return nil, entity.InvalidArgumentError("content type %q is not allowed", contentType)
+	}
+
+	attachment := &entity.Attachment{
+		PostID:      post.ID,
+		Key:         fmt.Sprintf("posts/%d/%s", post.ID, uuid.NewString()),
+		FileName:    cleanFileName(upload.FileName),
+		ContentType: contentType,
+		Size:        upload.Size,
+		UploaderID:  uploaderID,
+		CreatedAt:   int(time.Now().Unix()),
+	}
+
+	err = s.blobs.PutBlob(ctx, attachment.Key, content, attachment.Size, attachment.ContentType)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := s.attachmentRepo.CreateAttachment(ctx, attachment)
+	if err != nil {
+		// The blob isn't referenced by anything, so it's removed. The request context may be already canceled.
+		deleteErr := s.blobs.DeleteBlob(context.Background(), attachment.Key)
+		if deleteErr != nil {
+			s.log.ErrorContext(ctx, "failed to delete orphaned blob", "key", attachment.Key, "error", deleteErr.Error())
+		}
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// GetAttachments returns attachments of a post, the oldest first.
+func (s *PostService) GetAttachments(ctx context.Context, postID int) ([]entity.Attachment, error) {
+	ctx, span := tracer.Start(ctx, "PostService.GetAttachments")
+	defer span.End()
+
+	s.log.DebugContext(
+		ctx,
+		"GetAttachments",
+		"postID", postID,
+	)
+
+	return s.attachmentRepo.GetAttachmentsByPostID(ctx, postID)
+}
+
+// GetAttachment returns an attachment and its content if its post is visible to the viewer. The caller must close the content.
+func (s *PostService) GetAttachment(ctx context.Context, id int, viewerID int) (*entity.Attachment, io.ReadCloser, error) {
+	ctx, span := tracer.Start(ctx, "PostService.GetAttachment")
+	defer span.End()
+
+	s.log.DebugContext(
+		ctx,
+		"GetAttachment",
+		"id", id,
+		"viewerID", viewerID,
+	)
+
+	attachment, err := s.attachmentRepo.GetAttachmentByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, err = s.GetPostByID(ctx, attachment.PostID, viewerID)
+	if errors.Is(err, entity.ErrNotFound) {
+		// Attachments of hidden posts are hidden too.
+		return nil, nil, entity.NotFoundError("attachment with ID %d not found", id)
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	content, err := s.blobs.GetBlob(ctx, attachment.Key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return attachment, content, nil
+}
+
+// cleanFileName drops the directory part of a client file name and replaces characters unsafe for headers.
+func cleanFileName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '"' {
+			return '_'
+		}
+		return r
+	}, name)
+
+	if name == "." || name == "/" {
+		return "file"
+	}
+
+	return name
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -14,70 +15,117 @@ import (
 
 // CommentService is a service for managing comments.
 type CommentService struct {
-	repo internal.CommentRepository
-	cfg  *config.Comment
-	sub  *subscriptionManager
-	log  *logger.Logger
+	repo         internal.CommentRepository
+	postRepo     internal.PostRepository
+	presenceRepo internal.PresenceRepository
+	cfg          *config.Comment
+	presenceCfg  *config.Presence
+	sub          *subscriptionManager
+	presence     *presenceTracker
+	log          *logger.Logger
 }
 
 type subscription struct {
-	id     uuid.UUID
-	postID int
-	ch     chan *entity.Comment
+	id        uuid.UUID
+	postIDs   []int // Set for subscriptions to comments of posts.
+	commentID int   // Set for subscriptions to replies of a comment.
+	ch        chan *entity.Comment
+}
+
+// commentEvent is a created comment together with the IDs of all comments it replies to, directly or not.
+type commentEvent struct {
+	comment   *entity.Comment
+	ancestors []int
 }
 
 type subscriptionManager struct {
-	subscribers map[int][]*subscription
-	register    chan *subscription
-	unregister  chan *subscription
-	comments    chan *entity.Comment
+	subscriptions map[uuid.UUID]*subscription
+	byPost        map[int][]*subscription
+	byComment     map[int][]*subscription
+	register      chan *subscription
+	unregister    chan uuid.UUID
+	comments      chan *commentEvent
+	subscriptionCounters
+	managerState
 }
 
-// NewCommentService creates a new CommentService.
-func NewCommentService(repo internal.CommentRepository, cfg *config.Comment, log *logger.Logger) *CommentService {
+// NewCommentService creates a new CommentService. Subscriptions are served until the context is done.
+func NewCommentService(ctx context.Context, repo internal.CommentRepository, postRepo internal.PostRepository,
+	presenceRepo internal.PresenceRepository, cfg *config.Comment, presenceCfg *config.Presence, log *logger.Logger) *CommentService {
 	return &CommentService{
-		repo: repo,
-		cfg:  cfg,
-		sub:  newSubscriptionManager(),
-		log:  log,
+		repo:         repo,
+		postRepo:     postRepo,
+		presenceRepo: presenceRepo,
+		cfg:          cfg,
+		presenceCfg:  presenceCfg,
+		sub:          newSubscriptionManager(ctx),
+		presence:     newPresenceTracker(ctx, presenceRepo, presenceCfg),
+		log:          log.With("layer", "service"),
 	}
 }
 
-func newSubscriptionManager() *subscriptionManager {
+func newSubscriptionManager(ctx context.Context) *subscriptionManager {
 	sm := &subscriptionManager{
-		subscribers: make(map[int][]*subscription),
-		register:    make(chan *subscription),
-		unregister:  make(chan *subscription),
-		comments:    make(chan *entity.Comment),
+		subscriptions: make(map[uuid.UUID]*subscription),
+		byPost:        make(map[int][]*subscription),
+		byComment:     make(map[int][]*subscription),
+		register:      make(chan *subscription),
+		unregister:    make(chan uuid.UUID),
+		comments:      make(chan *commentEvent),
+		managerState:  newManagerState(),
 	}
 
 	go func() {
+		defer sm.stopped()
+		defer sm.closeSubscriptions()
+
 		for {
 			select {
+			case <-ctx.Done():
+				return
+			// Answer a readiness check.
+			case <-sm.pings:
 			// Register a new subscriber.
 			case sub := <-sm.register:
-				sm.subscribers[sub.postID] = append(sm.subscribers[sub.postID], sub)
-			// Unregister a subscriber. If there are no more subscribers for a post, delete the post from the map.
-			case sub := <-sm.unregister:
-				subs := sm.subscribers[sub.postID]
-				for i, s := range subs {
-					if s.id == sub.id {
-						sm.subscribers[sub.postID] = append(subs[:i], subs[i+1:]...)
-						close(s.ch)
-						break
+				sm.subscriptions[sub.id] = sub
+				if sub.commentID != 0 {
+					sm.byComment[sub.commentID] = append(sm.byComment[sub.commentID], sub)
+				} else {
+					for _, postID := range sub.postIDs {
+						sm.byPost[postID] = append(sm.byPost[postID], sub)
+					}
+				}
+				sm.active.Store(int64(len(sm.subscriptions)))
+			// Unregister a subscriber and close its channel.
+			case id := <-sm.unregister:
+				if sub, ok := sm.subscriptions[id]; ok {
+					sm.remove(sub)
+				}
+			// Send a comment to all subscribers of the post and of the comments it replies to without waiting
+			// for them. Subscribers that don't keep up are disconnected, so they don't stall creating of comments.
+			case event := <-sm.comments:
+				slow := make([]*subscription, 0)
+				send := func(sub *subscription) {
+					select {
+					case sub.ch <- event.comment:
+					default:
+						slow = append(slow, sub)
 					}
 				}
-				if len(sm.subscribers[sub.postID]) == 0 {
-					delete(sm.subscribers, sub.postID)
+
+				for _, sub := range sm.byPost[event.comment.PostID] {
+					send(sub)
 				}
-			// Send a comment to all subscribers of the post.
-			case comment := <-sm.comments:
-				if subs, ok := sm.subscribers[comment.PostID]; ok {
-					for _, sub := range subs {
-						sub.ch <- comment
+				for _, ancestorID := range event.ancestors {
+					for _, sub := range sm.byComment[ancestorID] {
+						send(sub)
 					}
+				}
 
+				for _, sub := range slow {
+					sm.remove(sub)
 				}
+				sm.queued.Add(-1)
 			}
 		}
 	}()
@@ -85,8 +133,65 @@ func newSubscriptionManager() *subscriptionManager {
 	return sm
 }
 
-// GetCommentsByPostID returns comments for a post.
-func (s *CommentService) GetCommentsByPostID(ctx context.Context, postID, page, amount int) (*[]entity.Comment, error) {
+// publish sends an event to the manager, blocking until the manager takes it.
+// Events published after the manager stops are dropped, there are no subscribers to send them to.
+func (sm *subscriptionManager) publish(event *commentEvent) {
+	sm.queued.Add(1)
+	select {
+	case sm.comments <- event:
+	case <-sm.done:
+		sm.queued.Add(-1)
+	}
+}
+
+// remove removes a subscription and closes its channel.
+func (sm *subscriptionManager) remove(sub *subscription) {
+	delete(sm.subscriptions, sub.id)
+	if sub.commentID != 0 {
+		removeSubscription(sm.byComment, sub.commentID, sub.id)
+	} else {
+		for _, postID := range sub.postIDs {
+			removeSubscription(sm.byPost, postID, sub.id)
+		}
+	}
+	sm.active.Store(int64(len(sm.subscriptions)))
+	close(sub.ch)
+}
+
+// closeSubscriptions closes channels of all subscribers, so their subscriptions complete.
+func (sm *subscriptionManager) closeSubscriptions() {
+	for id, sub := range sm.subscriptions {
+		delete(sm.subscriptions, id)
+		close(sub.ch)
+	}
+	clear(sm.byPost)
+	clear(sm.byComment)
+	sm.active.Store(0)
+}
+
+// removeSubscription removes the subscription with the given id from subs[key].
+// If there are no more subscriptions for the key, the key is deleted from the map.
+func removeSubscription(subs map[int][]*subscription, key int, id uuid.UUID) {
+	list := subs[key]
+	for i, s := range list {
+		if s.id == id {
+			list = append(list[:i], list[i+1:]...)
+			break
+		}
+	}
+
+	if len(list) == 0 {
+		delete(subs, key)
+	} else {
+		subs[key] = list
+	}
+}
+
+// GetCommentsByPostID returns comments for a post visible to the viewer.
+func (s *CommentService) GetCommentsByPostID(ctx context.Context, postID, viewerID, page, amount int) (*[]entity.Comment, error) {
+	ctx, span := tracer.Start(ctx, "CommentService.GetCommentsByPostID")
+	defer span.End()
+
 	// Check if page and wasn't passed and set them to default values.
 	var pageNumber, pageAmount uint
 	if page < 0 {
@@ -101,33 +206,64 @@ func (s *CommentService) GetCommentsByPostID(ctx context.Context, postID, page,
 		pageAmount = uint(amount) // We can safely cast amount to uint because we already checked if it's less than 0.
 	}
 
-	s.log.Debug(
+	if pageAmount > s.cfg.MaxAmount {
+		return nil, entity.InvalidArgumentError("amount must not exceed %d", s.cfg.MaxAmount)
+	}
+
+	s.log.DebugContext(
+		ctx,
 		"GetCommentsByPostID",
-		"layer", "service",
 		"postID", postID,
+		"viewerID", viewerID,
 		"pageNumber", pageNumber,
 		"pageAmount", pageAmount,
-		"requestID", ctx.Value("requestID"),
 	)
 
+	// Comments of posts the viewer can't see aren't listed either, without revealing that the post exists.
+	post, err := s.postRepo.GetPostByID(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	if post.Status != entity.PostStatusPublished && post.AuthorID != viewerID {
+		return nil, entity.NotFoundError("post with ID %d not found", postID)
+	}
+
 	return s.repo.GetCommentsByPostID(ctx, postID, pageNumber, pageAmount)
 }
 
 // CreateComment creates a new comment.
 func (s *CommentService) CreateComment(ctx context.Context, comment *entity.Comment) (*entity.Comment, error) {
+	ctx, span := tracer.Start(ctx, "CommentService.CreateComment")
+	defer span.End()
+
 	// Check for empty fields and length of content.
 	if []rune(comment.Content) == nil {
-		return nil, fmt.Errorf("content is empty")
+		return nil, entity.InvalidArgumentError("content is empty")
 	} else if uint(len([]rune(comment.Content))) > s.cfg.MaxCharacters { // Safely cast content to uint, it checked for nil.
-		return nil, fmt.Errorf("content is too long")
+		return nil, entity.InvalidArgumentError("content is too long")
+	} else if !validContentFormat(comment.ContentFormat) {
+		return nil, entity.InvalidArgumentError("unknown content format %q", comment.ContentFormat)
+	}
+
+	// Replies must be left under comments of the same post.
+	if comment.ParentCommentID > 0 {
+		parent, err := s.repo.GetCommentByID(ctx, comment.ParentCommentID)
+		if errors.Is(err, entity.ErrNotFound) {
+			return nil, entity.InvalidArgumentError("parent comment %d not found", comment.ParentCommentID)
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to get parent comment %d: %w", comment.ParentCommentID, err)
+		}
+
+		if parent.PostID != comment.PostID {
+			return nil, entity.InvalidArgumentError("parent comment %d belongs to another post", comment.ParentCommentID)
+		}
 	}
 
 	comment.PublishedAt = int(time.Now().Unix())
 
-	s.log.Debug(
+	s.log.DebugContext(
+		ctx,
 		"CreateComment",
-		"layer", "service",
-		"requestID", ctx.Value("requestID"),
 	)
 
 	comment, err := s.repo.CreateComment(ctx, comment)
@@ -135,44 +271,136 @@ func (s *CommentService) CreateComment(ctx context.Context, comment *entity.Comm
 		return nil, err
 	}
 
+	// The comment is created already, so a broken reply chain only keeps it from subscribers
+	// to replies of further ancestors.
+	ancestors, err := s.ancestors(ctx, comment)
+	if err != nil {
+		s.log.ErrorContext(
+			ctx,
+			"Failed to get ancestors of comment",
+			"commentID", comment.ID,
+			"error", err.Error(),
+		)
+	}
+
 	// Send the comment to all subscribers.
-	s.sub.comments <- comment
+	s.sub.publish(&commentEvent{comment: comment, ancestors: ancestors})
 
 	return comment, nil
 }
 
-// SubscribeComments subscribes to comments for a post.
-func (s *CommentService) SubscribeComments(ctx context.Context, postID int) (<-chan *entity.Comment, uuid.UUID, error) {
+// ancestors returns IDs of all comments in the reply chain of the comment, starting from its parent.
+// If the chain can't be followed to its root, the IDs found so far are returned with the error.
+func (s *CommentService) ancestors(ctx context.Context, comment *entity.Comment) ([]int, error) {
+	ancestors := make([]int, 0)
+	visited := map[int]bool{comment.ID: true}
+
+	// Root comments have ParentCommentID set to -1.
+	for parentID := comment.ParentCommentID; parentID > 0 && !visited[parentID]; {
+		visited[parentID] = true
+		ancestors = append(ancestors, parentID)
+
+		parent, err := s.repo.GetCommentByID(ctx, parentID)
+		if err != nil {
+			return ancestors, fmt.Errorf("failed to get parent comment %d: %w", parentID, err)
+		}
+		parentID = parent.ParentCommentID
+	}
+
+	return ancestors, nil
+}
+
+// SubscribeComments subscribes to comments for a list of posts. The subscription is closed if the subscriber
+// falls behind by more than subscriptionBufferSize comments, the same as of SubscribeReplies.
+func (s *CommentService) SubscribeComments(ctx context.Context, postIDs []int) (<-chan *entity.Comment, uuid.UUID, error) {
+	ctx, span := tracer.Start(ctx, "CommentService.SubscribeComments")
+	defer span.End()
+
+	if len(postIDs) == 0 {
+		return nil, uuid.Nil, entity.InvalidArgumentError("post IDs are empty")
+	}
+
 	sub := &subscription{
-		id:     uuid.New(),
-		postID: postID,
-		ch:     make(chan *entity.Comment),
+		id:      uuid.New(),
+		postIDs: uniqueIDs(postIDs),
+		ch:      make(chan *entity.Comment, subscriptionBufferSize),
 	}
 
-	s.log.Debug(
+	s.log.DebugContext(
+		ctx,
 		"SubscribeComments",
-		"layer", "service",
-		"postID", postID,
+		"postIDs", sub.postIDs,
 		"subscriptionID", sub.id,
-		"requestID", ctx.Value("requestID"),
 	)
 
-	s.sub.register <- sub
+	select {
+	case s.sub.register <- sub:
+	case <-s.sub.done:
+		return nil, uuid.Nil, ErrShuttingDown
+	}
+
 	return sub.ch, sub.id, nil
 }
 
-// UnsubscribeComments unsubscribes from comments for a post.
-func (s *CommentService) UnsubscribeComments(ctx context.Context, subscriptionID uuid.UUID) {
+// SubscribeReplies subscribes to replies of a comment, including replies to replies.
+func (s *CommentService) SubscribeReplies(ctx context.Context, commentID int) (<-chan *entity.Comment, uuid.UUID, error) {
+	ctx, span := tracer.Start(ctx, "CommentService.SubscribeReplies")
+	defer span.End()
+
+	if commentID <= 0 {
+		return nil, uuid.Nil, entity.InvalidArgumentError("comment ID is invalid")
+	}
+
 	sub := &subscription{
-		id: subscriptionID,
+		id:        uuid.New(),
+		commentID: commentID,
+		ch:        make(chan *entity.Comment, subscriptionBufferSize),
+	}
+
+	s.log.DebugContext(
+		ctx,
+		"SubscribeReplies",
+		"commentID", commentID,
+		"subscriptionID", sub.id,
+	)
+
+	select {
+	case s.sub.register <- sub:
+	case <-s.sub.done:
+		return nil, uuid.Nil, ErrShuttingDown
 	}
 
-	s.log.Debug(
+	return sub.ch, sub.id, nil
+}
+
+// UnsubscribeComments cancels a subscription created by SubscribeComments or SubscribeReplies.
+func (s *CommentService) UnsubscribeComments(ctx context.Context, subscriptionID uuid.UUID) {
+	ctx, span := tracer.Start(ctx, "CommentService.UnsubscribeComments")
+	defer span.End()
+
+	s.log.DebugContext(
+		ctx,
 		"UnsubscribeComments",
-		"layer", "service",
 		"subscriptionID", subscriptionID,
-		"requestID", ctx.Value("requestID"),
 	)
 
-	s.sub.unregister <- sub
+	select {
+	case s.sub.unregister <- subscriptionID:
+	case <-s.sub.done:
+		// Subscriptions are closed by the stopped manager.
+	}
+}
+
+// uniqueIDs returns ids without duplicates, preserving the order.
+func uniqueIDs(ids []int) []int {
+	seen := make(map[int]bool, len(ids))
+	unique := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	return unique
 }
@@ -0,0 +1,324 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oustrix/ozon_journal/config"
+	"github.com/oustrix/ozon_journal/internal"
+	"github.com/oustrix/ozon_journal/internal/entity"
+	"github.com/oustrix/ozon_journal/internal/repository/inmemory"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+)
+
+// commentRepoStub keeps comments in a map. Methods the tests don't use aren't implemented.
+type commentRepoStub struct {
+	internal.CommentRepository
+	mu       sync.Mutex
+	comments map[int]*entity.Comment
+}
+
+func newCommentRepoStub(comments ...entity.Comment) *commentRepoStub {
+	r := &commentRepoStub{comments: make(map[int]*entity.Comment)}
+	for i := range comments {
+		r.comments[comments[i].ID] = &comments[i]
+	}
+
+	return r
+}
+
+func (r *commentRepoStub) GetCommentByID(_ context.Context, id int) (*entity.Comment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	comment, ok := r.comments[id]
+	if !ok {
+		return nil, entity.NotFoundError("comment with id %d not found", id)
+	}
+	copied := *comment
+
+	return &copied, nil
+}
+
+func (r *commentRepoStub) CreateComment(_ context.Context, comment *entity.Comment) (*entity.Comment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	comment.ID = len(r.comments) + 100
+	copied := *comment
+	r.comments[comment.ID] = &copied
+
+	return comment, nil
+}
+
+func (r *commentRepoStub) GetCommentsByPostID(_ context.Context, postID int, _ uint, _ uint) (*[]entity.Comment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	comments := make([]entity.Comment, 0)
+	for _, comment := range r.comments {
+		if comment.PostID == postID {
+			comments = append(comments, *comment)
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool { return comments[i].ID < comments[j].ID })
+
+	return &comments, nil
+}
+
+func newTestCommentService(repo *commentRepoStub, postRepo *postRepoStub) *CommentService {
+	log := logger.New("error")
+	return NewCommentService(context.Background(), repo, postRepo, inmemory.NewPresenceRepository(log),
+		&config.Comment{MaxCharacters: 100, DefaultAmount: 10, MaxAmount: 50},
+		&config.Presence{ViewerTTL: 60, TypingTTL: 5}, log)
+}
+
+// receive returns IDs of comments sent to the channel until nothing is sent for a while.
+func receive(ch <-chan *entity.Comment) []int {
+	ids := make([]int, 0)
+	for {
+		select {
+		case comment := <-ch:
+			ids = append(ids, comment.ID)
+		case <-time.After(50 * time.Millisecond):
+			sort.Ints(ids)
+			return ids
+		}
+	}
+}
+
+func TestCreateCommentParent(t *testing.T) {
+	tests := []struct {
+		name     string
+		postID   int
+		parentID int
+		wantErr  error
+	}{
+		{name: "root comment", postID: 1, parentID: -1},
+		{name: "reply", postID: 1, parentID: 1},
+		{name: "missing parent", postID: 1, parentID: 5, wantErr: entity.ErrInvalidArgument},
+		{name: "parent of another post", postID: 2, parentID: 1, wantErr: entity.ErrInvalidArgument},
+		// The parent of comment 2 is missing, so ancestors of the reply can't be found after it's created.
+		{name: "broken reply chain", postID: 1, parentID: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newCommentRepoStub(
+				entity.Comment{ID: 1, PostID: 1, ParentCommentID: -1},
+				entity.Comment{ID: 2, PostID: 1, ParentCommentID: 9},
+			)
+			s := newTestCommentService(repo, newPostRepoStub())
+
+			_, err := s.CreateComment(context.Background(), &entity.Comment{
+				Content:         "reply",
+				ContentFormat:   entity.ContentFormatPlain,
+				PostID:          tt.postID,
+				ParentCommentID: tt.parentID,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateComment() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetCommentsByPostID(t *testing.T) {
+	tests := []struct {
+		name     string
+		postID   int
+		viewerID int
+		amount   int
+		want     []int
+		wantErr  error
+	}{
+		{name: "published post", postID: 1, amount: -1, want: []int{1, 2}},
+		{name: "largest amount", postID: 1, amount: 50, want: []int{1, 2}},
+		{name: "amount over the limit", postID: 1, amount: 51, wantErr: entity.ErrInvalidArgument},
+		{name: "published post without comments", postID: 4, amount: -1, want: []int{}},
+		{name: "draft of the viewer", postID: 2, viewerID: 1, amount: -1, want: []int{3}},
+		{name: "draft of another author", postID: 2, viewerID: 2, amount: -1, wantErr: entity.ErrNotFound},
+		{name: "draft for an anonymous viewer", postID: 2, amount: -1, wantErr: entity.ErrNotFound},
+		{name: "scheduled post of another author", postID: 3, viewerID: 2, amount: -1, wantErr: entity.ErrNotFound},
+		{name: "missing post", postID: 5, amount: -1, wantErr: entity.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestCommentService(
+				newCommentRepoStub(
+					entity.Comment{ID: 1, PostID: 1, ParentCommentID: -1},
+					entity.Comment{ID: 2, PostID: 1, ParentCommentID: 1},
+					entity.Comment{ID: 3, PostID: 2, ParentCommentID: -1},
+				),
+				newPostRepoStub(
+					entity.Post{ID: 1, AuthorID: 1, Status: entity.PostStatusPublished},
+					entity.Post{ID: 2, AuthorID: 1, Status: entity.PostStatusDraft},
+					entity.Post{ID: 3, AuthorID: 1, Status: entity.PostStatusScheduled},
+					entity.Post{ID: 4, AuthorID: 1, Status: entity.PostStatusPublished},
+				),
+			)
+
+			comments, err := s.GetCommentsByPostID(context.Background(), tt.postID, tt.viewerID, -1, tt.amount)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetCommentsByPostID() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			ids := make([]int, 0, len(*comments))
+			for _, comment := range *comments {
+				ids = append(ids, comment.ID)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.want) {
+				t.Errorf("GetCommentsByPostID() = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommentSubscriptions(t *testing.T) {
+	// Comment 1 is a root comment of post 1, comment 2 replies to it and comment 3 is a root comment of post 2.
+	existing := []entity.Comment{
+		{ID: 1, PostID: 1, ParentCommentID: -1},
+		{ID: 2, PostID: 1, ParentCommentID: 1},
+		{ID: 3, PostID: 2, ParentCommentID: -1},
+	}
+
+	tests := []struct {
+		name     string
+		postID   int
+		parentID int
+		// Whether subscribers to post 1, to posts 1 and 2, to replies of comment 1 and to replies of comment 2
+		// receive the comment.
+		want [4]bool
+	}{
+		{name: "root comment of post 1", postID: 1, parentID: -1, want: [4]bool{true, true, false, false}},
+		{name: "root comment of post 2", postID: 2, parentID: -1, want: [4]bool{false, true, false, false}},
+		{name: "reply to a root comment", postID: 1, parentID: 1, want: [4]bool{true, true, true, false}},
+		{name: "reply to a reply", postID: 1, parentID: 2, want: [4]bool{true, true, true, true}},
+		{name: "reply to a comment of post 2", postID: 2, parentID: 3, want: [4]bool{false, true, false, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newTestCommentService(newCommentRepoStub(existing...), newPostRepoStub())
+
+			subscribers := []func() (<-chan *entity.Comment, uuid.UUID, error){
+				func() (<-chan *entity.Comment, uuid.UUID, error) { return s.SubscribeComments(ctx, []int{1}) },
+				// Duplicate post IDs must not duplicate comments.
+				func() (<-chan *entity.Comment, uuid.UUID, error) { return s.SubscribeComments(ctx, []int{1, 2, 1}) },
+				func() (<-chan *entity.Comment, uuid.UUID, error) { return s.SubscribeReplies(ctx, 1) },
+				func() (<-chan *entity.Comment, uuid.UUID, error) { return s.SubscribeReplies(ctx, 2) },
+			}
+
+			channels := make([]<-chan *entity.Comment, 0, len(subscribers))
+			for _, subscribe := range subscribers {
+				ch, _, err := subscribe()
+				if err != nil {
+					t.Fatalf("failed to subscribe: %v", err)
+				}
+				channels = append(channels, ch)
+			}
+
+			received := make([][]int, len(channels))
+			var wg sync.WaitGroup
+			for i, ch := range channels {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					received[i] = receive(ch)
+				}()
+			}
+
+			comment, err := s.CreateComment(ctx, &entity.Comment{
+				Content:         "comment",
+				ContentFormat:   entity.ContentFormatPlain,
+				PostID:          tt.postID,
+				ParentCommentID: tt.parentID,
+			})
+			if err != nil {
+				t.Fatalf("CreateComment() error = %v", err)
+			}
+			wg.Wait()
+
+			for i, ids := range received {
+				want := []int{}
+				if tt.want[i] {
+					want = []int{comment.ID}
+				}
+				if fmt.Sprint(ids) != fmt.Sprint(want) {
+					t.Errorf("subscriber %d received %v, want %v", i, ids, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCommentSubscriptionEviction(t *testing.T) {
+	tests := []struct {
+		name       string
+		created    int
+		wantClosed bool
+	}{
+		{name: "subscriber keeps up", created: subscriptionBufferSize},
+		{name: "subscriber falls behind", created: subscriptionBufferSize + 1, wantClosed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newTestCommentService(newCommentRepoStub(), newPostRepoStub())
+
+			slow, _, err := s.SubscribeComments(ctx, []int{1})
+			if err != nil {
+				t.Fatalf("SubscribeComments() error = %v", err)
+			}
+			fast, fastID, err := s.SubscribeComments(ctx, []int{1})
+			if err != nil {
+				t.Fatalf("SubscribeComments() error = %v", err)
+			}
+			defer s.UnsubscribeComments(ctx, fastID)
+
+			received := make(chan int)
+			go func() {
+				received <- len(receive(fast))
+			}()
+
+			for i := 0; i < tt.created; i++ {
+				comment := &entity.Comment{Content: "comment", ContentFormat: entity.ContentFormatPlain, PostID: 1, ParentCommentID: -1}
+				_, err := s.CreateComment(ctx, comment)
+				if err != nil {
+					t.Fatalf("CreateComment() error = %v", err)
+				}
+			}
+
+			if n := <-received; n != tt.created {
+				t.Errorf("the subscriber reading comments received %d comments, want %d", n, tt.created)
+			}
+
+			// The channel of a disconnected subscriber is closed after the comments it has buffered.
+			for i := 0; i < subscriptionBufferSize; i++ {
+				<-slow
+			}
+			select {
+			case _, ok := <-slow:
+				if ok || !tt.wantClosed {
+					t.Errorf("the channel of the slow subscriber is closed: %v, want %v", !ok, tt.wantClosed)
+				}
+			case <-time.After(50 * time.Millisecond):
+				if tt.wantClosed {
+					t.Error("the slow subscriber isn't disconnected")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,501 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/oustrix/ozon_journal/internal"
+	"github.com/oustrix/ozon_journal/internal/entity"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+)
+
+// A dump is a JSON Lines stream. The first line is a header, then go posts ordered by ID, then comments
+// ordered by ID, so every record refers only to records above it. Fields of records are named explicitly
+// and don't follow changes of entities, a change of the format must bump dumpVersion.
+const (
+	dumpFormat  = "ozon_journal"
+	dumpVersion = 1
+
+	// dumpPageSize is the number of posts or comments read from repositories at once.
+	dumpPageSize = 100
+)
+
+// Types of dump records.
+const (
+	dumpRecordHeader  = "header"
+	dumpRecordPost    = "post"
+	dumpRecordComment = "comment"
+)
+
+// dumpRecord is a line of a dump. Exactly one of the entity fields is set, according to the type.
+type dumpRecord struct {
+	Type    string       `json:"type"`
+	Header  *dumpHeader  `json:"header,omitempty"`
+	Post    *dumpPost    `json:"post,omitempty"`
+	Comment *dumpComment `json:"comment,omitempty"`
+}
+
+type dumpHeader struct {
+	Format     string `json:"format"`
+	Version    int    `json:"version"`
+	ExportedAt int    `json:"exported_at"`
+}
+
+// dumpPost is a post with all its revisions and slugs. The current slug is one of the slugs.
+type dumpPost struct {
+	ID            int            `json:"id"`
+	Title         string         `json:"title"`
+	Slug          string         `json:"slug"`
+	Content       string         `json:"content"`
+	ContentFormat string         `json:"content_format"`
+	Status        string         `json:"status"`
+	AuthorID      int            `json:"author_id"`
+	Commentable   bool           `json:"commentable"`
+	PublishedAt   int            `json:"published_at,omitempty"`
+	PublishAt     int            `json:"publish_at,omitempty"`
+	Revision      int            `json:"revision"`
+	Revisions     []dumpRevision `json:"revisions"`
+	Slugs         []string       `json:"slugs"`
+}
+
+type dumpRevision struct {
+	Version   int    `json:"version"`
+	Title     string `json:"title"`
+	Content   string `json:"content"`
+	EditorID  int    `json:"editor_id"`
+	CreatedAt int    `json:"created_at"`
+}
+
+// dumpComment is a comment. ParentID is null for comments to posts.
+type dumpComment struct {
+	ID            int    `json:"id"`
+	PostID        int    `json:"post_id"`
+	ParentID      *int   `json:"parent_id"`
+	Content       string `json:"content"`
+	ContentFormat string `json:"content_format"`
+	AuthorID      int    `json:"author_id"`
+	PublishedAt   int    `json:"published_at"`
+}
+
+// DumpService exports all posts and comments of the journal to a dump and imports them back,
+// e.g. to move data between storages or environments. IDs and timestamps are kept.
+type DumpService struct {
+	postRepo    internal.PostRepository
+	commentRepo internal.CommentRepository
+	log         *logger.Logger
+}
+
+// NewDumpService creates a new DumpService.
+func NewDumpService(postRepo internal.PostRepository, commentRepo internal.CommentRepository, log *logger.Logger) *DumpService {
+	return &DumpService{
+		postRepo:    postRepo,
+		commentRepo: commentRepo,
+		log:         log.With("layer", "service"),
+	}
+}
+
+// Export writes all posts and comments to w. Data may change during the export, so comments
+// of posts created after their page was read are skipped to keep the dump consistent.
+func (s *DumpService) Export(ctx context.Context, w io.Writer) (*entity.DumpStats, error) {
+	ctx, span := tracer.Start(ctx, "DumpService.Export")
+	defer span.End()
+
+	s.log.DebugContext(
+		ctx,
+		"Export",
+	)
+
+	buf := bufio.NewWriter(w)
+	encoder := json.NewEncoder(buf)
+	stats := &entity.DumpStats{}
+
+	err := encoder.Encode(&dumpRecord{Type: dumpRecordHeader, Header: &dumpHeader{
+		Format:     dumpFormat,
+		Version:    dumpVersion,
+		ExportedAt: int(time.Now().Unix()),
+	}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write header: %w", err)
+	}
+
+	exported := make(map[int]bool)
+
+	for afterID := 0; ; {
+		posts, err := s.postRepo.ListPosts(ctx, afterID, dumpPageSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list posts: %w", err)
+		}
+		if len(posts) == 0 {
+			break
+		}
+
+		for i := range posts {
+			post, err := s.exportPost(ctx, &posts[i])
+			if err != nil {
+				return nil, err
+			}
+
+			err = encoder.Encode(&dumpRecord{Type: dumpRecordPost, Post: post})
+			if err != nil {
+				return nil, fmt.Errorf("failed to write post %d: %w", post.ID, err)
+			}
+
+			exported[post.ID] = true
+			stats.Posts++
+		}
+
+		afterID = posts[len(posts)-1].ID
+	}
+
+	for afterID := 0; ; {
+		comments, err := s.commentRepo.ListComments(ctx, afterID, dumpPageSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list comments: %w", err)
+		}
+		if len(comments) == 0 {
+			break
+		}
+
+		for _, comment := range comments {
+			if !exported[comment.PostID] {
+				continue
+			}
+
+			err = encoder.Encode(&dumpRecord{Type: dumpRecordComment, Comment: commentToDump(&comment)})
+			if err != nil {
+				return nil, fmt.Errorf("failed to write comment %d: %w", comment.ID, err)
+			}
+
+			stats.Comments++
+		}
+
+		afterID = comments[len(comments)-1].ID
+	}
+
+	err = buf.Flush()
+	if err != nil {
+		return nil, fmt.Errorf("failed to write dump: %w", err)
+	}
+
+	return stats, nil
+}
+
+// exportPost adds revisions and slugs to the post.
+func (s *DumpService) exportPost(ctx context.Context, post *entity.Post) (*dumpPost, error) {
+	revisions, err := s.postRepo.GetPostRevisions(ctx, post.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get revisions of post %d: %w", post.ID, err)
+	}
+
+	slugs, err := s.postRepo.GetPostSlugs(ctx, post.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get slugs of post %d: %w", post.ID, err)
+	}
+
+	dumped := &dumpPost{
+		ID:            post.ID,
+		Title:         post.Title,
+		Slug:          post.Slug,
+		Content:       post.Content,
+		ContentFormat: string(post.ContentFormat),
+		Status:        string(post.Status),
+		AuthorID:      post.AuthorID,
+		Commentable:   post.Commentable,
+		PublishedAt:   post.PublishedAt,
+		PublishAt:     post.PublishAt,
+		Revision:      post.Revision,
+		Revisions:     make([]dumpRevision, 0, len(revisions)),
+		Slugs:         slugs,
+	}
+
+	for _, revision := range revisions {
+		dumped.Revisions = append(dumped.Revisions, dumpRevision{
+			Version:   revision.Version,
+			Title:     revision.Title,
+			Content:   revision.Content,
+			EditorID:  revision.EditorID,
+			CreatedAt: revision.CreatedAt,
+		})
+	}
+
+	return dumped, nil
+}
+
+func commentToDump(comment *entity.Comment) *dumpComment {
+	dumped := &dumpComment{
+		ID:            comment.ID,
+		PostID:        comment.PostID,
+		Content:       comment.Content,
+		ContentFormat: string(comment.ContentFormat),
+		AuthorID:      comment.AuthorID,
+		PublishedAt:   comment.PublishedAt,
+	}
+
+	// Root comments have ParentCommentID set to -1.
+	if comment.ParentCommentID > 0 {
+		parentID := comment.ParentCommentID
+		dumped.ParentID = &parentID
+	}
+
+	return dumped
+}
+
+// dumpValidator checks that records of a dump are valid and refer only to records above them.
+type dumpValidator struct {
+	posts    map[int]bool
+	comments map[int]int // Post IDs keyed by comment IDs.
+	slugs    map[string]bool
+}
+
+// Import reads a dump from r and saves its posts and comments with their IDs. Every record is validated
+// before it is saved, and the first invalid record stops the import. Records above it stay imported,
+// so dumps should be checked with dryRun first, which validates the whole dump without saving anything.
+// The storage must be empty, so IDs of the dump are free.
+func (s *DumpService) Import(ctx context.Context, r io.Reader, dryRun bool) (*entity.DumpStats, error) {
+	ctx, span := tracer.Start(ctx, "DumpService.Import")
+	defer span.End()
+
+	s.log.DebugContext(
+		ctx,
+		"Import",
+		"dryRun", dryRun,
+	)
+
+	if !dryRun {
+		err := s.checkEmpty(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	reader := bufio.NewReader(r)
+	v := &dumpValidator{
+		posts:    make(map[int]bool),
+		comments: make(map[int]int),
+		slugs:    make(map[string]bool),
+	}
+	stats := &entity.DumpStats{}
+
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) && len(data) == 0 {
+			if line == 1 {
+				return nil, entity.InvalidArgumentError("dump is empty")
+			}
+			return stats, nil
+		} else if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to read dump: %w", err)
+		}
+
+		var record dumpRecord
+		err = json.Unmarshal(data, &record)
+		if err != nil {
+			return nil, entity.InvalidArgumentError("line %d: invalid JSON: %s", line, err.Error())
+		}
+
+		err = s.importRecord(ctx, v, &record, line == 1, dryRun, stats)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+}
+
+// checkEmpty returns an error if the storage has posts or comments.
+func (s *DumpService) checkEmpty(ctx context.Context) error {
+	posts, err := s.postRepo.ListPosts(ctx, 0, 1)
+	if err != nil {
+		return fmt.Errorf("failed to list posts: %w", err)
+	}
+
+	comments, err := s.commentRepo.ListComments(ctx, 0, 1)
+	if err != nil {
+		return fmt.Errorf("failed to list comments: %w", err)
+	}
+
+	if len(posts) > 0 || len(comments) > 0 {
+		return entity.InvalidArgumentError("storage isn't empty")
+	}
+
+	return nil
+}
+
+// importRecord validates the record and saves it unless dryRun is set.
+func (s *DumpService) importRecord(ctx context.Context, v *dumpValidator, record *dumpRecord, first bool, dryRun bool,
+	stats *entity.DumpStats) error {
+	if first != (record.Type == dumpRecordHeader) {
+		return entity.InvalidArgumentError("dump must start with a single header")
+	}
+
+	switch {
+	case record.Type == dumpRecordHeader && record.Header != nil:
+		if record.Header.Format != dumpFormat {
+			return entity.InvalidArgumentError("unknown format %q", record.Header.Format)
+		}
+		if record.Header.Version != dumpVersion {
+			return entity.InvalidArgumentError("unsupported version %d, expected %d", record.Header.Version, dumpVersion)
+		}
+		return nil
+	case record.Type == dumpRecordPost && record.Post != nil:
+		post, revisions, err := v.post(record.Post)
+		if err != nil {
+			return fmt.Errorf("post %d: %w", record.Post.ID, err)
+		}
+
+		if !dryRun {
+			err = s.postRepo.ImportPost(ctx, post, revisions, record.Post.Slugs)
+			if err != nil {
+				return fmt.Errorf("failed to import post %d: %w", post.ID, err)
+			}
+		}
+
+		stats.Posts++
+		return nil
+	case record.Type == dumpRecordComment && record.Comment != nil:
+		comment, err := v.comment(record.Comment)
+		if err != nil {
+			return fmt.Errorf("comment %d: %w", record.Comment.ID, err)
+		}
+
+		if !dryRun {
+			err = s.commentRepo.ImportComment(ctx, comment)
+			if err != nil {
+				return fmt.Errorf("failed to import comment %d: %w", comment.ID, err)
+			}
+		}
+
+		stats.Comments++
+		return nil
+	default:
+		return entity.InvalidArgumentError("invalid record of type %q", record.Type)
+	}
+}
+
+// post validates a post of a dump and converts it to an entity with revisions.
+func (v *dumpValidator) post(dumped *dumpPost) (*entity.Post, []entity.PostRevision, error) {
+	if dumped.ID <= 0 {
+		return nil, nil, entity.InvalidArgumentError("ID must be positive")
+	}
+	if v.posts[dumped.ID] {
+		return nil, nil, entity.InvalidArgumentError("duplicate ID")
+	}
+
+	post := &entity.Post{
+		ID:            dumped.ID,
+		Title:         dumped.Title,
+		Slug:          dumped.Slug,
+		Content:       dumped.Content,
+		ContentFormat: entity.ContentFormat(dumped.ContentFormat),
+		PublishedAt:   dumped.PublishedAt,
+		AuthorID:      dumped.AuthorID,
+		Commentable:   dumped.Commentable,
+		Status:        entity.PostStatus(dumped.Status),
+		PublishAt:     dumped.PublishAt,
+		Revision:      dumped.Revision,
+	}
+
+	switch post.Status {
+	case entity.PostStatusPublished, entity.PostStatusScheduled, entity.PostStatusDraft:
+	default:
+		return nil, nil, entity.InvalidArgumentError("unknown status %q", post.Status)
+	}
+
+	if !validContentFormat(post.ContentFormat) {
+		return nil, nil, entity.InvalidArgumentError("unknown content format %q", post.ContentFormat)
+	}
+
+	// Versions go in order from 1 without gaps, and the last one is the current title and content.
+	if post.Revision < 1 {
+		return nil, nil, entity.InvalidArgumentError("revision must be positive")
+	}
+	if post.Revision != len(dumped.Revisions) {
+		return nil, nil, entity.InvalidArgumentError("revision is %d, but there are %d revisions", post.Revision, len(dumped.Revisions))
+	}
+
+	revisions := make([]entity.PostRevision, 0, len(dumped.Revisions))
+	for i, revision := range dumped.Revisions {
+		if revision.Version != i+1 {
+			return nil, nil, entity.InvalidArgumentError("revision %d has version %d", i+1, revision.Version)
+		}
+
+		revisions = append(revisions, entity.PostRevision{
+			PostID:    post.ID,
+			Version:   revision.Version,
+			Title:     revision.Title,
+			Content:   revision.Content,
+			EditorID:  revision.EditorID,
+			CreatedAt: revision.CreatedAt,
+		})
+	}
+
+	last := revisions[len(revisions)-1]
+	if last.Title != post.Title || last.Content != post.Content {
+		return nil, nil, entity.InvalidArgumentError("the last revision doesn't match the title and the content")
+	}
+
+	current := false
+	for _, s := range dumped.Slugs {
+		if s == "" || v.slugs[s] {
+			return nil, nil, entity.InvalidArgumentError("slug %q is empty or taken", s)
+		}
+		current = current || s == post.Slug
+	}
+	if !current {
+		return nil, nil, entity.InvalidArgumentError("current slug %q isn't one of the slugs", post.Slug)
+	}
+
+	v.posts[post.ID] = true
+	for _, s := range dumped.Slugs {
+		v.slugs[s] = true
+	}
+
+	return post, revisions, nil
+}
+
+// comment validates a comment of a dump and converts it to an entity. The post and the parent comment
+// must be above the comment.
+func (v *dumpValidator) comment(dumped *dumpComment) (*entity.Comment, error) {
+	if dumped.ID <= 0 {
+		return nil, entity.InvalidArgumentError("ID must be positive")
+	}
+	if _, ok := v.comments[dumped.ID]; ok {
+		return nil, entity.InvalidArgumentError("duplicate ID")
+	}
+
+	if !v.posts[dumped.PostID] {
+		return nil, entity.InvalidArgumentError("post %d isn't in the dump above the comment", dumped.PostID)
+	}
+
+	comment := &entity.Comment{
+		ID:              dumped.ID,
+		Content:         dumped.Content,
+		ContentFormat:   entity.ContentFormat(dumped.ContentFormat),
+		AuthorID:        dumped.AuthorID,
+		PostID:          dumped.PostID,
+		PublishedAt:     dumped.PublishedAt,
+		ParentCommentID: -1,
+	}
+
+	if !validContentFormat(comment.ContentFormat) {
+		return nil, entity.InvalidArgumentError("unknown content format %q", comment.ContentFormat)
+	}
+
+	if dumped.ParentID != nil {
+		postID, ok := v.comments[*dumped.ParentID]
+		if !ok {
+			return nil, entity.InvalidArgumentError("parent comment %d isn't in the dump above the comment", *dumped.ParentID)
+		}
+		if postID != comment.PostID {
+			return nil, entity.InvalidArgumentError("parent comment %d belongs to another post", *dumped.ParentID)
+		}
+		comment.ParentCommentID = *dumped.ParentID
+	}
+
+	v.comments[comment.ID] = comment.PostID
+
+	return comment, nil
+}
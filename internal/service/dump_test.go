@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/oustrix/ozon_journal/internal/entity"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+)
+
+func newDumpValidator() *dumpValidator {
+	return &dumpValidator{
+		posts:    make(map[int]bool),
+		comments: make(map[int]int),
+		slugs:    make(map[string]bool),
+	}
+}
+
+// validDumpPost returns a valid post with two revisions.
+func validDumpPost(id int, slug string) *dumpPost {
+	return &dumpPost{
+		ID:            id,
+		Title:         "Title",
+		Slug:          slug,
+		Content:       "Content",
+		ContentFormat: "plain",
+		Status:        "published",
+		AuthorID:      1,
+		Commentable:   true,
+		PublishedAt:   1700000000,
+		Revision:      2,
+		Revisions: []dumpRevision{
+			{Version: 1, Title: "Draft", Content: "Draft content", EditorID: 1, CreatedAt: 1600000000},
+			{Version: 2, Title: "Title", Content: "Content", EditorID: 1, CreatedAt: 1700000000},
+		},
+		Slugs: []string{"draft", slug},
+	}
+}
+
+func validDumpComment(id, postID int, parentID *int) *dumpComment {
+	return &dumpComment{
+		ID:            id,
+		PostID:        postID,
+		ParentID:      parentID,
+		Content:       "Comment",
+		ContentFormat: "plain",
+		AuthorID:      2,
+		PublishedAt:   1700000001,
+	}
+}
+
+func TestDumpValidatorPost(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(p *dumpPost)
+		err    string
+	}{
+		{name: "valid", change: func(p *dumpPost) {}},
+		{name: "zero ID", change: func(p *dumpPost) { p.ID = 0 }, err: "ID must be positive"},
+		{name: "duplicate ID", change: func(p *dumpPost) { p.ID = 1 }, err: "duplicate ID"},
+		{name: "unknown status", change: func(p *dumpPost) { p.Status = "hidden" }, err: `unknown status "hidden"`},
+		{name: "unknown content format", change: func(p *dumpPost) { p.ContentFormat = "html" },
+			err: `unknown content format "html"`},
+		{name: "zero revision", change: func(p *dumpPost) { p.Revision = 0 }, err: "revision must be positive"},
+		{name: "missing revision", change: func(p *dumpPost) { p.Revision = 3 }, err: "revision is 3, but there are 2 revisions"},
+		{name: "gap in versions", change: func(p *dumpPost) { p.Revisions[1].Version = 3 },
+			err: "revision 2 has version 3"},
+		{name: "stale last revision", change: func(p *dumpPost) { p.Title = "Other" },
+			err: "the last revision doesn't match the title and the content"},
+		{name: "taken slug", change: func(p *dumpPost) { p.Slugs = append(p.Slugs, "first") }, err: `slug "first" is empty or taken`},
+		{name: "empty slug", change: func(p *dumpPost) { p.Slugs = append(p.Slugs, "") }, err: `slug "" is empty or taken`},
+		{name: "current slug missing", change: func(p *dumpPost) { p.Slugs = []string{"second-draft"} },
+			err: `current slug "second" isn't one of the slugs`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newDumpValidator()
+			_, _, err := v.post(validDumpPost(1, "first"))
+			if err != nil {
+				t.Fatalf("post 1: %v", err)
+			}
+
+			dumped := validDumpPost(2, "second")
+			dumped.Slugs[0] = "second-draft"
+			tt.change(dumped)
+
+			post, revisions, err := v.post(dumped)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("error = %v, want %q", err, tt.err)
+				}
+				if post != nil || revisions != nil {
+					t.Fatalf("invalid post is returned")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if post.ID != 2 || post.Slug != "second" || post.Revision != 2 || len(revisions) != 2 {
+				t.Fatalf("post = %+v, revisions = %+v", post, revisions)
+			}
+			if !v.posts[2] || !v.slugs["second"] || !v.slugs["second-draft"] {
+				t.Fatalf("post isn't recorded by the validator")
+			}
+		})
+	}
+}
+
+func TestDumpValidatorComment(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+
+	tests := []struct {
+		name    string
+		comment *dumpComment
+		parent  int
+		err     string
+	}{
+		{name: "comment to post", comment: validDumpComment(3, 1, nil), parent: -1},
+		{name: "reply", comment: validDumpComment(3, 1, intPtr(1)), parent: 1},
+		{name: "zero ID", comment: validDumpComment(0, 1, nil), err: "ID must be positive"},
+		{name: "duplicate ID", comment: validDumpComment(2, 1, nil), err: "duplicate ID"},
+		{name: "dangling post", comment: validDumpComment(3, 5, nil), err: "post 5 isn't in the dump above the comment"},
+		{name: "dangling parent", comment: validDumpComment(3, 1, intPtr(7)),
+			err: "parent comment 7 isn't in the dump above the comment"},
+		{name: "parent of another post", comment: validDumpComment(3, 1, intPtr(2)),
+			err: "parent comment 2 belongs to another post"},
+		{name: "unknown content format", comment: func() *dumpComment {
+			c := validDumpComment(3, 1, nil)
+			c.ContentFormat = "html"
+			return c
+		}(), err: `unknown content format "html"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newDumpValidator()
+			for _, p := range []*dumpPost{validDumpPost(1, "first"), validDumpPost(2, "second")} {
+				p.Slugs = []string{p.Slug}
+				_, _, err := v.post(p)
+				if err != nil {
+					t.Fatalf("post %d: %v", p.ID, err)
+				}
+			}
+			for _, c := range []*dumpComment{validDumpComment(1, 1, nil), validDumpComment(2, 2, nil)} {
+				_, err := v.comment(c)
+				if err != nil {
+					t.Fatalf("comment %d: %v", c.ID, err)
+				}
+			}
+
+			comment, err := v.comment(tt.comment)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if comment.ID != 3 || comment.PostID != 1 || comment.ParentCommentID != tt.parent {
+				t.Fatalf("comment = %+v", comment)
+			}
+			if v.comments[3] != 1 {
+				t.Fatalf("comment isn't recorded by the validator")
+			}
+		})
+	}
+}
+
+func TestDumpServiceImportDryRun(t *testing.T) {
+	const (
+		header  = `{"type":"header","header":{"format":"ozon_journal","version":1,"exported_at":1700000000}}`
+		post    = `{"type":"post","post":{"id":1,"title":"T","slug":"t","content":"C","content_format":"plain","status":"published","author_id":1,"revision":1,"revisions":[{"version":1,"title":"T","content":"C"}],"slugs":["t"]}}`
+		comment = `{"type":"comment","comment":{"id":1,"post_id":1,"parent_id":null,"content":"C","content_format":"plain"}}`
+		reply   = `{"type":"comment","comment":{"id":2,"post_id":1,"parent_id":1,"content":"C","content_format":"plain"}}`
+	)
+
+	tests := []struct {
+		name     string
+		dump     []string
+		posts    int
+		comments int
+		err      string
+	}{
+		{name: "valid", dump: []string{header, post, comment, reply}, posts: 1, comments: 2},
+		{name: "header only", dump: []string{header}},
+		{name: "empty", dump: nil, err: "dump is empty"},
+		{name: "invalid JSON", dump: []string{header, `{"type":"post",`}, err: "line 2: invalid JSON"},
+		{name: "missing header", dump: []string{post}, err: "line 1: dump must start with a single header"},
+		{name: "second header", dump: []string{header, header}, err: "line 2: dump must start with a single header"},
+		{name: "unknown format", dump: []string{`{"type":"header","header":{"format":"other","version":1}}`},
+			err: `line 1: unknown format "other"`},
+		{name: "unsupported version", dump: []string{`{"type":"header","header":{"format":"ozon_journal","version":2}}`},
+			err: "line 1: unsupported version 2, expected 1"},
+		{name: "unknown type", dump: []string{header, `{"type":"user"}`}, err: `line 2: invalid record of type "user"`},
+		{name: "type without record", dump: []string{header, `{"type":"post"}`}, err: `line 2: invalid record of type "post"`},
+		{name: "duplicate post", dump: []string{header, post, post}, err: "line 3: post 1: duplicate ID"},
+		{name: "duplicate comment", dump: []string{header, post, comment, comment}, err: "line 4: comment 1: duplicate ID"},
+		{name: "comment above its post", dump: []string{header, comment, post},
+			err: "line 2: comment 1: post 1 isn't in the dump above the comment"},
+		{name: "reply above its parent", dump: []string{header, post, reply, comment},
+			err: "line 3: comment 2: parent comment 1 isn't in the dump above the comment"},
+	}
+
+	// Repositories aren't used by dry runs.
+	s := NewDumpService(nil, nil, logger.New("error", logger.Output(io.Discard)))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dump := strings.Join(tt.dump, "\n")
+			if dump != "" {
+				dump += "\n"
+			}
+
+			stats, err := s.Import(context.Background(), strings.NewReader(dump), true)
+			if tt.err != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want %q", err, tt.err)
+				}
+				// Errors of dumps are caused by the request.
+				if !errors.Is(err, entity.ErrInvalidArgument) {
+					t.Errorf("error = %v, want an invalid argument", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if stats.Posts != tt.posts || stats.Comments != tt.comments {
+				t.Fatalf("stats = %+v, want %d posts and %d comments", stats, tt.posts, tt.comments)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrShuttingDown is returned by subscribe methods when subscription managers are stopped.
+var ErrShuttingDown = errors.New("server is shutting down")
+
+// managerState lets readiness checks reach the goroutine of a subscription manager. Managers answer pings
+// between handling events, so a manager that has returned or is stuck fails the check.
+// Managers run until their contexts are done.
+type managerState struct {
+	pings chan struct{}
+	done  chan struct{} // Closed when the goroutine returns, so senders to the manager don't block forever.
+}
+
+func newManagerState() managerState {
+	return managerState{pings: make(chan struct{}), done: make(chan struct{})}
+}
+
+// stopped marks the manager stopped. Subscriptions must be closed before, so subscribers learn about it.
+func (s *managerState) stopped() {
+	close(s.done)
+}
+
+// check returns an error if the goroutine of the manager has returned or doesn't answer a ping
+// until the context is done.
+func (s *managerState) check(ctx context.Context, name string) error {
+	select {
+	case s.pings <- struct{}{}:
+		return nil
+	case <-s.done:
+		return fmt.Errorf("%s subscription manager isn't running", name)
+	case <-ctx.Done():
+		return fmt.Errorf("%s subscription manager doesn't respond: %w", name, ctx.Err())
+	}
+}
+
+// CheckSubscriptions returns an error if the manager of subscriptions to published posts isn't running.
+func (s *PostService) CheckSubscriptions(ctx context.Context) error {
+	return s.sub.check(ctx, "posts")
+}
+
+// CheckSubscriptions returns an error if the manager of comment subscriptions or the presence tracker
+// isn't running.
+func (s *CommentService) CheckSubscriptions(ctx context.Context) error {
+	return errors.Join(s.sub.check(ctx, "comments"), s.presence.check(ctx, "presence"))
+}
+
+// CheckSubscriptions returns an error if the manager of subscriptions to reaction counts isn't running.
+func (s *ReactionService) CheckSubscriptions(ctx context.Context) error {
+	return s.sub.check(ctx, "reactions")
+}
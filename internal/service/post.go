@@ -2,85 +2,644 @@ package service
 
 import (
 	"context"
-	"fmt"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/oustrix/ozon_journal/config"
 	"github.com/oustrix/ozon_journal/internal"
 	"github.com/oustrix/ozon_journal/internal/entity"
 	"github.com/oustrix/ozon_journal/pkg/logger"
+	"github.com/oustrix/ozon_journal/pkg/slug"
+	"github.com/oustrix/ozon_journal/pkg/textdiff"
 )
 
 // PostService is a service that provides methods to work with posts.
 type PostService struct {
-	repo internal.PostRepository
-	cfg  *config.Post
-	log  *logger.Logger
+	repo           internal.PostRepository
+	attachmentRepo internal.AttachmentRepository
+	blobs          internal.BlobStore
+	cfg            *config.Post
+	attachmentCfg  *config.Attachment
+	contentTypes   map[string]bool
+	moderators     map[int]bool
+	sub            *postSubscriptionManager
+	log            *logger.Logger
 }
 
-// NewPostService creates a new PostService.
-func NewPostService(repo internal.PostRepository, cfg *config.Post, log *logger.Logger) *PostService {
-	return &PostService{repo: repo, cfg: cfg, log: log}
+type postSubscription struct {
+	id uuid.UUID
+	ch chan *entity.Post
 }
 
-// GetPosts returns a list of posts.
-func (s *PostService) GetPosts(ctx context.Context, page int, amount int) (*[]entity.Post, error) {
-	// Check if page and wasn't passed and set them to default values.
-	var pageNumber, pageAmount uint
+type postSubscriptionManager struct {
+	subscribers map[uuid.UUID]*postSubscription
+	register    chan *postSubscription
+	unregister  chan uuid.UUID
+	posts       chan *entity.Post
+	subscriptionCounters
+	managerState
+}
 
-	if page < 0 {
-		pageNumber = s.cfg.DefaultPage
-	} else {
-		// We can safely cast page to uint because we already checked if it's less than 0.
-		pageNumber = uint(page)
+// NewPostService creates a new PostService. Subscriptions are served until the context is done.
+func NewPostService(ctx context.Context, repo internal.PostRepository, attachmentRepo internal.AttachmentRepository, blobs internal.BlobStore,
+	cfg *config.Post, attachmentCfg *config.Attachment, log *logger.Logger) *PostService {
+	moderators := make(map[int]bool, len(cfg.ModeratorIDs))
+	for _, id := range cfg.ModeratorIDs {
+		moderators[id] = true
 	}
 
-	if amount < 0 {
-		pageAmount = s.cfg.DefaultAmount
-	} else {
-		// We can safely cast amount to uint because we already checked if it's less than 0.
-		pageAmount = uint(amount)
+	contentTypes := make(map[string]bool, len(attachmentCfg.ContentTypes))
+	for _, contentType := range attachmentCfg.ContentTypes {
+		contentTypes[contentType] = true
 	}
 
-	s.log.Debug(
+	return &PostService{
+		repo:           repo,
+		attachmentRepo: attachmentRepo,
+		blobs:          blobs,
+		cfg:            cfg,
+		attachmentCfg:  attachmentCfg,
+		contentTypes:   contentTypes,
+		moderators:     moderators,
+		sub:            newPostSubscriptionManager(ctx),
+		log:            log.With("layer", "service"),
+	}
+}
+
+func newPostSubscriptionManager(ctx context.Context) *postSubscriptionManager {
+	sm := &postSubscriptionManager{
+		subscribers:  make(map[uuid.UUID]*postSubscription),
+		register:     make(chan *postSubscription),
+		unregister:   make(chan uuid.UUID),
+		posts:        make(chan *entity.Post),
+		managerState: newManagerState(),
+	}
+
+	go func() {
+		defer sm.stopped()
+		defer sm.closeSubscriptions()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			// Answer a readiness check.
+			case <-sm.pings:
+			// Register a new subscriber.
+			case sub := <-sm.register:
+				sm.subscribers[sub.id] = sub
+				sm.active.Store(int64(len(sm.subscribers)))
+			// Unregister a subscriber and close its channel.
+			case id := <-sm.unregister:
+				if sub, ok := sm.subscribers[id]; ok {
+					delete(sm.subscribers, id)
+					sm.active.Store(int64(len(sm.subscribers)))
+					close(sub.ch)
+				}
+			// Send a published post to all subscribers without waiting for them.
+			// Subscribers that don't keep up are disconnected, so they don't stall publishing of posts.
+			case post := <-sm.posts:
+				for id, sub := range sm.subscribers {
+					select {
+					case sub.ch <- post:
+					default:
+						delete(sm.subscribers, id)
+						close(sub.ch)
+					}
+				}
+				sm.active.Store(int64(len(sm.subscribers)))
+				sm.queued.Add(-1)
+			}
+		}
+	}()
+
+	return sm
+}
+
+// publish sends a published post to the manager, blocking until the manager takes it.
+// Posts published after the manager stops are dropped, there are no subscribers to send them to.
+func (sm *postSubscriptionManager) publish(post *entity.Post) {
+	sm.queued.Add(1)
+	select {
+	case sm.posts <- post:
+	case <-sm.done:
+		sm.queued.Add(-1)
+	}
+}
+
+// closeSubscriptions closes channels of all subscribers, so their subscriptions complete.
+func (sm *postSubscriptionManager) closeSubscriptions() {
+	for id, sub := range sm.subscribers {
+		delete(sm.subscribers, id)
+		close(sub.ch)
+	}
+	sm.active.Store(0)
+}
+
+// GetPosts returns a list of published posts.
+func (s *PostService) GetPosts(ctx context.Context, page int, amount int) (*[]entity.Post, error) {
+	ctx, span := tracer.Start(ctx, "PostService.GetPosts")
+	defer span.End()
+
+	pageNumber, pageAmount, err := s.pagination(page, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.DebugContext(
+		ctx,
 		"GetPosts",
 		"pageNumber", pageNumber,
 		"pageAmount", pageAmount,
-		"requestID", ctx.Value("requestID"),
 	)
 
 	return s.repo.GetPosts(ctx, pageNumber, pageAmount)
 }
 
-// GetPostByID returns a post by its ID.
-func (s *PostService) GetPostByID(ctx context.Context, id int) (*entity.Post, error) {
-	s.log.Debug(
+// GetUnpublishedPosts returns drafts and scheduled posts of an author.
+func (s *PostService) GetUnpublishedPosts(ctx context.Context, authorID int, page int, amount int) (*[]entity.Post, error) {
+	ctx, span := tracer.Start(ctx, "PostService.GetUnpublishedPosts")
+	defer span.End()
+
+	pageNumber, pageAmount, err := s.pagination(page, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.DebugContext(
+		ctx,
+		"GetUnpublishedPosts",
+		"authorID", authorID,
+		"pageNumber", pageNumber,
+		"pageAmount", pageAmount,
+	)
+
+	return s.repo.GetUnpublishedPosts(ctx, authorID, pageNumber, pageAmount)
+}
+
+// GetPostsByAuthorID returns published posts of an author.
+func (s *PostService) GetPostsByAuthorID(ctx context.Context, authorID int, page int, amount int) (*[]entity.Post, error) {
+	ctx, span := tracer.Start(ctx, "PostService.GetPostsByAuthorID")
+	defer span.End()
+
+	pageNumber, pageAmount, err := s.pagination(page, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.DebugContext(
+		ctx,
+		"GetPostsByAuthorID",
+		"authorID", authorID,
+		"pageNumber", pageNumber,
+		"pageAmount", pageAmount,
+	)
+
+	return s.repo.GetPostsByAuthorID(ctx, authorID, pageNumber, pageAmount)
+}
+
+// GetPostByID returns a post by its ID. Posts that aren't published yet are returned to their authors only.
+func (s *PostService) GetPostByID(ctx context.Context, id int, viewerID int) (*entity.Post, error) {
+	ctx, span := tracer.Start(ctx, "PostService.GetPostByID")
+	defer span.End()
+
+	s.log.DebugContext(
+		ctx,
 		"GetPostByID",
 		"id", id,
-		"requestID", ctx.Value("requestID"),
+		"viewerID", viewerID,
 	)
-	return s.repo.GetPostByID(ctx, id)
+
+	post, err := s.repo.GetPostByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Don't reveal that an unpublished post exists.
+	if post.Status != entity.PostStatusPublished && post.AuthorID != viewerID {
+		return nil, entity.NotFoundError("post with ID %d not found", id)
+	}
+
+	return post, nil
+}
+
+// GetPostBySlug returns a post by its current or earlier slug with the same visibility rules as GetPostByID.
+// The returned post has the current slug, so clients can redirect from an old one.
+func (s *PostService) GetPostBySlug(ctx context.Context, slug string, viewerID int) (*entity.Post, error) {
+	ctx, span := tracer.Start(ctx, "PostService.GetPostBySlug")
+	defer span.End()
+
+	s.log.DebugContext(
+		ctx,
+		"GetPostBySlug",
+		"slug", slug,
+		"viewerID", viewerID,
+	)
+
+	post, err := s.repo.GetPostBySlug(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	// Don't reveal that an unpublished post exists.
+	if post.Status != entity.PostStatusPublished && post.AuthorID != viewerID {
+		return nil, entity.NotFoundError("post with slug %q not found", slug)
+	}
+
+	return post, nil
 }
 
-// CreatePost creates a new post.
+// CreatePost creates a new post with a slug made of its title. Published posts are sent to subscribers right away.
 func (s *PostService) CreatePost(ctx context.Context, post *entity.Post) (*entity.Post, error) {
-	// Check for empty fields and length of content and title.
-	if []rune(post.Content) == nil {
-		return nil, fmt.Errorf("content is empty")
-	} else if uint(len([]rune(post.Content))) > s.cfg.ContentMaxCharacters { // Sefeley cast content to uint, it checked for nil.
-		return nil, fmt.Errorf("content is too long")
-	} else if []rune(post.Title) == nil {
-		return nil, fmt.Errorf("title is empty")
-	} else if uint(len([]rune(post.Title))) > s.cfg.TitleMaxCharacters { // Safely cast title to uint, it checked for nil.
-		return nil, fmt.Errorf("title is too long")
+	ctx, span := tracer.Start(ctx, "PostService.CreatePost")
+	defer span.End()
+
+	err := s.validate(post.Title, post.Content)
+	if err != nil {
+		return nil, err
 	}
 
-	post.PublishedAt = int(time.Now().Unix())
+	if !validContentFormat(post.ContentFormat) {
+		return nil, entity.InvalidArgumentError("unknown content format %q", post.ContentFormat)
+	}
 
-	s.log.Debug(
+	post.Slug = slug.Make(post.Title)
+
+	now := int(time.Now().Unix())
+
+	// Set publication times according to the status.
+	switch post.Status {
+	case entity.PostStatusPublished:
+		post.PublishedAt = now
+		post.PublishAt = 0
+	case entity.PostStatusScheduled:
+		if post.PublishAt <= now {
+			return nil, entity.InvalidArgumentError("publication time must be in the future")
+		}
+		post.PublishedAt = 0
+	case entity.PostStatusDraft:
+		post.PublishedAt = 0
+		post.PublishAt = 0
+	default:
+		return nil, entity.InvalidArgumentError("unknown post status %q", post.Status)
+	}
+
+	s.log.DebugContext(
+		ctx,
 		"CreatePost",
-		"requestID", ctx.Value("requestID"),
+		"status", post.Status,
+	)
+
+	post, err = s.repo.CreatePost(ctx, post)
+	if err != nil {
+		return nil, err
+	}
+
+	if post.Status == entity.PostStatusPublished {
+		s.sub.publish(post)
+	}
+
+	return post, nil
+}
+
+// PublishPost publishes a draft or a scheduled post of the author at publishAt.
+// If publishAt is not in the future, the post is published right away.
+func (s *PostService) PublishPost(ctx context.Context, id int, authorID int, publishAt int) (*entity.Post, error) {
+	ctx, span := tracer.Start(ctx, "PostService.PublishPost")
+	defer span.End()
+
+	s.log.DebugContext(
+		ctx,
+		"PublishPost",
+		"id", id,
+		"publishAt", publishAt,
+	)
+
+	post, err := s.repo.GetPostByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if post.AuthorID != authorID {
+		// Don't reveal that an unpublished post exists.
+		if post.Status != entity.PostStatusPublished {
+			return nil, entity.NotFoundError("post with ID %d not found", id)
+		}
+
+		return nil, entity.PermissionDeniedError("post with ID %d can be published by its author only", id)
+	} else if post.Status == entity.PostStatusPublished {
+		return nil, entity.InvalidArgumentError("post with ID %d is already published", id)
+	}
+
+	now := int(time.Now().Unix())
+	if publishAt > now {
+		post.Status = entity.PostStatusScheduled
+		post.PublishAt = publishAt
+	} else {
+		post.Status = entity.PostStatusPublished
+		post.PublishedAt = now
+		post.PublishAt = 0
+	}
+
+	post, err = s.repo.UpdatePostStatus(ctx, post)
+	if err != nil {
+		return nil, err
+	}
+
+	if post.Status == entity.PostStatusPublished {
+		s.sub.publish(post)
+	}
+
+	return post, nil
+}
+
+// PublishDuePosts publishes scheduled posts whose time has come and sends them to subscribers.
+func (s *PostService) PublishDuePosts(ctx context.Context) ([]entity.Post, error) {
+	ctx, span := tracer.Start(ctx, "PostService.PublishDuePosts")
+	defer span.End()
+
+	posts, err := s.repo.PublishDuePosts(ctx, int(time.Now().Unix()))
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.DebugContext(
+		ctx,
+		"PublishDuePosts",
+		"amount", len(posts),
+	)
+
+	for i := range posts {
+		s.sub.publish(&posts[i])
+	}
+
+	return posts, nil
+}
+
+// UpdatePost changes the title and the content of a post and saves them as a new revision.
+// Fields passed as nil are left unchanged. Posts can be edited by their authors and moderators.
+func (s *PostService) UpdatePost(ctx context.Context, id int, editorID int, title *string, content *string) (*entity.Post, error) {
+	ctx, span := tracer.Start(ctx, "PostService.UpdatePost")
+	defer span.End()
+
+	s.log.DebugContext(
+		ctx,
+		"UpdatePost",
+		"id", id,
+		"editorID", editorID,
+	)
+
+	post, err := s.editablePost(ctx, id, editorID)
+	if err != nil {
+		return nil, err
+	}
+
+	revision := &entity.PostRevision{
+		PostID:   post.ID,
+		Title:    post.Title,
+		Content:  post.Content,
+		EditorID: editorID,
+	}
+	if title != nil {
+		revision.Title = *title
+	}
+	if content != nil {
+		revision.Content = *content
+	}
+
+	return s.saveRevision(ctx, post, revision)
+}
+
+// GetPostRevisions returns all revisions of a post, the oldest first.
+func (s *PostService) GetPostRevisions(ctx context.Context, postID int) ([]entity.PostRevision, error) {
+	ctx, span := tracer.Start(ctx, "PostService.GetPostRevisions")
+	defer span.End()
+
+	s.log.DebugContext(
+		ctx,
+		"GetPostRevisions",
+		"postID", postID,
+	)
+
+	return s.repo.GetPostRevisions(ctx, postID)
+}
+
+// DiffPostRevisions returns the difference between two revisions of a post visible to the viewer.
+func (s *PostService) DiffPostRevisions(ctx context.Context, postID int, from int, to int, mode entity.DiffMode, viewerID int) (*entity.PostRevisionDiff, error) {
+	ctx, span := tracer.Start(ctx, "PostService.DiffPostRevisions")
+	defer span.End()
+
+	s.log.DebugContext(
+		ctx,
+		"DiffPostRevisions",
+		"postID", postID,
+		"from", from,
+		"to", to,
+		"mode", mode,
+	)
+
+	_, err := s.GetPostByID(ctx, postID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	fromRevision, err := s.repo.GetPostRevision(ctx, postID, from)
+	if err != nil {
+		return nil, err
+	}
+
+	toRevision, err := s.repo.GetPostRevision(ctx, postID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var diff func(a, b string) []textdiff.Chunk
+	switch mode {
+	case entity.DiffModeLine:
+		diff = textdiff.Lines
+	case entity.DiffModeWord:
+		diff = textdiff.Words
+	default:
+		return nil, entity.InvalidArgumentError("unknown diff mode %q", mode)
+	}
+
+	return &entity.PostRevisionDiff{
+		PostID:  postID,
+		From:    from,
+		To:      to,
+		Title:   diffChunks(diff(fromRevision.Title, toRevision.Title)),
+		Content: diffChunks(diff(fromRevision.Content, toRevision.Content)),
+	}, nil
+}
+
+// RestorePostRevision makes the title and the content of an earlier revision current again.
+// The history isn't rewritten, the restored revision is saved as a new one.
+func (s *PostService) RestorePostRevision(ctx context.Context, postID int, version int, editorID int) (*entity.Post, error) {
+	ctx, span := tracer.Start(ctx, "PostService.RestorePostRevision")
+	defer span.End()
+
+	s.log.DebugContext(
+		ctx,
+		"RestorePostRevision",
+		"postID", postID,
+		"version", version,
+		"editorID", editorID,
+	)
+
+	post, err := s.editablePost(ctx, postID, editorID)
+	if err != nil {
+		return nil, err
+	}
+
+	restored, err := s.repo.GetPostRevision(ctx, postID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.saveRevision(ctx, post, &entity.PostRevision{
+		PostID:   post.ID,
+		Title:    restored.Title,
+		Content:  restored.Content,
+		EditorID: editorID,
+	})
+}
+
+// editablePost returns a post if the editor is its author or a moderator.
+func (s *PostService) editablePost(ctx context.Context, id int, editorID int) (*entity.Post, error) {
+	post, err := s.repo.GetPostByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if post.AuthorID == editorID || s.moderators[editorID] {
+		return post, nil
+	}
+
+	// Don't reveal that an unpublished post exists.
+	if post.Status != entity.PostStatusPublished {
+		return nil, entity.NotFoundError("post with ID %d not found", id)
+	}
+
+	return nil, entity.PermissionDeniedError("post with ID %d can be edited by its author or moderators only", id)
+}
+
+// saveRevision validates the revision and saves it as the next version of the post.
+// If the title and the content don't change, the post is returned as is.
+// A new title gets the post a new slug unless both titles give the same one.
+func (s *PostService) saveRevision(ctx context.Context, post *entity.Post, revision *entity.PostRevision) (*entity.Post, error) {
+	err := s.validate(revision.Title, revision.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	if revision.Title == post.Title && revision.Content == post.Content {
+		return post, nil
+	}
+
+	revision.CreatedAt = int(time.Now().Unix())
+
+	newSlug := ""
+	if revision.Title != post.Title && slug.Make(revision.Title) != slug.Make(post.Title) {
+		newSlug = slug.Make(revision.Title)
+	}
+
+	return s.repo.UpdatePost(ctx, revision, newSlug)
+}
+
+// validate checks that the title and the content aren't empty and aren't too long.
+func (s *PostService) validate(title string, content string) error {
+	// Check for empty fields and length of content and title.
+	if []rune(content) == nil {
+		return entity.InvalidArgumentError("content is empty")
+	} else if uint(len([]rune(content))) > s.cfg.ContentMaxCharacters { // Sefeley cast content to uint, it checked for nil.
+		return entity.InvalidArgumentError("content is too long")
+	} else if []rune(title) == nil {
+		return entity.InvalidArgumentError("title is empty")
+	} else if uint(len([]rune(title))) > s.cfg.TitleMaxCharacters { // Safely cast title to uint, it checked for nil.
+		return entity.InvalidArgumentError("title is too long")
+	}
+
+	return nil
+}
+
+// diffChunks converts chunks of textdiff to entities.
+func diffChunks(chunks []textdiff.Chunk) []entity.DiffChunk {
+	result := make([]entity.DiffChunk, 0, len(chunks))
+	for _, c := range chunks {
+		result = append(result, entity.DiffChunk{Operation: entity.DiffOperation(c.Operation), Text: c.Text})
+	}
+
+	return result
+}
+
+// SubscribePosts subscribes to published posts. The subscription is closed if the subscriber falls behind
+// by more than subscriptionBufferSize posts.
+func (s *PostService) SubscribePosts(ctx context.Context) (<-chan *entity.Post, uuid.UUID, error) {
+	ctx, span := tracer.Start(ctx, "PostService.SubscribePosts")
+	defer span.End()
+
+	sub := &postSubscription{
+		id: uuid.New(),
+		ch: make(chan *entity.Post, subscriptionBufferSize),
+	}
+
+	s.log.DebugContext(
+		ctx,
+		"SubscribePosts",
+		"subscriptionID", sub.id,
 	)
 
-	return s.repo.CreatePost(ctx, post)
+	select {
+	case s.sub.register <- sub:
+	case <-s.sub.done:
+		return nil, uuid.Nil, ErrShuttingDown
+	}
+
+	return sub.ch, sub.id, nil
+}
+
+// UnsubscribePosts cancels a subscription created by SubscribePosts.
+func (s *PostService) UnsubscribePosts(ctx context.Context, subscriptionID uuid.UUID) {
+	ctx, span := tracer.Start(ctx, "PostService.UnsubscribePosts")
+	defer span.End()
+
+	s.log.DebugContext(
+		ctx,
+		"UnsubscribePosts",
+		"subscriptionID", subscriptionID,
+	)
+
+	select {
+	case s.sub.unregister <- subscriptionID:
+	case <-s.sub.done:
+		// Subscriptions are closed by the stopped manager.
+	}
+}
+
+// pagination returns the page number and the page size, replacing values that weren't passed with defaults.
+func (s *PostService) pagination(page int, amount int) (uint, uint, error) {
+	var pageNumber, pageAmount uint
+
+	if page < 0 {
+		pageNumber = s.cfg.DefaultPage
+	} else {
+		// We can safely cast page to uint because we already checked if it's less than 0.
+		pageNumber = uint(page)
+	}
+
+	// Pages of posts are numbered from 1, so page 0 is the first page rather than a negative offset.
+	pageNumber = max(pageNumber, 1)
+
+	if amount < 0 {
+		pageAmount = s.cfg.DefaultAmount
+	} else {
+		// We can safely cast amount to uint because we already checked if it's less than 0.
+		pageAmount = uint(amount)
+	}
+
+	if pageAmount > s.cfg.MaxAmount {
+		return 0, 0, entity.InvalidArgumentError("amount must not exceed %d", s.cfg.MaxAmount)
+	}
+
+	return pageNumber, pageAmount, nil
 }
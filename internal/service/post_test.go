@@ -0,0 +1,313 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oustrix/ozon_journal/config"
+	"github.com/oustrix/ozon_journal/internal"
+	"github.com/oustrix/ozon_journal/internal/entity"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+)
+
+// postRepoStub keeps posts in a map. Methods the tests don't use aren't implemented.
+type postRepoStub struct {
+	internal.PostRepository
+	mu    sync.Mutex
+	posts map[int]*entity.Post
+}
+
+func newPostRepoStub(posts ...entity.Post) *postRepoStub {
+	r := &postRepoStub{posts: make(map[int]*entity.Post)}
+	for i := range posts {
+		r.posts[posts[i].ID] = &posts[i]
+	}
+
+	return r
+}
+
+func (r *postRepoStub) GetPostByID(_ context.Context, id int) (*entity.Post, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	post, ok := r.posts[id]
+	if !ok {
+		return nil, entity.NotFoundError("post with id %d not found", id)
+	}
+	copied := *post
+
+	return &copied, nil
+}
+
+func (r *postRepoStub) CreatePost(_ context.Context, post *entity.Post) (*entity.Post, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	post.ID = len(r.posts) + 100
+	copied := *post
+	r.posts[post.ID] = &copied
+
+	return post, nil
+}
+
+func (r *postRepoStub) UpdatePostStatus(_ context.Context, post *entity.Post) (*entity.Post, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.posts[post.ID]
+	if !ok {
+		return nil, entity.NotFoundError("post with id %d not found", post.ID)
+	}
+	stored.Status, stored.PublishAt, stored.PublishedAt = post.Status, post.PublishAt, post.PublishedAt
+	copied := *stored
+
+	return &copied, nil
+}
+
+func (r *postRepoStub) PublishDuePosts(_ context.Context, now int) ([]entity.Post, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	published := make([]entity.Post, 0)
+	for _, post := range r.posts {
+		if post.Status == entity.PostStatusScheduled && post.PublishAt <= now {
+			post.Status, post.PublishedAt, post.PublishAt = entity.PostStatusPublished, post.PublishAt, 0
+			published = append(published, *post)
+		}
+	}
+	sort.Slice(published, func(i, j int) bool { return published[i].ID < published[j].ID })
+
+	return published, nil
+}
+
+func newTestPostService(repo *postRepoStub) *PostService {
+	return NewPostService(context.Background(), repo, nil, nil, &config.Post{ContentMaxCharacters: 100, TitleMaxCharacters: 100},
+		&config.Attachment{}, logger.New("error"))
+}
+
+// receivePosts returns IDs of posts sent to the channel until nothing is sent for a while.
+func receivePosts(ch <-chan *entity.Post) []int {
+	ids := make([]int, 0)
+	for {
+		select {
+		case post := <-ch:
+			ids = append(ids, post.ID)
+		case <-time.After(50 * time.Millisecond):
+			return ids
+		}
+	}
+}
+
+func TestPublishPost(t *testing.T) {
+	now := int(time.Now().Unix())
+
+	tests := []struct {
+		name       string
+		id         int
+		authorID   int
+		publishAt  int
+		wantStatus entity.PostStatus
+		wantErr    error
+		wantSent   bool
+	}{
+		{name: "draft", id: 1, authorID: 1, wantStatus: entity.PostStatusPublished, wantSent: true},
+		{name: "scheduled post right away", id: 2, authorID: 1, publishAt: now - 10, wantStatus: entity.PostStatusPublished,
+			wantSent: true},
+		{name: "draft later", id: 1, authorID: 1, publishAt: now + 3600, wantStatus: entity.PostStatusScheduled},
+		{name: "already published", id: 3, authorID: 1, wantErr: entity.ErrInvalidArgument},
+		{name: "draft of another author", id: 1, authorID: 2, wantErr: entity.ErrNotFound},
+		{name: "published post of another author", id: 3, authorID: 2, wantErr: entity.ErrPermissionDenied},
+		{name: "missing post", id: 4, authorID: 1, wantErr: entity.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newTestPostService(newPostRepoStub(
+				entity.Post{ID: 1, AuthorID: 1, Status: entity.PostStatusDraft},
+				entity.Post{ID: 2, AuthorID: 1, Status: entity.PostStatusScheduled, PublishAt: now + 60},
+				entity.Post{ID: 3, AuthorID: 1, Status: entity.PostStatusPublished, PublishedAt: now - 60},
+			))
+
+			ch, id, err := s.SubscribePosts(ctx)
+			if err != nil {
+				t.Fatalf("SubscribePosts() error = %v", err)
+			}
+			defer s.UnsubscribePosts(ctx, id)
+
+			post, err := s.PublishPost(ctx, tt.id, tt.authorID, tt.publishAt)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("PublishPost() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if post.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", post.Status, tt.wantStatus)
+			}
+
+			sent := receivePosts(ch)
+			if tt.wantSent != (len(sent) == 1) {
+				t.Errorf("posts sent to subscribers = %v, want sent %v", sent, tt.wantSent)
+			}
+		})
+	}
+}
+
+func TestPublishDuePosts(t *testing.T) {
+	now := int(time.Now().Unix())
+
+	tests := []struct {
+		name  string
+		posts []entity.Post
+		want  []int
+	}{
+		{name: "nothing scheduled", posts: []entity.Post{{ID: 1, Status: entity.PostStatusDraft}}, want: []int{}},
+		{
+			name: "due and future posts",
+			posts: []entity.Post{
+				{ID: 1, Status: entity.PostStatusScheduled, PublishAt: now - 60},
+				{ID: 2, Status: entity.PostStatusScheduled, PublishAt: now + 60},
+				{ID: 3, Status: entity.PostStatusScheduled, PublishAt: now},
+			},
+			want: []int{1, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := newPostRepoStub(tt.posts...)
+			s := newTestPostService(repo)
+
+			ch, id, err := s.SubscribePosts(ctx)
+			if err != nil {
+				t.Fatalf("SubscribePosts() error = %v", err)
+			}
+			defer s.UnsubscribePosts(ctx, id)
+
+			posts, err := s.PublishDuePosts(ctx)
+			if err != nil {
+				t.Fatalf("PublishDuePosts() error = %v", err)
+			}
+
+			published := make([]int, 0, len(posts))
+			for _, post := range posts {
+				published = append(published, post.ID)
+				if post.Status != entity.PostStatusPublished {
+					t.Errorf("post %d has status %q", post.ID, post.Status)
+				}
+			}
+			if fmt.Sprint(published) != fmt.Sprint(tt.want) {
+				t.Errorf("PublishDuePosts() = %v, want %v", published, tt.want)
+			}
+			if sent := receivePosts(ch); fmt.Sprint(sent) != fmt.Sprint(tt.want) {
+				t.Errorf("posts sent to subscribers = %v, want %v", sent, tt.want)
+			}
+
+			// Published posts aren't published again.
+			posts, err = s.PublishDuePosts(ctx)
+			if err != nil || len(posts) != 0 {
+				t.Errorf("second PublishDuePosts() = %v, %v, want no posts", posts, err)
+			}
+		})
+	}
+}
+
+func TestPostSubscriptionEviction(t *testing.T) {
+	tests := []struct {
+		name       string
+		published  int
+		wantClosed bool
+	}{
+		{name: "subscriber keeps up", published: subscriptionBufferSize},
+		{name: "subscriber falls behind", published: subscriptionBufferSize + 1, wantClosed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newTestPostService(newPostRepoStub())
+
+			slow, _, err := s.SubscribePosts(ctx)
+			if err != nil {
+				t.Fatalf("SubscribePosts() error = %v", err)
+			}
+			fast, fastID, err := s.SubscribePosts(ctx)
+			if err != nil {
+				t.Fatalf("SubscribePosts() error = %v", err)
+			}
+			defer s.UnsubscribePosts(ctx, fastID)
+
+			received := make(chan int)
+			go func() {
+				received <- len(receivePosts(fast))
+			}()
+
+			for i := 0; i < tt.published; i++ {
+				post := &entity.Post{Title: "Title", Content: "Content", ContentFormat: entity.ContentFormatPlain,
+					Status: entity.PostStatusPublished}
+				_, err := s.CreatePost(ctx, post)
+				if err != nil {
+					t.Fatalf("CreatePost() error = %v", err)
+				}
+			}
+
+			if n := <-received; n != tt.published {
+				t.Errorf("the subscriber reading posts received %d posts, want %d", n, tt.published)
+			}
+
+			// The channel of a disconnected subscriber is closed after the posts it has buffered.
+			for i := 0; i < subscriptionBufferSize; i++ {
+				<-slow
+			}
+			select {
+			case _, ok := <-slow:
+				if ok || !tt.wantClosed {
+					t.Errorf("the channel of the slow subscriber is closed: %v, want %v", !ok, tt.wantClosed)
+				}
+			case <-time.After(50 * time.Millisecond):
+				if tt.wantClosed {
+					t.Error("the slow subscriber isn't disconnected")
+				}
+			}
+		})
+	}
+}
+
+func TestPagination(t *testing.T) {
+	tests := []struct {
+		name       string
+		page       int
+		amount     int
+		wantPage   uint
+		wantAmount uint
+		wantErr    error
+	}{
+		{name: "passed values", page: 3, amount: 5, wantPage: 3, wantAmount: 5},
+		{name: "defaults", page: -1, amount: -1, wantPage: 2, wantAmount: 10},
+		{name: "page 0 is the first page", page: 0, amount: 5, wantPage: 1, wantAmount: 5},
+		{name: "largest amount", page: 1, amount: 50, wantPage: 1, wantAmount: 50},
+		{name: "amount over the limit", page: 1, amount: 51, wantErr: entity.ErrInvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &PostService{cfg: &config.Post{DefaultPage: 2, DefaultAmount: 10, MaxAmount: 50}}
+
+			page, amount, err := s.pagination(tt.page, tt.amount)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("pagination() error = %v, want %v", err, tt.wantErr)
+			}
+			if page != tt.wantPage || amount != tt.wantAmount {
+				t.Errorf("pagination() = %d, %d, want %d, %d", page, amount, tt.wantPage, tt.wantAmount)
+			}
+		})
+	}
+}
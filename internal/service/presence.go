@@ -0,0 +1,411 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oustrix/ozon_journal/config"
+	"github.com/oustrix/ozon_journal/internal"
+	"github.com/oustrix/ozon_journal/internal/entity"
+)
+
+// presenceExpireInterval is how often expired viewers and typing users are removed.
+const presenceExpireInterval = time.Second
+
+type presenceSubscription struct {
+	id     uuid.UUID
+	postID int
+	userID int
+	ch     chan *entity.Presence
+	stop   chan struct{} // Closed on unsubscribe to stop refreshing the viewer session.
+}
+
+// postPresence is a presence state of a single post.
+type postPresence struct {
+	viewers map[string]viewerSession // Keyed by session ID.
+	typing  map[int]time.Time        // Keyed by user ID, values are expiration times.
+}
+
+type viewerSession struct {
+	userID    int
+	expiresAt time.Time
+}
+
+// presenceTracker keeps track of post viewers and typing users and sends updates to subscribers.
+// The state is built from presence events, so every application instance gets the same picture
+// when events are delivered between instances.
+type presenceTracker struct {
+	cfg           *config.Presence
+	posts         map[int]*postPresence
+	subscriptions map[uuid.UUID]*presenceSubscription
+	byPost        map[int][]*presenceSubscription
+	register      chan *presenceSubscription
+	unregister    chan uuid.UUID
+	events        <-chan *entity.PresenceEvent
+	subscriptionCounters
+	managerState
+}
+
+func newPresenceTracker(ctx context.Context, repo internal.PresenceRepository, cfg *config.Presence) *presenceTracker {
+	pt := &presenceTracker{
+		cfg:           cfg,
+		posts:         make(map[int]*postPresence),
+		subscriptions: make(map[uuid.UUID]*presenceSubscription),
+		byPost:        make(map[int][]*presenceSubscription),
+		register:      make(chan *presenceSubscription),
+		unregister:    make(chan uuid.UUID),
+		managerState:  newManagerState(),
+	}
+
+	// The repository closes the channel of events when the context is done, which stops the tracker.
+	events := repo.ListenPresence(ctx)
+	pt.events = events
+
+	go func() {
+		defer pt.stopped()
+		defer pt.closeSubscriptions()
+
+		ticker := time.NewTicker(presenceExpireInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			// Answer a readiness check.
+			case <-pt.pings:
+			// Register a new subscriber and send it the current state.
+			case sub := <-pt.register:
+				pt.subscriptions[sub.id] = sub
+				pt.byPost[sub.postID] = append(pt.byPost[sub.postID], sub)
+				pt.active.Store(int64(len(pt.subscriptions)))
+				sendPresence(sub.ch, pt.snapshot(sub.postID))
+			// Unregister a subscriber and close its channel.
+			case id := <-pt.unregister:
+				sub, ok := pt.subscriptions[id]
+				if !ok {
+					break
+				}
+				delete(pt.subscriptions, id)
+
+				subs := pt.byPost[sub.postID]
+				for i, s := range subs {
+					if s.id == id {
+						subs = append(subs[:i], subs[i+1:]...)
+						break
+					}
+				}
+				if len(subs) == 0 {
+					delete(pt.byPost, sub.postID)
+				} else {
+					pt.byPost[sub.postID] = subs
+				}
+
+				pt.active.Store(int64(len(pt.subscriptions)))
+				close(sub.stop)
+				close(sub.ch)
+			// Apply an event and notify subscribers if the state of the post changed.
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				if pt.apply(event, time.Now()) {
+					pt.broadcast(event.PostID)
+				}
+			// Remove expired viewers and typing users.
+			case now := <-ticker.C:
+				for _, postID := range pt.expire(now) {
+					pt.broadcast(postID)
+				}
+			}
+		}
+	}()
+
+	return pt
+}
+
+// closeSubscriptions closes channels of all subscribers, so their subscriptions complete,
+// and stops refreshing their viewer sessions.
+func (pt *presenceTracker) closeSubscriptions() {
+	for id, sub := range pt.subscriptions {
+		delete(pt.subscriptions, id)
+		close(sub.stop)
+		close(sub.ch)
+	}
+	clear(pt.byPost)
+	pt.active.Store(0)
+}
+
+// stats returns the state of the tracker. Presence events are queued in the repository channel,
+// updates are sent to subscribers without blocking.
+func (pt *presenceTracker) stats() SubscriptionStats {
+	stats := pt.subscriptionCounters.stats()
+	stats.Queued = len(pt.events)
+	return stats
+}
+
+// apply applies an event to the state and reports whether the state has visibly changed.
+func (pt *presenceTracker) apply(event *entity.PresenceEvent, now time.Time) bool {
+	post, ok := pt.posts[event.PostID]
+	if !ok {
+		post = &postPresence{
+			viewers: make(map[string]viewerSession),
+			typing:  make(map[int]time.Time),
+		}
+		pt.posts[event.PostID] = post
+	}
+
+	changed := false
+	switch event.Kind {
+	case entity.PresenceViewing:
+		_, known := post.viewers[event.SessionID]
+		changed = !known
+		post.viewers[event.SessionID] = viewerSession{
+			userID:    event.UserID,
+			expiresAt: now.Add(time.Duration(pt.cfg.ViewerTTL) * time.Second),
+		}
+	case entity.PresenceLeft:
+		_, changed = post.viewers[event.SessionID]
+		delete(post.viewers, event.SessionID)
+	case entity.PresenceTyping:
+		_, known := post.typing[event.UserID]
+		changed = !known
+		post.typing[event.UserID] = now.Add(time.Duration(pt.cfg.TypingTTL) * time.Second)
+	}
+
+	if len(post.viewers) == 0 && len(post.typing) == 0 {
+		delete(pt.posts, event.PostID)
+	}
+
+	return changed
+}
+
+// expire removes expired viewers and typing users and returns IDs of changed posts.
+func (pt *presenceTracker) expire(now time.Time) []int {
+	changed := make([]int, 0)
+
+	for postID, post := range pt.posts {
+		postChanged := false
+
+		for sessionID, viewer := range post.viewers {
+			if now.After(viewer.expiresAt) {
+				delete(post.viewers, sessionID)
+				postChanged = true
+			}
+		}
+
+		for userID, expiresAt := range post.typing {
+			if now.After(expiresAt) {
+				delete(post.typing, userID)
+				postChanged = true
+			}
+		}
+
+		if len(post.viewers) == 0 && len(post.typing) == 0 {
+			delete(pt.posts, postID)
+		}
+
+		if postChanged {
+			changed = append(changed, postID)
+		}
+	}
+
+	return changed
+}
+
+// snapshot returns the current presence of a post.
+func (pt *presenceTracker) snapshot(postID int) *entity.Presence {
+	presence := &entity.Presence{
+		PostID:    postID,
+		ViewerIDs: make([]int, 0),
+		TypingIDs: make([]int, 0),
+	}
+
+	post, ok := pt.posts[postID]
+	if !ok {
+		return presence
+	}
+
+	// Anonymous viewers are counted, but their IDs aren't listed.
+	seen := make(map[int]bool)
+	for _, viewer := range post.viewers {
+		presence.Viewers++
+		if viewer.userID != 0 && !seen[viewer.userID] {
+			seen[viewer.userID] = true
+			presence.ViewerIDs = append(presence.ViewerIDs, viewer.userID)
+		}
+	}
+
+	for userID := range post.typing {
+		presence.TypingIDs = append(presence.TypingIDs, userID)
+	}
+
+	sort.Ints(presence.ViewerIDs)
+	sort.Ints(presence.TypingIDs)
+
+	return presence
+}
+
+// broadcast sends the current presence of a post to its subscribers.
+func (pt *presenceTracker) broadcast(postID int) {
+	subs := pt.byPost[postID]
+	if len(subs) == 0 {
+		return
+	}
+
+	presence := pt.snapshot(postID)
+	for _, sub := range subs {
+		sendPresence(sub.ch, presence)
+	}
+}
+
+// sendPresence sends presence to a buffered channel of size 1 without blocking.
+// If the subscriber hasn't read the previous state yet, it is replaced with the new one.
+func sendPresence(ch chan *entity.Presence, presence *entity.Presence) {
+	select {
+	case ch <- presence:
+	default:
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- presence
+	}
+}
+
+// SubscribePresence subscribes to presence of a post and marks the user as its viewer until unsubscribed.
+// Zero userID stands for an anonymous viewer.
+func (s *CommentService) SubscribePresence(ctx context.Context, postID, userID int) (<-chan *entity.Presence, uuid.UUID, error) {
+	ctx, span := tracer.Start(ctx, "CommentService.SubscribePresence")
+	defer span.End()
+
+	sub := &presenceSubscription{
+		id:     uuid.New(),
+		postID: postID,
+		userID: userID,
+		ch:     make(chan *entity.Presence, 1),
+		stop:   make(chan struct{}),
+	}
+
+	s.log.DebugContext(
+		ctx,
+		"SubscribePresence",
+		"postID", postID,
+		"userID", userID,
+		"subscriptionID", sub.id,
+	)
+
+	err := s.checkPost(ctx, postID)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+
+	// Register before publishing the viewer, so the subscriber doesn't miss the state with itself.
+	select {
+	case s.presence.register <- sub:
+	case <-s.presence.done:
+		return nil, uuid.Nil, ErrShuttingDown
+	}
+
+	err = s.publishViewer(ctx, sub, entity.PresenceViewing)
+	if err != nil {
+		select {
+		case s.presence.unregister <- sub.id:
+		case <-s.presence.done:
+		}
+		return nil, uuid.Nil, err
+	}
+
+	// Refresh the viewer session before it expires.
+	go func() {
+		ticker := time.NewTicker(time.Duration(s.presenceCfg.ViewerTTL) * time.Second / 2)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				err := s.publishViewer(context.Background(), sub, entity.PresenceViewing)
+				if err != nil {
+					s.log.Error("failed to refresh viewer", "error", err.Error())
+				}
+			case <-sub.stop:
+				err := s.publishViewer(context.Background(), sub, entity.PresenceLeft)
+				if err != nil {
+					s.log.Error("failed to remove viewer", "error", err.Error())
+				}
+				return
+			}
+		}
+	}()
+
+	return sub.ch, sub.id, nil
+}
+
+// UnsubscribePresence cancels a subscription created by SubscribePresence.
+func (s *CommentService) UnsubscribePresence(ctx context.Context, subscriptionID uuid.UUID) {
+	ctx, span := tracer.Start(ctx, "CommentService.UnsubscribePresence")
+	defer span.End()
+
+	s.log.DebugContext(
+		ctx,
+		"UnsubscribePresence",
+		"subscriptionID", subscriptionID,
+	)
+
+	select {
+	case s.presence.unregister <- subscriptionID:
+	case <-s.presence.done:
+		// Subscriptions are closed by the stopped manager.
+	}
+}
+
+// SetTyping marks the user as typing a comment to the post for a short time.
+func (s *CommentService) SetTyping(ctx context.Context, postID, userID int) error {
+	ctx, span := tracer.Start(ctx, "CommentService.SetTyping")
+	defer span.End()
+
+	if userID <= 0 {
+		return entity.InvalidArgumentError("user ID is invalid")
+	}
+
+	s.log.DebugContext(
+		ctx,
+		"SetTyping",
+		"postID", postID,
+		"userID", userID,
+	)
+
+	err := s.checkPost(ctx, postID)
+	if err != nil {
+		return err
+	}
+
+	return s.presenceRepo.PublishPresence(ctx, &entity.PresenceEvent{
+		PostID: postID,
+		UserID: userID,
+		Kind:   entity.PresenceTyping,
+	})
+}
+
+// checkPost checks that the post exists and is published, so presence isn't tracked for posts nobody can see.
+func (s *CommentService) checkPost(ctx context.Context, postID int) error {
+	post, err := s.postRepo.GetPostByID(ctx, postID)
+	if err != nil {
+		return err
+	}
+
+	if post.Status != entity.PostStatusPublished {
+		return entity.NotFoundError("post with ID %d not found", postID)
+	}
+
+	return nil
+}
+
+func (s *CommentService) publishViewer(ctx context.Context, sub *presenceSubscription, kind entity.PresenceEventKind) error {
+	return s.presenceRepo.PublishPresence(ctx, &entity.PresenceEvent{
+		PostID:    sub.postID,
+		UserID:    sub.userID,
+		SessionID: sub.id.String(),
+		Kind:      kind,
+	})
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/oustrix/ozon_journal/config"
+	"github.com/oustrix/ozon_journal/internal/entity"
+)
+
+func TestPresenceTrackerApply(t *testing.T) {
+	viewing := func(session string, userID int) *entity.PresenceEvent {
+		return &entity.PresenceEvent{PostID: 1, UserID: userID, SessionID: session, Kind: entity.PresenceViewing}
+	}
+	left := func(session string) *entity.PresenceEvent {
+		return &entity.PresenceEvent{PostID: 1, SessionID: session, Kind: entity.PresenceLeft}
+	}
+	typing := func(userID int) *entity.PresenceEvent {
+		return &entity.PresenceEvent{PostID: 1, UserID: userID, Kind: entity.PresenceTyping}
+	}
+
+	tests := []struct {
+		name    string
+		events  []*entity.PresenceEvent
+		changed []bool
+		want    entity.Presence
+	}{
+		{
+			name:    "viewers",
+			events:  []*entity.PresenceEvent{viewing("a", 2), viewing("b", 1)},
+			changed: []bool{true, true},
+			want:    entity.Presence{PostID: 1, Viewers: 2, ViewerIDs: []int{1, 2}, TypingIDs: []int{}},
+		},
+		{
+			name:    "refreshed viewer",
+			events:  []*entity.PresenceEvent{viewing("a", 1), viewing("a", 1)},
+			changed: []bool{true, false},
+			want:    entity.Presence{PostID: 1, Viewers: 1, ViewerIDs: []int{1}, TypingIDs: []int{}},
+		},
+		{
+			name:    "user with several sessions",
+			events:  []*entity.PresenceEvent{viewing("a", 1), viewing("b", 1)},
+			changed: []bool{true, true},
+			want:    entity.Presence{PostID: 1, Viewers: 2, ViewerIDs: []int{1}, TypingIDs: []int{}},
+		},
+		{
+			name:    "anonymous viewer",
+			events:  []*entity.PresenceEvent{viewing("a", 0)},
+			changed: []bool{true},
+			want:    entity.Presence{PostID: 1, Viewers: 1, ViewerIDs: []int{}, TypingIDs: []int{}},
+		},
+		{
+			name:    "left viewer",
+			events:  []*entity.PresenceEvent{viewing("a", 1), left("a"), left("a")},
+			changed: []bool{true, true, false},
+			want:    entity.Presence{PostID: 1, ViewerIDs: []int{}, TypingIDs: []int{}},
+		},
+		{
+			name:    "typing users",
+			events:  []*entity.PresenceEvent{typing(3), typing(1), typing(3)},
+			changed: []bool{true, true, false},
+			want:    entity.Presence{PostID: 1, ViewerIDs: []int{}, TypingIDs: []int{1, 3}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pt := &presenceTracker{cfg: &config.Presence{ViewerTTL: 60, TypingTTL: 5}, posts: make(map[int]*postPresence)}
+
+			now := time.Now()
+			for i, event := range tt.events {
+				if changed := pt.apply(event, now); changed != tt.changed[i] {
+					t.Errorf("apply() of event %d = %v, want %v", i, changed, tt.changed[i])
+				}
+			}
+
+			if got := pt.snapshot(1); fmt.Sprint(*got) != fmt.Sprint(tt.want) {
+				t.Errorf("snapshot() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPresenceTrackerExpire(t *testing.T) {
+	tests := []struct {
+		name    string
+		after   time.Duration
+		changed []int
+		want    entity.Presence
+	}{
+		{
+			name:    "nothing expired",
+			after:   time.Second,
+			changed: []int{},
+			want:    entity.Presence{PostID: 1, Viewers: 1, ViewerIDs: []int{1}, TypingIDs: []int{2}},
+		},
+		{
+			name:    "typing expired",
+			after:   10 * time.Second,
+			changed: []int{1},
+			want:    entity.Presence{PostID: 1, Viewers: 1, ViewerIDs: []int{1}, TypingIDs: []int{}},
+		},
+		{
+			name:    "everything expired",
+			after:   2 * time.Minute,
+			changed: []int{1},
+			want:    entity.Presence{PostID: 1, ViewerIDs: []int{}, TypingIDs: []int{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pt := &presenceTracker{cfg: &config.Presence{ViewerTTL: 60, TypingTTL: 5}, posts: make(map[int]*postPresence)}
+
+			now := time.Now()
+			pt.apply(&entity.PresenceEvent{PostID: 1, UserID: 1, SessionID: "a", Kind: entity.PresenceViewing}, now)
+			pt.apply(&entity.PresenceEvent{PostID: 1, UserID: 2, Kind: entity.PresenceTyping}, now)
+
+			if changed := pt.expire(now.Add(tt.after)); fmt.Sprint(changed) != fmt.Sprint(tt.changed) {
+				t.Errorf("expire() = %v, want %v", changed, tt.changed)
+			}
+			if got := pt.snapshot(1); fmt.Sprint(*got) != fmt.Sprint(tt.want) {
+				t.Errorf("snapshot() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubscribePresence(t *testing.T) {
+	ctx := context.Background()
+	s := newTestCommentService(newCommentRepoStub(), newPostRepoStub(entity.Post{ID: 1, Status: entity.PostStatusPublished}))
+
+	_, _, err := s.SubscribePresence(ctx, 2, 1)
+	if err == nil {
+		t.Fatal("SubscribePresence() of a missing post succeeded")
+	}
+
+	ch, id, err := s.SubscribePresence(ctx, 1, 1)
+	if err != nil {
+		t.Fatalf("SubscribePresence() error = %v", err)
+	}
+
+	// The subscriber gets the state without itself first and then sees itself as a viewer.
+	want := entity.Presence{PostID: 1, Viewers: 1, ViewerIDs: []int{1}, TypingIDs: []int{}}
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case presence := <-ch:
+			if fmt.Sprint(*presence) != fmt.Sprint(want) {
+				continue
+			}
+		case <-timeout:
+			t.Fatal("the subscriber didn't see itself as a viewer")
+		}
+		break
+	}
+
+	s.UnsubscribePresence(ctx, id)
+	for range ch {
+	}
+}
@@ -0,0 +1,342 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oustrix/ozon_journal/config"
+	"github.com/oustrix/ozon_journal/internal"
+	"github.com/oustrix/ozon_journal/internal/entity"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+)
+
+// reactionLockStripes is the number of locks changes of reactions are serialized by, selected by the target ID.
+const reactionLockStripes = 64
+
+// ReactionService is a service for managing reactions on posts and comments.
+type ReactionService struct {
+	repo        internal.ReactionRepository
+	postRepo    internal.PostRepository
+	commentRepo internal.CommentRepository
+	cfg         *config.Reaction
+	emojis      map[string]bool
+	sub         *reactionSubscriptionManager
+	// locks are held from a change of reactions until its counts are published,
+	// so subscribers get counts of a target in the order of changes.
+	locks [reactionLockStripes]sync.Mutex
+	log   *logger.Logger
+}
+
+type reactionTarget struct {
+	targetType entity.ReactionTargetType
+	targetID   int
+}
+
+type reactionSubscription struct {
+	id     uuid.UUID
+	target reactionTarget
+	ch     chan *entity.ReactionsChange
+}
+
+type reactionSubscriptionManager struct {
+	subscriptions map[uuid.UUID]*reactionSubscription
+	byTarget      map[reactionTarget][]*reactionSubscription
+	register      chan *reactionSubscription
+	unregister    chan uuid.UUID
+	changes       chan *entity.ReactionsChange
+	subscriptionCounters
+	managerState
+}
+
+// NewReactionService creates a new ReactionService. Subscriptions are served until the context is done.
+func NewReactionService(ctx context.Context, repo internal.ReactionRepository, postRepo internal.PostRepository,
+	commentRepo internal.CommentRepository, cfg *config.Reaction, log *logger.Logger) *ReactionService {
+	emojis := make(map[string]bool, len(cfg.Emojis))
+	for _, emoji := range cfg.Emojis {
+		emojis[emoji] = true
+	}
+
+	return &ReactionService{
+		repo:        repo,
+		postRepo:    postRepo,
+		commentRepo: commentRepo,
+		cfg:         cfg,
+		emojis:      emojis,
+		sub:         newReactionSubscriptionManager(ctx),
+		log:         log.With("layer", "service"),
+	}
+}
+
+func newReactionSubscriptionManager(ctx context.Context) *reactionSubscriptionManager {
+	sm := &reactionSubscriptionManager{
+		subscriptions: make(map[uuid.UUID]*reactionSubscription),
+		byTarget:      make(map[reactionTarget][]*reactionSubscription),
+		register:      make(chan *reactionSubscription),
+		unregister:    make(chan uuid.UUID),
+		changes:       make(chan *entity.ReactionsChange),
+		managerState:  newManagerState(),
+	}
+
+	go func() {
+		defer sm.stopped()
+		defer sm.closeSubscriptions()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			// Answer a readiness check.
+			case <-sm.pings:
+			// Register a new subscriber.
+			case sub := <-sm.register:
+				sm.subscriptions[sub.id] = sub
+				sm.byTarget[sub.target] = append(sm.byTarget[sub.target], sub)
+				sm.active.Store(int64(len(sm.subscriptions)))
+			// Unregister a subscriber and close its channel.
+			case id := <-sm.unregister:
+				sub, ok := sm.subscriptions[id]
+				if !ok {
+					break
+				}
+				delete(sm.subscriptions, id)
+
+				subs := sm.byTarget[sub.target]
+				for i, s := range subs {
+					if s.id == id {
+						subs = append(subs[:i], subs[i+1:]...)
+						break
+					}
+				}
+				if len(subs) == 0 {
+					delete(sm.byTarget, sub.target)
+				} else {
+					sm.byTarget[sub.target] = subs
+				}
+
+				sm.active.Store(int64(len(sm.subscriptions)))
+				close(sub.ch)
+			// Send the change to all subscribers of the target.
+			case change := <-sm.changes:
+				target := reactionTarget{targetType: change.TargetType, targetID: change.TargetID}
+				for _, sub := range sm.byTarget[target] {
+					sendReactionsChange(sub.ch, change)
+				}
+				sm.queued.Add(-1)
+			}
+		}
+	}()
+
+	return sm
+}
+
+// publish sends a change to the manager, blocking until the manager takes it.
+// Changes published after the manager stops are dropped, there are no subscribers to send them to.
+func (sm *reactionSubscriptionManager) publish(change *entity.ReactionsChange) {
+	sm.queued.Add(1)
+	select {
+	case sm.changes <- change:
+	case <-sm.done:
+		sm.queued.Add(-1)
+	}
+}
+
+// closeSubscriptions closes channels of all subscribers, so their subscriptions complete.
+func (sm *reactionSubscriptionManager) closeSubscriptions() {
+	for id, sub := range sm.subscriptions {
+		delete(sm.subscriptions, id)
+		close(sub.ch)
+	}
+	clear(sm.byTarget)
+	sm.active.Store(0)
+}
+
+// sendReactionsChange sends a change to a buffered channel of size 1 without blocking.
+// Every change contains all counts, so an unread change is replaced with the new one.
+func sendReactionsChange(ch chan *entity.ReactionsChange, change *entity.ReactionsChange) {
+	select {
+	case ch <- change:
+	default:
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- change
+	}
+}
+
+// React adds a reaction and returns updated reaction counts of the target.
+func (s *ReactionService) React(ctx context.Context, reaction *entity.Reaction) ([]entity.ReactionCount, error) {
+	ctx, span := tracer.Start(ctx, "ReactionService.React")
+	defer span.End()
+
+	err := s.validate(ctx, reaction)
+	if err != nil {
+		return nil, err
+	}
+
+	reaction.CreatedAt = int(time.Now().Unix())
+
+	s.log.DebugContext(
+		ctx,
+		"React",
+		"targetType", reaction.TargetType,
+		"targetID", reaction.TargetID,
+	)
+
+	mu := s.lock(reaction)
+	defer mu.Unlock()
+
+	counts, added, err := s.repo.AddReaction(ctx, reaction)
+	if err != nil {
+		return nil, err
+	}
+
+	if added {
+		s.publishCounts(reaction, counts)
+	}
+
+	return counts, nil
+}
+
+// Unreact removes a reaction and returns updated reaction counts of the target.
+func (s *ReactionService) Unreact(ctx context.Context, reaction *entity.Reaction) ([]entity.ReactionCount, error) {
+	ctx, span := tracer.Start(ctx, "ReactionService.Unreact")
+	defer span.End()
+
+	err := s.validate(ctx, reaction)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.DebugContext(
+		ctx,
+		"Unreact",
+		"targetType", reaction.TargetType,
+		"targetID", reaction.TargetID,
+	)
+
+	mu := s.lock(reaction)
+	defer mu.Unlock()
+
+	counts, removed, err := s.repo.RemoveReaction(ctx, reaction)
+	if err != nil {
+		return nil, err
+	}
+
+	if removed {
+		s.publishCounts(reaction, counts)
+	}
+
+	return counts, nil
+}
+
+// GetReactionCounts returns reaction counts of a post or a comment.
+func (s *ReactionService) GetReactionCounts(ctx context.Context, targetType entity.ReactionTargetType, targetID int) ([]entity.ReactionCount, error) {
+	ctx, span := tracer.Start(ctx, "ReactionService.GetReactionCounts")
+	defer span.End()
+
+	s.log.DebugContext(
+		ctx,
+		"GetReactionCounts",
+		"targetType", targetType,
+		"targetID", targetID,
+	)
+
+	return s.repo.GetReactionCounts(ctx, targetType, targetID)
+}
+
+// SubscribeReactions subscribes to changes of reaction counts of a post or a comment.
+func (s *ReactionService) SubscribeReactions(ctx context.Context, targetType entity.ReactionTargetType, targetID int) (<-chan *entity.ReactionsChange, uuid.UUID, error) {
+	ctx, span := tracer.Start(ctx, "ReactionService.SubscribeReactions")
+	defer span.End()
+
+	sub := &reactionSubscription{
+		id:     uuid.New(),
+		target: reactionTarget{targetType: targetType, targetID: targetID},
+		ch:     make(chan *entity.ReactionsChange, 1),
+	}
+
+	s.log.DebugContext(
+		ctx,
+		"SubscribeReactions",
+		"targetType", targetType,
+		"targetID", targetID,
+		"subscriptionID", sub.id,
+	)
+
+	select {
+	case s.sub.register <- sub:
+	case <-s.sub.done:
+		return nil, uuid.Nil, ErrShuttingDown
+	}
+
+	return sub.ch, sub.id, nil
+}
+
+// UnsubscribeReactions cancels a subscription created by SubscribeReactions.
+func (s *ReactionService) UnsubscribeReactions(ctx context.Context, subscriptionID uuid.UUID) {
+	ctx, span := tracer.Start(ctx, "ReactionService.UnsubscribeReactions")
+	defer span.End()
+
+	s.log.DebugContext(
+		ctx,
+		"UnsubscribeReactions",
+		"subscriptionID", subscriptionID,
+	)
+
+	select {
+	case s.sub.unregister <- subscriptionID:
+	case <-s.sub.done:
+		// Subscriptions are closed by the stopped manager.
+	}
+}
+
+// validate checks the user, that the emoji is allowed and the target exists.
+func (s *ReactionService) validate(ctx context.Context, reaction *entity.Reaction) error {
+	if reaction.UserID <= 0 {
+		return entity.InvalidArgumentError("user ID is invalid")
+	}
+
+	if !s.emojis[reaction.Emoji] {
+		return entity.InvalidArgumentError("emoji %q is not allowed", reaction.Emoji)
+	}
+
+	switch reaction.TargetType {
+	case entity.ReactionTargetPost:
+		post, err := s.postRepo.GetPostByID(ctx, reaction.TargetID)
+		if err != nil {
+			return err
+		}
+
+		if post.Status != entity.PostStatusPublished {
+			return entity.NotFoundError("post with ID %d not found", reaction.TargetID)
+		}
+	case entity.ReactionTargetComment:
+		_, err := s.commentRepo.GetCommentByID(ctx, reaction.TargetID)
+		if err != nil {
+			return err
+		}
+	default:
+		return entity.InvalidArgumentError("unknown reaction target type %q", reaction.TargetType)
+	}
+
+	return nil
+}
+
+// lock locks changes of reactions of the reaction target and returns the held lock.
+func (s *ReactionService) lock(reaction *entity.Reaction) *sync.Mutex {
+	mu := &s.locks[uint(reaction.TargetID)%reactionLockStripes]
+	mu.Lock()
+	return mu
+}
+
+// publishCounts notifies subscribers of the reaction target about its new counts.
+func (s *ReactionService) publishCounts(reaction *entity.Reaction, counts []entity.ReactionCount) {
+	s.sub.publish(&entity.ReactionsChange{
+		TargetType: reaction.TargetType,
+		TargetID:   reaction.TargetID,
+		Reactions:  counts,
+	})
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/oustrix/ozon_journal/config"
+	"github.com/oustrix/ozon_journal/internal/entity"
+	"github.com/oustrix/ozon_journal/internal/repository/inmemory"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+)
+
+func newTestReactionService() *ReactionService {
+	log := logger.New("error")
+	posts := newPostRepoStub(entity.Post{ID: 1, Status: entity.PostStatusPublished})
+	comments := newCommentRepoStub(entity.Comment{ID: 1, PostID: 1, ParentCommentID: -1})
+
+	return NewReactionService(context.Background(), inmemory.NewReactionRepository(log), posts, comments,
+		&config.Reaction{Emojis: []string{"👍", "🔥"}}, log)
+}
+
+func TestReactionCounts(t *testing.T) {
+	type change struct {
+		remove bool
+		userID int
+		emoji  string
+	}
+
+	tests := []struct {
+		name    string
+		changes []change
+		want    []entity.ReactionCount
+	}{
+		{name: "no reactions", want: []entity.ReactionCount{}},
+		{
+			name:    "most popular first",
+			changes: []change{{userID: 1, emoji: "🔥"}, {userID: 1, emoji: "👍"}, {userID: 2, emoji: "👍"}},
+			want:    []entity.ReactionCount{{Emoji: "👍", Count: 2}, {Emoji: "🔥", Count: 1}},
+		},
+		{
+			name:    "same emoji twice",
+			changes: []change{{userID: 1, emoji: "👍"}, {userID: 1, emoji: "👍"}},
+			want:    []entity.ReactionCount{{Emoji: "👍", Count: 1}},
+		},
+		{
+			name:    "removed reaction",
+			changes: []change{{userID: 1, emoji: "👍"}, {userID: 2, emoji: "👍"}, {remove: true, userID: 1, emoji: "👍"}},
+			want:    []entity.ReactionCount{{Emoji: "👍", Count: 1}},
+		},
+		{
+			name:    "removed last reaction",
+			changes: []change{{userID: 1, emoji: "👍"}, {remove: true, userID: 1, emoji: "👍"}},
+			want:    []entity.ReactionCount{},
+		},
+		{
+			name:    "removed missing reaction",
+			changes: []change{{userID: 1, emoji: "👍"}, {remove: true, userID: 2, emoji: "👍"}},
+			want:    []entity.ReactionCount{{Emoji: "👍", Count: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newTestReactionService()
+
+			var counts []entity.ReactionCount
+			for _, c := range tt.changes {
+				reaction := &entity.Reaction{TargetType: entity.ReactionTargetPost, TargetID: 1, UserID: c.userID, Emoji: c.emoji}
+
+				var err error
+				if c.remove {
+					counts, err = s.Unreact(ctx, reaction)
+				} else {
+					counts, err = s.React(ctx, reaction)
+				}
+				if err != nil {
+					t.Fatalf("failed to change reactions: %v", err)
+				}
+			}
+
+			got, err := s.GetReactionCounts(ctx, entity.ReactionTargetPost, 1)
+			if err != nil {
+				t.Fatalf("GetReactionCounts() error = %v", err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("GetReactionCounts() = %v, want %v", got, tt.want)
+			}
+			// The last change returns the same counts.
+			if len(tt.changes) > 0 && fmt.Sprint(counts) != fmt.Sprint(tt.want) {
+				t.Errorf("counts of the last change = %v, want %v", counts, tt.want)
+			}
+		})
+	}
+}
+
+func TestReactValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		reaction entity.Reaction
+		wantErr  bool
+	}{
+		{name: "post", reaction: entity.Reaction{TargetType: entity.ReactionTargetPost, TargetID: 1, UserID: 1, Emoji: "👍"}},
+		{name: "comment", reaction: entity.Reaction{TargetType: entity.ReactionTargetComment, TargetID: 1, UserID: 1, Emoji: "👍"}},
+		{name: "missing user", reaction: entity.Reaction{TargetType: entity.ReactionTargetPost, TargetID: 1, Emoji: "👍"},
+			wantErr: true},
+		{name: "emoji not allowed", wantErr: true,
+			reaction: entity.Reaction{TargetType: entity.ReactionTargetPost, TargetID: 1, UserID: 1, Emoji: "💩"}},
+		{name: "missing post", reaction: entity.Reaction{TargetType: entity.ReactionTargetPost, TargetID: 2, UserID: 1, Emoji: "👍"},
+			wantErr: true},
+		{name: "missing comment", wantErr: true,
+			reaction: entity.Reaction{TargetType: entity.ReactionTargetComment, TargetID: 2, UserID: 1, Emoji: "👍"}},
+		{name: "unknown target", reaction: entity.Reaction{TargetType: "user", TargetID: 1, UserID: 1, Emoji: "👍"},
+			wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTestReactionService().React(context.Background(), &tt.reaction)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("React() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSubscribeReactions(t *testing.T) {
+	ctx := context.Background()
+	s := newTestReactionService()
+
+	ch, id, err := s.SubscribeReactions(ctx, entity.ReactionTargetPost, 1)
+	if err != nil {
+		t.Fatalf("SubscribeReactions() error = %v", err)
+	}
+	defer s.UnsubscribeReactions(ctx, id)
+
+	// A reaction on another target isn't sent to the subscriber.
+	_, err = s.React(ctx, &entity.Reaction{TargetType: entity.ReactionTargetComment, TargetID: 1, UserID: 1, Emoji: "👍"})
+	if err != nil {
+		t.Fatalf("React() error = %v", err)
+	}
+	_, err = s.React(ctx, &entity.Reaction{TargetType: entity.ReactionTargetPost, TargetID: 1, UserID: 1, Emoji: "🔥"})
+	if err != nil {
+		t.Fatalf("React() error = %v", err)
+	}
+
+	want := entity.ReactionsChange{
+		TargetType: entity.ReactionTargetPost,
+		TargetID:   1,
+		Reactions:  []entity.ReactionCount{{Emoji: "🔥", Count: 1}},
+	}
+	select {
+	case change := <-ch:
+		if fmt.Sprint(*change) != fmt.Sprint(want) {
+			t.Errorf("change = %v, want %v", *change, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the change wasn't sent to the subscriber")
+	}
+}
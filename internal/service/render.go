@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/oustrix/ozon_journal/config"
+	"github.com/oustrix/ozon_journal/internal/entity"
+	"github.com/oustrix/ozon_journal/pkg/logger"
+	"github.com/oustrix/ozon_journal/pkg/markdown"
+)
+
+// RenderService is a service that renders the content of posts and comments to sanitized HTML.
+type RenderService struct {
+	renderer *markdown.Renderer
+	cache    *lru.Cache[string, string] // Rendered HTML keyed by post revision or comment.
+	log      *logger.Logger
+}
+
+// NewRenderService creates a new RenderService.
+func NewRenderService(cfg *config.Render, log *logger.Logger) (*RenderService, error) {
+	cache, err := lru.New[string, string](int(cfg.CacheSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create render cache: %w", err)
+	}
+
+	return &RenderService{renderer: markdown.New(), cache: cache, log: log.With("layer", "service")}, nil
+}
+
+// RenderPost returns the content of a post as HTML.
+// Content of a post changes with its revision only, so the result is cached per revision.
+func (s *RenderService) RenderPost(ctx context.Context, post *entity.Post) (string, error) {
+	key := fmt.Sprintf("post:%d:%d", post.ID, post.Revision)
+	return s.render(ctx, key, post.ContentFormat, post.Content)
+}
+
+// RenderComment returns the content of a comment as HTML. Comments can't be edited, so the result is cached per comment.
+func (s *RenderService) RenderComment(ctx context.Context, comment *entity.Comment) (string, error) {
+	key := fmt.Sprintf("comment:%d", comment.ID)
+	return s.render(ctx, key, comment.ContentFormat, comment.Content)
+}
+
+// validContentFormat checks if the content format is known.
+func validContentFormat(format entity.ContentFormat) bool {
+	return format == entity.ContentFormatPlain || format == entity.ContentFormatMarkdown
+}
+
+func (s *RenderService) render(ctx context.Context, key string, format entity.ContentFormat, content string) (string, error) {
+	if html, ok := s.cache.Get(key); ok {
+		return html, nil
+	}
+
+	s.log.DebugContext(
+		ctx,
+		"render",
+		"key", key,
+		"format", format,
+	)
+
+	var html string
+	switch format {
+	case entity.ContentFormatMarkdown:
+		var err error
+		html, err = s.renderer.Markdown(content)
+		if err != nil {
+			return "", err
+		}
+	case entity.ContentFormatPlain:
+		html = s.renderer.Plain(content)
+	default:
+		return "", fmt.Errorf("unknown content format %q", format)
+	}
+
+	s.cache.Add(key, html)
+
+	return html, nil
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"sync/atomic"
+)
+
+// SubscriptionStats is a snapshot of the state of a subscription manager.
+type SubscriptionStats struct {
+	// Subscriptions is the number of active subscriptions.
+	Subscriptions int
+	// Queued is the number of events that are waiting for the manager or being delivered to subscribers.
+	// It grows when subscribers don't keep up with events.
+	Queued int
+}
+
+// subscriptionBufferSize is the number of events a subscriber to posts or comments may fall behind by
+// before it is disconnected.
+const subscriptionBufferSize = 64
+
+// subscriptionCounters are updated by subscription managers and read by metrics without locking.
+type subscriptionCounters struct {
+	active atomic.Int64
+	queued atomic.Int64
+}
+
+func (c *subscriptionCounters) stats() SubscriptionStats {
+	return SubscriptionStats{
+		Subscriptions: int(c.active.Load()),
+		Queued:        int(c.queued.Load()),
+	}
+}
+
+// SubscriptionStats returns the state of subscriptions to published posts.
+func (s *PostService) SubscriptionStats() map[string]SubscriptionStats {
+	return map[string]SubscriptionStats{"posts": s.sub.stats()}
+}
+
+// SubscriptionStats returns the state of subscriptions to comments, replies and presence.
+// Subscriptions to comments and replies share a manager.
+func (s *CommentService) SubscriptionStats() map[string]SubscriptionStats {
+	return map[string]SubscriptionStats{
+		"comments": s.sub.stats(),
+		"presence": s.presence.stats(),
+	}
+}
+
+// SubscriptionStats returns the state of subscriptions to reaction counts.
+func (s *ReactionService) SubscriptionStats() map[string]SubscriptionStats {
+	return map[string]SubscriptionStats{"reactions": s.sub.stats()}
+}
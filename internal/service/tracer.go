@@ -0,0 +1,6 @@
+package service
+
+import "go.opentelemetry.io/otel"
+
+// tracer creates spans of service methods with the global tracer provider.
+var tracer = otel.Tracer("github.com/oustrix/ozon_journal/internal/service")
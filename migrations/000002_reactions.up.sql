@@ -0,0 +1,8 @@
+CREATE TABLE reactions (
+    target_type VARCHAR(16) NOT NULL,
+    target_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    emoji VARCHAR(32) NOT NULL,
+    created_at BIGINT NOT NULL,
+    PRIMARY KEY (target_type, target_id, user_id, emoji)
+);
\ No newline at end of file
@@ -0,0 +1,5 @@
+ALTER TABLE posts ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'published';
+ALTER TABLE posts ADD COLUMN publish_at BIGINT;
+
+CREATE INDEX idx_posts_status_publish_at ON posts(status, publish_at);
+CREATE INDEX idx_posts_author_id ON posts(author_id);
\ No newline at end of file
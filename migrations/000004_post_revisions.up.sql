@@ -0,0 +1,16 @@
+ALTER TABLE posts ADD COLUMN revision INTEGER NOT NULL DEFAULT 1;
+
+CREATE TABLE post_revisions (
+    post_id INTEGER NOT NULL,
+    version INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL,
+    editor_id INTEGER NOT NULL,
+    created_at BIGINT NOT NULL,
+    PRIMARY KEY (post_id, version),
+    FOREIGN KEY (post_id) REFERENCES posts(id)
+);
+
+-- Existing posts get their current title and content as the first revision.
+INSERT INTO post_revisions (post_id, version, title, content, editor_id, created_at)
+SELECT id, 1, title, content, author_id, published_at FROM posts;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE comments DROP COLUMN IF EXISTS content_format;
+ALTER TABLE posts DROP COLUMN IF EXISTS content_format;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE posts ADD COLUMN content_format VARCHAR(16) NOT NULL DEFAULT 'plain';
+ALTER TABLE comments ADD COLUMN content_format VARCHAR(16) NOT NULL DEFAULT 'plain';
\ No newline at end of file
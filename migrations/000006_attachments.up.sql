@@ -0,0 +1,13 @@
+CREATE TABLE attachments (
+    id SERIAL PRIMARY KEY,
+    post_id INTEGER NOT NULL,
+    key TEXT NOT NULL UNIQUE,
+    file_name TEXT NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    uploader_id INTEGER NOT NULL,
+    created_at BIGINT NOT NULL,
+    FOREIGN KEY (post_id) REFERENCES posts(id)
+);
+
+CREATE INDEX idx_attachments_post_id ON attachments(post_id);
\ No newline at end of file
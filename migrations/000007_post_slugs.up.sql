@@ -0,0 +1,16 @@
+ALTER TABLE posts ADD COLUMN slug TEXT NOT NULL DEFAULT '';
+
+-- Titles can't be transliterated in SQL, so existing posts get slugs from their IDs.
+UPDATE posts SET slug = 'post-' || id;
+
+CREATE TABLE post_slugs (
+    slug TEXT PRIMARY KEY,
+    post_id INTEGER NOT NULL,
+    created_at BIGINT NOT NULL,
+    FOREIGN KEY (post_id) REFERENCES posts(id)
+);
+
+CREATE INDEX idx_post_slugs_post_id ON post_slugs(post_id);
+
+INSERT INTO post_slugs (slug, post_id, created_at)
+SELECT slug, id, published_at FROM posts;
\ No newline at end of file
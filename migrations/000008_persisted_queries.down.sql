@@ -0,0 +1 @@
+DROP TABLE IF EXISTS persisted_queries;
\ No newline at end of file
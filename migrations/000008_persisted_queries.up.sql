@@ -0,0 +1,8 @@
+CREATE TABLE persisted_queries (
+    hash CHAR(64) PRIMARY KEY,
+    query TEXT NOT NULL,
+    created_at BIGINT NOT NULL
+);
+
+-- The cache evicts the oldest queries when it is full.
+CREATE INDEX idx_persisted_queries_created_at ON persisted_queries(created_at);
\ No newline at end of file
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS attachments;
+DROP TABLE IF EXISTS reactions;
+DROP TABLE IF EXISTS post_slugs;
+DROP TABLE IF EXISTS post_revisions;
+DROP TABLE IF EXISTS comments;
+DROP TABLE IF EXISTS posts;
\ No newline at end of file
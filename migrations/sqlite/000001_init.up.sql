@@ -0,0 +1,73 @@
+CREATE TABLE posts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    title TEXT NOT NULL,
+    slug TEXT NOT NULL DEFAULT '',
+    content TEXT NOT NULL,
+    content_format TEXT NOT NULL DEFAULT 'plain',
+    published_at INTEGER NOT NULL,
+    author_id INTEGER NOT NULL,
+    commentable BOOLEAN NOT NULL DEFAULT TRUE,
+    status TEXT NOT NULL DEFAULT 'published',
+    publish_at INTEGER,
+    revision INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE INDEX idx_posts_published_at ON posts(published_at);
+CREATE INDEX idx_posts_status_publish_at ON posts(status, publish_at);
+CREATE INDEX idx_posts_author_id ON posts(author_id);
+
+CREATE TABLE comments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    content TEXT NOT NULL,
+    content_format TEXT NOT NULL DEFAULT 'plain',
+    author_id INTEGER NOT NULL,
+    post_id INTEGER NOT NULL,
+    published_at INTEGER NOT NULL,
+    parent_comment_id INTEGER NOT NULL,
+    FOREIGN KEY (post_id) REFERENCES posts(id)
+);
+
+CREATE INDEX idx_comments_post_id ON comments(post_id);
+
+CREATE TABLE post_revisions (
+    post_id INTEGER NOT NULL,
+    version INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL,
+    editor_id INTEGER NOT NULL,
+    created_at INTEGER NOT NULL,
+    PRIMARY KEY (post_id, version),
+    FOREIGN KEY (post_id) REFERENCES posts(id)
+);
+
+CREATE TABLE post_slugs (
+    slug TEXT PRIMARY KEY,
+    post_id INTEGER NOT NULL,
+    created_at INTEGER NOT NULL,
+    FOREIGN KEY (post_id) REFERENCES posts(id)
+);
+
+CREATE INDEX idx_post_slugs_post_id ON post_slugs(post_id);
+
+CREATE TABLE reactions (
+    target_type TEXT NOT NULL,
+    target_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    emoji TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    PRIMARY KEY (target_type, target_id, user_id, emoji)
+);
+
+CREATE TABLE attachments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    post_id INTEGER NOT NULL,
+    key TEXT NOT NULL UNIQUE,
+    file_name TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    uploader_id INTEGER NOT NULL,
+    created_at INTEGER NOT NULL,
+    FOREIGN KEY (post_id) REFERENCES posts(id)
+);
+
+CREATE INDEX idx_attachments_post_id ON attachments(post_id);
\ No newline at end of file
@@ -0,0 +1,23 @@
+package grpcserver
+
+import (
+	"net"
+	"time"
+)
+
+// Option allows for managing server options.
+type Option func(*Server)
+
+// Port is a server option for setting the port.
+func Port(port string) Option {
+	return func(s *Server) {
+		s.addr = net.JoinHostPort("", port)
+	}
+}
+
+// ShutdownTimeout sets the shutdown timeout for the server.
+func ShutdownTimeout(timeout time.Duration) Option {
+	return func(s *Server) {
+		s.shutdownTimeout = timeout
+	}
+}
@@ -0,0 +1,81 @@
+package grpcserver
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+const (
+	_defaultAddr            = ":9090"
+	_defaultShutdownTimeout = 3 * time.Second
+)
+
+// ErrShutdownTimeout is returned by Shutdown when calls don't finish in time and are cancelled.
+var ErrShutdownTimeout = errors.New("grpc server shutdown timed out")
+
+// Server manages the lifecycle of a gRPC server.
+type Server struct {
+	server          *grpc.Server
+	addr            string
+	notify          chan error
+	shutdownTimeout time.Duration
+}
+
+// New creates a new gRPC server serving the services registered on server.
+func New(server *grpc.Server, opts ...Option) *Server {
+	s := &Server{
+		server:          server,
+		addr:            _defaultAddr,
+		notify:          make(chan error, 1),
+		shutdownTimeout: _defaultShutdownTimeout,
+	}
+
+	// Custom options
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.start()
+
+	return s
+}
+
+func (s *Server) start() {
+	go func() {
+		listener, err := net.Listen("tcp", s.addr)
+		if err != nil {
+			s.notify <- err
+			close(s.notify)
+			return
+		}
+
+		s.notify <- s.server.Serve(listener)
+		close(s.notify)
+	}()
+}
+
+// Notify returns a channel that will receive an error when the server stops.
+func (s *Server) Notify() <-chan error {
+	return s.notify
+}
+
+// Shutdown gracefully shuts down the server. Streaming calls may last forever,
+// so calls still running after the shutdown timeout are cancelled.
+func (s *Server) Shutdown() error {
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-time.After(s.shutdownTimeout):
+		s.server.Stop()
+		return ErrShutdownTimeout
+	}
+}
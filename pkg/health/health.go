@@ -0,0 +1,118 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const _defaultTimeout = 2 * time.Second
+
+// Check reports whether a dependency of the application is ready to serve requests.
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Health serves liveness and readiness probes. The application is live while it can answer
+// the probe, and ready while all checks pass and it isn't shutting down.
+type Health struct {
+	timeout   time.Duration
+	onFailure func(ctx context.Context, name string, err error)
+
+	mu     sync.RWMutex
+	checks []namedCheck
+
+	shuttingDown atomic.Bool
+}
+
+// status is a body of responses of the probes.
+type status struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// New creates a new Health without checks.
+func New(opts ...Option) *Health {
+	h := &Health{
+		timeout:   _defaultTimeout,
+		onFailure: func(context.Context, string, error) {},
+	}
+
+	// Custom options
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+// Add adds a readiness check. Checks are run in the order they are added.
+func (h *Health) Add(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.checks = append(h.checks, namedCheck{name: name, check: check})
+}
+
+// Shutdown makes the readiness probe fail, so load balancers stop sending new requests
+// while the application is shutting down. It can't be undone.
+func (h *Health) Shutdown() {
+	h.shuttingDown.Store(true)
+}
+
+// LivenessHandler returns a handler of the liveness probe. It doesn't run checks, so failing dependencies
+// don't get the application restarted.
+func (h *Health) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeStatus(w, http.StatusOK, &status{Status: "ok"})
+	})
+}
+
+// ReadinessHandler returns a handler of the readiness probe. It responds with 503 and the statuses
+// of all checks if any of them fails. Errors of checks aren't exposed, they are passed to OnFailure.
+func (h *Health) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.shuttingDown.Load() {
+			writeStatus(w, http.StatusServiceUnavailable, &status{Status: "shutting down"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+		defer cancel()
+
+		h.mu.RLock()
+		checks := h.checks
+		h.mu.RUnlock()
+
+		resp := &status{Status: "ok", Checks: make(map[string]string, len(checks))}
+		code := http.StatusOK
+
+		for _, c := range checks {
+			err := c.check(ctx)
+			if err != nil {
+				h.onFailure(ctx, c.name, err)
+				resp.Checks[c.name] = "failed"
+				resp.Status = "unavailable"
+				code = http.StatusServiceUnavailable
+			} else {
+				resp.Checks[c.name] = "ok"
+			}
+		}
+
+		writeStatus(w, code, resp)
+	})
+}
+
+func writeStatus(w http.ResponseWriter, code int, body *status) {
+	w.Header().Set("Content-Type", "application/json")
+	// Probes must see the current state.
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,24 @@
+package health
+
+import (
+	"context"
+	"time"
+)
+
+// Option allows for managing health options.
+type Option func(*Health)
+
+// Timeout limits the time of running all readiness checks of a probe.
+func Timeout(timeout time.Duration) Option {
+	return func(h *Health) {
+		h.timeout = timeout
+	}
+}
+
+// OnFailure sets a function called with errors of failed readiness checks. Responses of the probe
+// carry only names of checks and their statuses, so errors are reported to the function only.
+func OnFailure(onFailure func(ctx context.Context, name string, err error)) Option {
+	return func(h *Health) {
+		h.onFailure = onFailure
+	}
+}
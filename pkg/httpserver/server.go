@@ -2,7 +2,9 @@ package httpserver
 
 import (
 	"context"
+	"net"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -18,21 +20,31 @@ type Server struct {
 	server          *http.Server
 	notify          chan error
 	shutdownTimeout time.Duration
+
+	// Contexts of requests are derived from the base context, which is cancelled on shutdown
+	// to stop handlers of hijacked connections.
+	cancel   context.CancelFunc
+	handlers sync.WaitGroup
 }
 
 // New creates a new HTTP server.
 func New(handler http.Handler, opts ...Option) *Server {
-	httpServer := &http.Server{
-		Handler:      handler,
-		ReadTimeout:  _defaultReadTimeout,
-		WriteTimeout: _defaultWriteTimeout,
-		Addr:         _defaultAddr,
-	}
+	ctx, cancel := context.WithCancel(context.Background())
 
 	s := &Server{
-		server:          httpServer,
 		notify:          make(chan error, 1),
 		shutdownTimeout: _defaultShutdownTimeout,
+		cancel:          cancel,
+	}
+
+	s.server = &http.Server{
+		Handler:      s.track(handler),
+		ReadTimeout:  _defaultReadTimeout,
+		WriteTimeout: _defaultWriteTimeout,
+		Addr:         _defaultAddr,
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
 	}
 
 	// Custom options
@@ -57,10 +69,39 @@ func (s *Server) Notify() <-chan error {
 	return s.notify
 }
 
-// Shutdown gracefully shuts down the server.
+// track counts running handlers, so Shutdown can wait for them.
+func (s *Server) track(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handlers.Add(1)
+		defer s.handlers.Done()
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// Shutdown gracefully shuts down the server. http.Server.Shutdown doesn't wait for hijacked connections,
+// e.g. websockets, so after other connections are closed, contexts of requests are cancelled
+// and Shutdown waits for the handlers of hijacked connections to return.
 func (s *Server) Shutdown() error {
 	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
 	defer cancel()
 
-	return s.server.Shutdown(ctx)
+	err := s.server.Shutdown(ctx)
+	s.cancel()
+	if err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.handlers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
@@ -0,0 +1,57 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/oustrix/ozon_journal/pkg/requestid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fieldsKey is the key of fields added to contexts by ContextWith.
+type fieldsKey struct{}
+
+// ContextWith returns a copy of ctx with fields added to records logged with the context,
+// so fields of a request don't have to be passed to every call.
+func ContextWith(ctx context.Context, args ...interface{}) context.Context {
+	fields, _ := ctx.Value(fieldsKey{}).([]interface{})
+
+	// The slice of the parent context must not be appended to, it may be shared by other contexts.
+	merged := make([]interface{}, 0, len(fields)+len(args))
+	merged = append(merged, fields...)
+	merged = append(merged, args...)
+
+	return context.WithValue(ctx, fieldsKey{}, merged)
+}
+
+// contextHandler adds request IDs, trace IDs and fields of contexts to records.
+type contextHandler struct {
+	slog.Handler
+}
+
+// Handle adds fields of the context to the record and passes it to the wrapped handler.
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := requestid.FromContext(ctx); id != "" {
+		r.AddAttrs(slog.String("requestID", id))
+	}
+
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		r.AddAttrs(slog.String("traceID", span.TraceID().String()))
+	}
+
+	if fields, ok := ctx.Value(fieldsKey{}).([]interface{}); ok {
+		r.Add(fields...)
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler.
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup implements slog.Handler.
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
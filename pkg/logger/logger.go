@@ -1,39 +1,116 @@
 package logger
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"strings"
+	"time"
+)
+
+// Formats of records.
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+const (
+	_defaultFormat = FormatJSON
+	_samplingTick  = time.Second
 )
 
 // Logger is a wrapper around library logger.
 type Logger struct {
-	log *slog.Logger
+	log   *slog.Logger
+	level *slog.LevelVar // shared by the logger and its children
+
+	format             string
+	output             io.Writer
+	samplingInitial    int
+	samplingThereafter int
 }
 
 // New creates a new Logger instance.
 // If there is no such level, the default level is info.
-func New(level string) *Logger {
-	var l slog.Level
+func New(level string, opts ...Option) *Logger {
+	l := &Logger{
+		level:  new(slog.LevelVar),
+		format: _defaultFormat,
+		output: os.Stdout,
+	}
+
+	// Custom options
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		lvl = slog.LevelInfo
+	}
+	l.level.Set(lvl)
+
+	handlerOpts := &slog.HandlerOptions{Level: l.level}
+
+	var handler slog.Handler
+	if l.format == FormatText {
+		handler = slog.NewTextHandler(l.output, handlerOpts)
+	} else {
+		handler = slog.NewJSONHandler(l.output, handlerOpts)
+	}
+
+	if l.samplingInitial > 0 {
+		handler = &samplingHandler{
+			Handler: handler,
+			sampler: newSampler(l.samplingInitial, l.samplingThereafter, _samplingTick),
+		}
+	}
+
+	l.log = slog.New(contextHandler{handler})
 
+	return l
+}
+
+// ParseLevel converts a name of a level to the level.
+func ParseLevel(level string) (slog.Level, error) {
 	switch strings.ToLower(level) {
 	case "debug":
-		l = slog.LevelDebug
+		return slog.LevelDebug, nil
 	case "info":
-		l = slog.LevelInfo
+		return slog.LevelInfo, nil
 	case "warn":
-		l = slog.LevelWarn
+		return slog.LevelWarn, nil
 	case "error":
-		l = slog.LevelError
+		return slog.LevelError, nil
 	default:
-		l = slog.LevelInfo
+		return 0, fmt.Errorf("unknown level %q", level)
 	}
+}
+
+// With returns a child logger adding the arguments to every record. Children share the level of their parent.
+func (l *Logger) With(args ...interface{}) *Logger {
+	child := *l
+	child.log = l.log.With(args...)
+	return &child
+}
+
+// Level returns the name of the current level.
+func (l *Logger) Level() string {
+	return strings.ToLower(l.level.Level().String())
+}
 
-	opts := &slog.HandlerOptions{Level: l}
+// SetLevel changes the level of the logger, its parents and its children.
+func (l *Logger) SetLevel(level string) error {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
 
-	log := slog.New(slog.NewJSONHandler(os.Stdout, opts))
+	l.level.Set(lvl)
 
-	return &Logger{log: log}
+	return nil
 }
 
 // Debug logs a message at the debug level.
@@ -55,3 +132,23 @@ func (l *Logger) Warn(msg string, args ...interface{}) {
 func (l *Logger) Error(msg string, args ...interface{}) {
 	l.log.Error(msg, args...)
 }
+
+// DebugContext logs a message at the debug level with the fields of the context.
+func (l *Logger) DebugContext(ctx context.Context, msg string, args ...interface{}) {
+	l.log.DebugContext(ctx, msg, args...)
+}
+
+// InfoContext logs a message at the info level with the fields of the context.
+func (l *Logger) InfoContext(ctx context.Context, msg string, args ...interface{}) {
+	l.log.InfoContext(ctx, msg, args...)
+}
+
+// WarnContext logs a message at the warn level with the fields of the context.
+func (l *Logger) WarnContext(ctx context.Context, msg string, args ...interface{}) {
+	l.log.WarnContext(ctx, msg, args...)
+}
+
+// ErrorContext logs a message at the error level with the fields of the context.
+func (l *Logger) ErrorContext(ctx context.Context, msg string, args ...interface{}) {
+	l.log.ErrorContext(ctx, msg, args...)
+}
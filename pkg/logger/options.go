@@ -0,0 +1,29 @@
+package logger
+
+import "io"
+
+// Option allows for managing logger options.
+type Option func(*Logger)
+
+// Format sets the format of records: FormatJSON or FormatText.
+func Format(format string) Option {
+	return func(l *Logger) {
+		l.format = format
+	}
+}
+
+// Output sets the writer of records.
+func Output(w io.Writer) Option {
+	return func(l *Logger) {
+		l.output = w
+	}
+}
+
+// Sampling limits debug records with the same message to the first records of every second
+// and every thereafter-th record after them. Zero initial disables sampling.
+func Sampling(initial, thereafter int) Option {
+	return func(l *Logger) {
+		l.samplingInitial = initial
+		l.samplingThereafter = thereafter
+	}
+}
@@ -0,0 +1,74 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// sampler counts records by messages within ticks. It is shared by handlers of a logger and its children.
+type sampler struct {
+	initial    uint64
+	thereafter uint64
+	tick       time.Duration
+
+	mu     sync.Mutex
+	reset  time.Time
+	counts map[string]uint64
+}
+
+func newSampler(initial, thereafter int, tick time.Duration) *sampler {
+	return &sampler{
+		initial:    uint64(initial),
+		thereafter: uint64(thereafter),
+		tick:       tick,
+		counts:     make(map[string]uint64),
+	}
+}
+
+// allow reports whether a record with the message should be logged.
+func (s *sampler) allow(msg string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.reset) >= s.tick {
+		s.reset = now
+		clear(s.counts)
+	}
+
+	s.counts[msg]++
+	n := s.counts[msg]
+
+	if n <= s.initial {
+		return true
+	}
+
+	return s.thereafter > 0 && (n-s.initial)%s.thereafter == 0
+}
+
+// samplingHandler drops debug records exceeding the limits of the sampler. Records of other levels
+// are rare and important, so they are always passed.
+type samplingHandler struct {
+	slog.Handler
+	sampler *sampler
+}
+
+// Handle passes the record to the wrapped handler if the sampler allows it.
+func (h *samplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level <= slog.LevelDebug && !h.sampler.allow(r.Message, r.Time) {
+		return nil
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &samplingHandler{Handler: h.Handler.WithAttrs(attrs), sampler: h.sampler}
+}
+
+// WithGroup implements slog.Handler.
+func (h *samplingHandler) WithGroup(name string) slog.Handler {
+	return &samplingHandler{Handler: h.Handler.WithGroup(name), sampler: h.sampler}
+}
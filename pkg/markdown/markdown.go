@@ -0,0 +1,68 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// Renderer converts user content to HTML that is safe to insert into a page.
+type Renderer struct {
+	md     goldmark.Markdown
+	policy *bluemonday.Policy
+}
+
+// New creates a new Renderer instance.
+// Raw HTML in Markdown is dropped by the renderer, and the result is additionally passed through
+// an allowlist sanitizer, so only formatting tags and safe links get to the output.
+func New() *Renderer {
+	policy := bluemonday.UGCPolicy()
+	policy.RequireNoFollowOnLinks(true)
+	policy.AddTargetBlankToFullyQualifiedLinks(true)
+
+	return &Renderer{
+		md:     goldmark.New(goldmark.WithExtensions(extension.GFM)),
+		policy: policy,
+	}
+}
+
+// Markdown renders Markdown text to sanitized HTML.
+func (r *Renderer) Markdown(text string) (string, error) {
+	var buf bytes.Buffer
+
+	err := r.md.Convert([]byte(text), &buf)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert markdown: %w", err)
+	}
+
+	return r.policy.Sanitize(buf.String()), nil
+}
+
+// Plain renders plain text to HTML. Paragraphs are separated by blank lines, single line breaks are kept.
+func (r *Renderer) Plain(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	var buf strings.Builder
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		paragraph = strings.Trim(paragraph, "\n")
+		if strings.TrimSpace(paragraph) == "" {
+			continue
+		}
+
+		lines := strings.Split(paragraph, "\n")
+		for i, line := range lines {
+			lines[i] = html.EscapeString(line)
+		}
+
+		buf.WriteString("<p>")
+		buf.WriteString(strings.Join(lines, "<br>\n"))
+		buf.WriteString("</p>\n")
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,83 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdownSanitizes(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		// forbidden must not appear in the output, wanted must.
+		forbidden []string
+		wanted    []string
+	}{
+		{name: "script tag", text: "<script>alert(1)</script>", forbidden: []string{"<script", "alert(1)</script>"}},
+		{name: "inline script tag", text: "text <script>alert(1)</script> text", forbidden: []string{"<script"}},
+		{name: "event handler", text: `<img src="x" onerror="alert(1)">`, forbidden: []string{"onerror"}},
+		{name: "javascript link", text: "[click](javascript:alert(1))", forbidden: []string{"javascript:"}},
+		{name: "encoded javascript link", text: "[click](jav&#x61;script:alert(1))", forbidden: []string{"script:"}},
+		{name: "data link", text: "[click](data:text/html;base64,PHNjcmlwdD4=)", forbidden: []string{"data:"}},
+		{name: "javascript image", text: "![x](javascript:alert(1))", forbidden: []string{"javascript:"}},
+		{name: "iframe", text: `<iframe src="https://example.com"></iframe>`, forbidden: []string{"<iframe"}},
+		{name: "style attribute", text: `<p style="background:url(javascript:alert(1))">x</p>`, forbidden: []string{"style="}},
+		{name: "autolink", text: "<javascript:alert(1)>", forbidden: []string{`href="javascript:`}},
+		{
+			name:   "safe link",
+			text:   "[site](https://example.com)",
+			wanted: []string{`href="https://example.com"`, `rel="nofollow noopener"`, `target="_blank"`},
+		},
+		{
+			name:   "formatting",
+			text:   "**bold** _italic_ `code` ~~strike~~",
+			wanted: []string{"<strong>bold</strong>", "<em>italic</em>", "<code>code</code>", "<del>strike</del>"},
+		},
+	}
+
+	r := New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html, err := r.Markdown(tt.text)
+			if err != nil {
+				t.Fatalf("Markdown() error = %v", err)
+			}
+
+			for _, s := range tt.forbidden {
+				if strings.Contains(html, s) {
+					t.Errorf("Markdown() = %q, contains %q", html, s)
+				}
+			}
+			for _, s := range tt.wanted {
+				if !strings.Contains(html, s) {
+					t.Errorf("Markdown() = %q, doesn't contain %q", html, s)
+				}
+			}
+		})
+	}
+}
+
+func TestPlain(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "empty", text: "", want: ""},
+		{name: "paragraph", text: "text", want: "<p>text</p>\n"},
+		{name: "line breaks", text: "a\nb\r\nc", want: "<p>a<br>\nb<br>\nc</p>\n"},
+		{name: "paragraphs", text: "a\n\n\n\nb\n", want: "<p>a</p>\n<p>b</p>\n"},
+		{name: "html is escaped", text: `<script>alert("x")</script>`,
+			want: "<p>&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;</p>\n"},
+		{name: "markdown is kept", text: "**bold**", want: "<p>**bold**</p>\n"},
+	}
+
+	r := New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.Plain(tt.text); got != tt.want {
+				t.Errorf("Plain() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
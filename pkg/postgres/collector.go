@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// poolCollector exports statistics of the connection pool. They are read on every scrape.
+type poolCollector struct {
+	pg *Postgres
+
+	totalConns        *prometheus.Desc
+	acquiredConns     *prometheus.Desc
+	idleConns         *prometheus.Desc
+	constructingConns *prometheus.Desc
+	maxConns          *prometheus.Desc
+	acquires          *prometheus.Desc
+	canceledAcquires  *prometheus.Desc
+	emptyAcquires     *prometheus.Desc
+	acquireDuration   *prometheus.Desc
+}
+
+// Collector returns a Prometheus collector of connection pool statistics with names prefixed by namespace.
+func (p *Postgres) Collector(namespace string) prometheus.Collector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "postgres_pool", name), help, nil, nil)
+	}
+
+	return &poolCollector{
+		pg:                p,
+		totalConns:        desc("total_connections", "Number of connections in the pool."),
+		acquiredConns:     desc("acquired_connections", "Number of connections in use."),
+		idleConns:         desc("idle_connections", "Number of idle connections."),
+		constructingConns: desc("constructing_connections", "Number of connections being established."),
+		maxConns:          desc("max_connections", "Maximum size of the pool."),
+		acquires:          desc("acquires_total", "Number of successful acquires of connections."),
+		canceledAcquires:  desc("canceled_acquires_total", "Number of acquires canceled by contexts."),
+		emptyAcquires:     desc("empty_acquires_total", "Number of acquires that waited for a connection because the pool was empty."),
+		acquireDuration:   desc("acquire_duration_seconds_total", "Total time spent acquiring connections."),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.totalConns
+	ch <- c.acquiredConns
+	ch <- c.idleConns
+	ch <- c.constructingConns
+	ch <- c.maxConns
+	ch <- c.acquires
+	ch <- c.canceledAcquires
+	ch <- c.emptyAcquires
+	ch <- c.acquireDuration
+}
+
+// Collect implements prometheus.Collector.
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pg.Pool.Stat()
+
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.constructingConns, prometheus.GaugeValue, float64(stat.ConstructingConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquires, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquires, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquires, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+}
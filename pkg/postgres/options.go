@@ -1,6 +1,10 @@
 package postgres
 
-import "time"
+import (
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
 
 // Option allows for managing postgres options.
 type Option func(*Postgres)
@@ -25,3 +29,10 @@ func ConnTimeout(timeout time.Duration) Option {
 		c.connTimeout = timeout
 	}
 }
+
+// TracerProvider enables spans of queries with their SQL statements. Arguments aren't recorded.
+func TracerProvider(provider trace.TracerProvider) Option {
+	return func(c *Postgres) {
+		c.tracerProvider = provider
+	}
+}
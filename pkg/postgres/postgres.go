@@ -6,7 +6,9 @@ import (
 	"time"
 
 	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -21,6 +23,8 @@ type Postgres struct {
 	connAttempts int
 	connTimeout  time.Duration
 
+	tracerProvider trace.TracerProvider
+
 	Builder squirrel.StatementBuilderType
 	Pool    *pgxpool.Pool
 }
@@ -47,6 +51,11 @@ func New(url string, opts ...Option) (*Postgres, error) {
 
 	poolConfig.MaxConns = int32(pg.maxPoolSize)
 
+	if pg.tracerProvider != nil {
+		poolConfig.ConnConfig.Logger = &queryTracer{tracer: pg.tracerProvider.Tracer(tracerName)}
+		poolConfig.ConnConfig.LogLevel = pgx.LogLevelInfo
+	}
+
 	for pg.connAttempts > 0 {
 		pg.Pool, err = pgxpool.ConnectConfig(context.Background(), poolConfig)
 		if err == nil {
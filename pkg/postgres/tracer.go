@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/oustrix/ozon_journal/pkg/postgres"
+
+// queryTracer creates spans of queries from pgx logs. pgx logs a query with its duration when it completes,
+// so the span is started back in time.
+type queryTracer struct {
+	tracer trace.Tracer
+}
+
+// Log implements pgx.Logger.
+func (t *queryTracer) Log(ctx context.Context, _ pgx.LogLevel, msg string, data map[string]interface{}) {
+	if msg != "Query" && msg != "Exec" {
+		return
+	}
+
+	// Queries outside of traces, like the ones of background jobs, aren't recorded.
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return
+	}
+
+	sql, _ := data["sql"].(string)
+	elapsed, _ := data["time"].(time.Duration)
+	end := time.Now()
+
+	operation := sql
+	if i := strings.IndexAny(sql, " \t\n"); i >= 0 {
+		operation = sql[:i]
+	}
+	operation = strings.ToUpper(operation)
+
+	_, span := t.tracer.Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(end.Add(-elapsed)),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(sql),
+		))
+
+	if err, ok := data["err"].(error); ok {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End(trace.WithTimestamp(end))
+}
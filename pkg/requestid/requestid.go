@@ -0,0 +1,64 @@
+package requestid
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// Header is the header carrying request IDs in requests and responses.
+const Header = "X-Request-ID"
+
+// maxLength limits IDs accepted from clients, so they can't flood logs.
+const maxLength = 128
+
+// ctxKey is the key of request IDs in contexts.
+type ctxKey struct{}
+
+// New generates a new request ID.
+func New() string {
+	return uuid.NewString()
+}
+
+// WithID returns a copy of ctx carrying the request ID.
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext returns the request ID of ctx or an empty string if there is none.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+// Valid reports whether an ID received from a client may be used. IDs must be short
+// and consist of printable ASCII characters.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Middleware takes the request ID from the X-Request-ID header of a request or generates a new one,
+// adds it to the context of the request and returns it in the header of the response.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if !Valid(id) {
+			id = New()
+		}
+
+		w.Header().Set(Header, id)
+
+		next.ServeHTTP(w, r.WithContext(WithID(r.Context(), id)))
+	})
+}
@@ -0,0 +1,19 @@
+package s3
+
+// Option allows for managing S3 client options.
+type Option func(*Client)
+
+// Region sets the region used for signing requests.
+func Region(region string) Option {
+	return func(c *Client) {
+		c.region = region
+	}
+}
+
+// Credentials sets the access key pair used for signing requests.
+func Credentials(accessKey, secretKey string) Option {
+	return func(c *Client) {
+		c.accessKey = accessKey
+		c.secretKey = secretKey
+	}
+}
@@ -0,0 +1,150 @@
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const _defaultRegion = "us-east-1"
+
+// ErrNotFound is returned when an object doesn't exist.
+var ErrNotFound = errors.New("object not found")
+
+// Client is a minimal client of S3-compatible object storages (AWS S3, MinIO, Ceph, etc.).
+// It uses path-style URLs, so the bucket doesn't have to be resolvable as a subdomain of the endpoint.
+// Requests are limited by deadlines of their contexts only, so reading of large objects isn't cut off
+// by a timeout of the client.
+type Client struct {
+	endpoint  *url.URL
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+	http      *http.Client
+}
+
+// New creates a new Client instance for a bucket on the endpoint, e.g. "http://localhost:9000".
+func New(endpoint, bucket string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("s3 - New - url.Parse: %w", err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("s3 - New - invalid endpoint %q", endpoint)
+	}
+
+	if bucket == "" {
+		return nil, fmt.Errorf("s3 - New - bucket is empty")
+	}
+
+	c := &Client{
+		endpoint: u,
+		bucket:   bucket,
+		region:   _defaultRegion,
+		http:     &http.Client{},
+	}
+
+	// Custom options
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// PutObject uploads an object of the given size.
+func (c *Client) PutObject(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+
+	resp, err := c.do(ctx, http.MethodPut, key, body, size, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return nil
+}
+
+// GetObject returns the content of an object. The caller must close it.
+func (c *Client) GetObject(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := c.do(ctx, http.MethodGet, key, nil, 0, http.Header{})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+// DeleteObject deletes an object. Deleting a missing object isn't an error.
+func (c *Client) DeleteObject(ctx context.Context, key string) error {
+	resp, err := c.do(ctx, http.MethodDelete, key, nil, 0, http.Header{})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return nil
+}
+
+// do sends a signed request for an object and returns the response if it succeeded.
+func (c *Client) do(ctx context.Context, method, key string, body io.Reader, size int64, header http.Header) (*http.Response, error) {
+	u := *c.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + c.bucket + "/" + key
+	u.RawPath = escapePath(u.Path)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("s3 - failed to create request: %w", err)
+	}
+
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+
+	if c.accessKey != "" {
+		sign(req, c.accessKey, c.secretKey, c.region, time.Now())
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3 - failed to send request: %w", err)
+	}
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+
+	return resp, nil
+}
+
+// responseError converts an unsuccessful response to an error, using the S3 error code if the body has it.
+func responseError(resp *http.Response) error {
+	var body struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	_ = xml.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body)
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+
+	if body.Code != "" {
+		return fmt.Errorf("s3 - %s: %s: %s", resp.Status, body.Code, body.Message)
+	}
+
+	return fmt.Errorf("s3 - %s", resp.Status)
+}
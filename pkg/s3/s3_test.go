@@ -0,0 +1,225 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordedRequest is a request received by the test server.
+type recordedRequest struct {
+	method  string
+	path    string
+	header  http.Header
+	content string
+}
+
+// newServer starts a server recording requests and responding with the handler.
+func newServer(t *testing.T, handler http.HandlerFunc) (*httptest.Server, *[]recordedRequest) {
+	t.Helper()
+
+	requests := make([]recordedRequest, 0)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, _ := io.ReadAll(r.Body)
+		requests = append(requests, recordedRequest{
+			method:  r.Method,
+			path:    r.URL.EscapedPath(),
+			header:  r.Header.Clone(),
+			content: string(content),
+		})
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &requests
+}
+
+func newClient(t *testing.T, endpoint string, opts ...Option) *Client {
+	t.Helper()
+
+	c, err := New(endpoint, "journal", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		bucket   string
+		wantErr  bool
+	}{
+		{name: "http", endpoint: "http://localhost:9000", bucket: "journal"},
+		{name: "https with path", endpoint: "https://storage.example.com/s3/", bucket: "journal"},
+		{name: "no scheme", endpoint: "localhost:9000", bucket: "journal", wantErr: true},
+		{name: "unsupported scheme", endpoint: "ftp://localhost", bucket: "journal", wantErr: true},
+		{name: "empty bucket", endpoint: "http://localhost:9000", bucket: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.endpoint, tt.bucket)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPutObject(t *testing.T) {
+	srv, requests := newServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	c := newClient(t, srv.URL, Credentials("access", "secret"), Region("eu-central-1"))
+
+	err := c.PutObject(context.Background(), "posts/1/a b.txt", strings.NewReader("hello"), 5, "text/plain")
+	if err != nil {
+		t.Fatalf("PutObject() error = %v", err)
+	}
+
+	if len(*requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(*requests))
+	}
+	req := (*requests)[0]
+
+	if req.method != http.MethodPut {
+		t.Errorf("method = %q, want %q", req.method, http.MethodPut)
+	}
+	if req.path != "/journal/posts/1/a%20b.txt" {
+		t.Errorf("path = %q, want %q", req.path, "/journal/posts/1/a%20b.txt")
+	}
+	if req.content != "hello" {
+		t.Errorf("content = %q, want %q", req.content, "hello")
+	}
+	if got := req.header.Get("Content-Type"); got != "text/plain" {
+		t.Errorf("Content-Type = %q, want %q", got, "text/plain")
+	}
+	if got := req.header.Get("X-Amz-Content-Sha256"); got != unsignedPayload {
+		t.Errorf("X-Amz-Content-Sha256 = %q, want %q", got, unsignedPayload)
+	}
+
+	auth := req.header.Get("Authorization")
+	wantPrefix := "AWS4-HMAC-SHA256 Credential=access/" + time.Now().UTC().Format("20060102") + "/eu-central-1/s3/aws4_request, " +
+		"SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date, Signature="
+	if !strings.HasPrefix(auth, wantPrefix) {
+		t.Errorf("Authorization = %q, want prefix %q", auth, wantPrefix)
+	}
+}
+
+func TestGetObject(t *testing.T) {
+	srv, requests := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("content"))
+	})
+	c := newClient(t, srv.URL+"/prefix/")
+
+	body, err := c.GetObject(context.Background(), "key")
+	if err != nil {
+		t.Fatalf("GetObject() error = %v", err)
+	}
+	defer body.Close()
+
+	content, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "content" {
+		t.Errorf("content = %q, want %q", content, "content")
+	}
+
+	req := (*requests)[0]
+	if req.method != http.MethodGet || req.path != "/prefix/journal/key" {
+		t.Errorf("request = %s %s, want GET /prefix/journal/key", req.method, req.path)
+	}
+	// Requests aren't signed without credentials.
+	if got := req.header.Get("Authorization"); got != "" {
+		t.Errorf("Authorization = %q, want none", got)
+	}
+}
+
+func TestDeleteObject(t *testing.T) {
+	srv, requests := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	c := newClient(t, srv.URL)
+
+	err := c.DeleteObject(context.Background(), "key")
+	if err != nil {
+		t.Fatalf("DeleteObject() error = %v", err)
+	}
+
+	if req := (*requests)[0]; req.method != http.MethodDelete || req.path != "/journal/key" {
+		t.Errorf("request = %s %s, want DELETE /journal/key", req.method, req.path)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr error
+		wantMsg string
+	}{
+		{
+			name:    "not found",
+			status:  http.StatusNotFound,
+			body:    "<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>",
+			wantErr: ErrNotFound,
+		},
+		{
+			name:    "error with code",
+			status:  http.StatusForbidden,
+			body:    "<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>",
+			wantMsg: "s3 - 403 Forbidden: AccessDenied: Access Denied",
+		},
+		{
+			name:    "error without body",
+			status:  http.StatusInternalServerError,
+			wantMsg: "s3 - 500 Internal Server Error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, _ := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+			c := newClient(t, srv.URL)
+
+			_, err := c.GetObject(context.Background(), "key")
+			if err == nil {
+				t.Fatal("GetObject() error = nil")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetObject() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantMsg != "" && err.Error() != tt.wantMsg {
+				t.Errorf("GetObject() error = %q, want %q", err.Error(), tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestContextDeadline(t *testing.T) {
+	release := make(chan struct{})
+	srv, _ := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})
+	defer close(release)
+	c := newClient(t, srv.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := c.DeleteObject(ctx, "key")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("DeleteObject() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
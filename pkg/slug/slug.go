@@ -0,0 +1,88 @@
+package slug
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxLength is the maximum length of a slug in bytes, including a uniqueness suffix.
+const MaxLength = 80
+
+// fallback is used when nothing is left of a title after transliteration.
+const fallback = "post"
+
+// transliteration maps letters that don't decompose to Latin ones. Cyrillic letters follow
+// the common Russian and Ukrainian romanization used in URLs.
+var transliteration = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo", 'ж': "zh", 'з': "z", 'и': "i",
+	'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t",
+	'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "",
+	'э': "e", 'ю': "yu", 'я': "ya", 'і': "i", 'ї': "yi", 'є': "ye", 'ґ': "g", 'ў': "u",
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'ł': "l", 'đ': "d", 'ð': "d", 'þ': "th", 'ı': "i",
+}
+
+// Make creates a slug from a title: lowercase Latin letters and digits separated by single hyphens.
+func Make(title string) string {
+	var b strings.Builder
+
+	for _, r := range strings.ToLower(title) {
+		if latin, ok := transliteration[r]; ok {
+			b.WriteString(latin)
+			continue
+		}
+
+		// Decomposition splits a letter with diacritics into a base letter and combining marks, the marks are dropped.
+		// Letters like "й" are transliterated above, because their marks change the sound.
+		for _, d := range norm.NFKD.String(string(r)) {
+			switch {
+			case unicode.Is(unicode.Mn, d):
+			case d < unicode.MaxASCII && (unicode.IsLetter(d) || unicode.IsDigit(d)):
+				b.WriteRune(unicode.ToLower(d))
+			default:
+				b.WriteByte('-')
+			}
+		}
+	}
+
+	s := collapse(b.String())
+	s = truncate(s, MaxLength)
+	if s == "" {
+		return fallback
+	}
+
+	return s
+}
+
+// WithSuffix returns the n-th candidate for a unique slug: the slug itself for the first one
+// and the slug with a numeric suffix for the next ones, so "title", "title-2", "title-3", etc.
+func WithSuffix(slug string, n int) string {
+	if n <= 1 {
+		return slug
+	}
+
+	suffix := "-" + strconv.Itoa(n)
+	return truncate(slug, MaxLength-len(suffix)) + suffix
+}
+
+// collapse replaces runs of hyphens with single ones and trims them at the ends.
+func collapse(s string) string {
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == '-' })
+	return strings.Join(parts, "-")
+}
+
+// truncate cuts a slug to the length, preferring to cut at a word boundary.
+func truncate(s string, length int) string {
+	if len(s) <= length {
+		return s
+	}
+
+	s = s[:length]
+	if i := strings.LastIndexByte(s, '-'); i > length/2 {
+		s = s[:i]
+	}
+
+	return strings.TrimRight(s, "-")
+}
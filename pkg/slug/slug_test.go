@@ -0,0 +1,96 @@
+package slug
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		want  string
+	}{
+		{name: "latin", title: "Hello, World!", want: "hello-world"},
+		{name: "digits", title: "Top 10 Go tips", want: "top-10-go-tips"},
+		{name: "russian", title: "Привет, мир", want: "privet-mir"},
+		{name: "russian digraphs", title: "Щука, жёлтый чай и юла", want: "shchuka-zhyoltyy-chay-i-yula"},
+		{name: "hard and soft signs", title: "Подъезд и мышь", want: "podezd-i-mysh"},
+		{name: "ukrainian", title: "Їжак і ґанок, Європа", want: "yizhak-i-ganok-yevropa"},
+		{name: "belarusian", title: "Ўсё", want: "usyo"},
+		{name: "diacritics", title: "Crème brûlée à la française", want: "creme-brulee-a-la-francaise"},
+		{name: "special letters", title: "Straße Ærø Łódź", want: "strasse-aero-lodz"},
+		{name: "mixed scripts", title: "Go и Postgres", want: "go-i-postgres"},
+		{name: "punctuation runs", title: "--- a --- b ---", want: "a-b"},
+		{name: "compatibility forms", title: "ﬁle №1", want: "file-no1"},
+		{name: "nothing left", title: "!!! ???", want: "post"},
+		{name: "empty", title: "", want: "post"},
+		{name: "other scripts", title: "日本語", want: "post"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Make(tt.title); got != tt.want {
+				t.Errorf("Make(%q) = %q, want %q", tt.title, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMakeTruncates(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		want  string
+	}{
+		{name: "cut at a word boundary", title: strings.Repeat("word ", 20),
+			want: strings.TrimSuffix(strings.Repeat("word-", 16), "-")},
+		{name: "long word", title: strings.Repeat("a", 100), want: strings.Repeat("a", MaxLength)},
+		{name: "boundary too early", title: "a " + strings.Repeat("b", 100), want: "a-" + strings.Repeat("b", MaxLength-2)},
+		// Cyrillic letters become several Latin ones, so the limit applies to the transliterated slug.
+		{name: "cyrillic", title: strings.Repeat("щ", 30), want: strings.Repeat("shch", MaxLength/4)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Make(tt.title)
+			if got != tt.want {
+				t.Errorf("Make() = %q, want %q", got, tt.want)
+			}
+			if len(got) > MaxLength {
+				t.Errorf("len(Make()) = %d, more than %d", len(got), MaxLength)
+			}
+		})
+	}
+}
+
+func TestWithSuffix(t *testing.T) {
+	long := strings.Repeat("word-", 15) + "last"
+
+	tests := []struct {
+		name string
+		slug string
+		n    int
+		want string
+	}{
+		{name: "first candidate", slug: "title", n: 1, want: "title"},
+		{name: "zero", slug: "title", n: 0, want: "title"},
+		{name: "second candidate", slug: "title", n: 2, want: "title-2"},
+		{name: "big number", slug: "title", n: 123, want: "title-123"},
+		{name: "long slug", slug: long, n: 2, want: strings.Repeat("word-", 15) + "2"},
+		{name: "long slug without hyphens", slug: strings.Repeat("a", MaxLength), n: 10,
+			want: strings.Repeat("a", MaxLength-3) + "-10"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := WithSuffix(tt.slug, tt.n)
+			if got != tt.want {
+				t.Errorf("WithSuffix(%q, %d) = %q, want %q", tt.slug, tt.n, got, tt.want)
+			}
+			if len(got) > MaxLength {
+				t.Errorf("len(WithSuffix()) = %d, more than %d", len(got), MaxLength)
+			}
+		})
+	}
+}
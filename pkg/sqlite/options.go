@@ -0,0 +1,22 @@
+package sqlite
+
+import (
+	"time"
+)
+
+// Option allows for managing sqlite options.
+type Option func(*SQLite)
+
+// MaxOpenConns sets the max number of open connections. Only one of them writes at a time.
+func MaxOpenConns(conns int) Option {
+	return func(s *SQLite) {
+		s.maxOpenConns = conns
+	}
+}
+
+// BusyTimeout sets how long a connection waits for the lock of the database held by another one.
+func BusyTimeout(timeout time.Duration) Option {
+	return func(s *SQLite) {
+		s.busyTimeout = timeout
+	}
+}
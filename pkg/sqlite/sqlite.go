@@ -0,0 +1,106 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	// database/sql driver
+	_ "modernc.org/sqlite"
+)
+
+const (
+	_defaultMaxOpenConns = 4
+	_defaultBusyTimeout  = 5 * time.Second
+)
+
+// SQLite is a struct that holds the database handle and the squirrel builder.
+type SQLite struct {
+	maxOpenConns int
+	busyTimeout  time.Duration
+
+	Builder squirrel.StatementBuilderType
+	DB      *sql.DB
+}
+
+// New opens the database file, creating it and its directory if they don't exist, and creates a new SQLite instance.
+// Foreign keys are enforced, and transactions take the write lock when they begin, so concurrent transactions
+// wait for each other for the busy timeout instead of failing when they start writing.
+func New(path string, opts ...Option) (*SQLite, error) {
+	s := &SQLite{
+		maxOpenConns: _defaultMaxOpenConns,
+		busyTimeout:  _defaultBusyTimeout,
+	}
+
+	// Custom options
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.Builder = squirrel.StatementBuilder.PlaceholderFormat(squirrel.Question)
+
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite - New - os.MkdirAll: %w", err)
+	}
+
+	s.DB, err = sql.Open("sqlite", path+"?"+Params(s.busyTimeout)+"&_txlock=immediate")
+	if err != nil {
+		return nil, fmt.Errorf("sqlite - New - sql.Open: %w", err)
+	}
+
+	s.DB.SetMaxOpenConns(s.maxOpenConns)
+
+	err = s.DB.Ping()
+	if err != nil {
+		s.DB.Close()
+		return nil, fmt.Errorf("sqlite - New - db.Ping: %w", err)
+	}
+
+	return s, nil
+}
+
+// Params returns query parameters of the driver setting up connections the same way as New does,
+// e.g. for connections of migrations.
+func Params(busyTimeout time.Duration) string {
+	params := url.Values{}
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", busyTimeout.Milliseconds()))
+	params.Add("_pragma", "foreign_keys(1)")
+	// Readers don't block the writer and the writer doesn't block readers.
+	params.Add("_pragma", "journal_mode(WAL)")
+
+	return params.Encode()
+}
+
+// BeginFunc runs fn in a transaction. The transaction is committed if fn returns nil and rolled back otherwise.
+func (s *SQLite) BeginFunc(ctx context.Context, fn func(*sql.Tx) error) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	err = fn(tx)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Close closes the database.
+func (s *SQLite) Close() {
+	if s.DB != nil {
+		s.DB.Close()
+	}
+}
@@ -0,0 +1,227 @@
+package textdiff
+
+import (
+	"strings"
+	"unicode"
+)
+
+// maxEditDistance limits the work done for very different texts.
+// When it is exceeded, the old text is reported as deleted and the new one as inserted.
+const maxEditDistance = 1000
+
+// Operation is a kind of change of a text chunk.
+type Operation string
+
+const (
+	Equal  Operation = "equal"
+	Insert Operation = "insert"
+	Delete Operation = "delete"
+)
+
+// Chunk is a piece of text with the same kind of change.
+type Chunk struct {
+	Operation Operation
+	Text      string
+}
+
+// Lines returns the difference between two texts line by line.
+func Lines(a, b string) []Chunk {
+	return diff(splitLines(a), splitLines(b))
+}
+
+// Words returns the difference between two texts word by word. Whitespace is compared as separate tokens.
+func Words(a, b string) []Chunk {
+	return diff(splitWords(a), splitWords(b))
+}
+
+// splitLines splits a text into lines keeping line endings, so joined lines give the text back.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	lines := strings.SplitAfter(text, "\n")
+
+	// A text ending with a line break gives an empty last line, which isn't a line.
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// splitWords splits a text into runs of whitespace and non-whitespace characters.
+func splitWords(text string) []string {
+	tokens := make([]string, 0)
+
+	start := 0
+	prevSpace := false
+	for i, r := range text {
+		space := unicode.IsSpace(r)
+		if i > start && space != prevSpace {
+			tokens = append(tokens, text[start:i])
+			start = i
+		}
+		prevSpace = space
+	}
+
+	if start < len(text) {
+		tokens = append(tokens, text[start:])
+	}
+
+	return tokens
+}
+
+// diff returns the shortest edit script between token lists as chunks of merged tokens.
+func diff(a, b []string) []Chunk {
+	var chunks []Chunk
+	add := func(op Operation, tokens ...string) {
+		if len(tokens) == 0 {
+			return
+		}
+
+		text := strings.Join(tokens, "")
+		if len(chunks) > 0 && chunks[len(chunks)-1].Operation == op {
+			chunks[len(chunks)-1].Text += text
+			return
+		}
+		chunks = append(chunks, Chunk{Operation: op, Text: text})
+	}
+
+	// Common prefix and suffix are cut off, so the search works on the changed part only.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	add(Equal, a[:prefix]...)
+	for _, e := range myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]) {
+		add(e.op, e.token)
+	}
+	add(Equal, a[len(a)-suffix:]...)
+
+	if chunks == nil {
+		return []Chunk{}
+	}
+
+	return chunks
+}
+
+type edit struct {
+	op    Operation
+	token string
+}
+
+// myers finds the shortest edit script with the Myers algorithm.
+// See "An O(ND) Difference Algorithm and Its Variations", E. Myers, 1986.
+func myers(a, b []string) []edit {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil
+	}
+
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3) // v[offset+k] is the furthest x on diagonal k.
+
+	// trace[d] holds v for diagonals -d..d after d edits, it is used to restore the path.
+	trace := make([][]int, 0)
+
+	for d := 0; d <= max; d++ {
+		if d > maxEditDistance {
+			return replaceAll(a, b)
+		}
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+				return backtrack(trace, a, b)
+			}
+		}
+
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+	}
+
+	return replaceAll(a, b)
+}
+
+// backtrack restores the edit script from the end of the path to its start.
+func backtrack(trace [][]int, a, b []string) []edit {
+	x, y := len(a), len(b)
+	edits := make([]edit, 0)
+
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d-1]
+		get := func(k int) int { return prev[k+d-1] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && get(k-1) < get(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+
+		prevX := get(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			edits = append(edits, edit{op: Equal, token: a[x-1]})
+			x--
+			y--
+		}
+
+		if x == prevX {
+			edits = append(edits, edit{op: Insert, token: b[y-1]})
+			y--
+		} else {
+			edits = append(edits, edit{op: Delete, token: a[x-1]})
+			x--
+		}
+	}
+
+	for x > 0 && y > 0 {
+		edits = append(edits, edit{op: Equal, token: a[x-1]})
+		x--
+		y--
+	}
+
+	// Edits were collected from the end.
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+
+	return edits
+}
+
+// replaceAll returns an edit script deleting all tokens of a and inserting all tokens of b.
+func replaceAll(a, b []string) []edit {
+	edits := make([]edit, 0, len(a)+len(b))
+	for _, token := range a {
+		edits = append(edits, edit{op: Delete, token: token})
+	}
+	for _, token := range b {
+		edits = append(edits, edit{op: Insert, token: token})
+	}
+
+	return edits
+}
@@ -0,0 +1,139 @@
+package textdiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// apply returns the old and the new text restored from chunks.
+func apply(chunks []Chunk) (string, string) {
+	var a, b strings.Builder
+	for _, chunk := range chunks {
+		if chunk.Operation != Insert {
+			a.WriteString(chunk.Text)
+		}
+		if chunk.Operation != Delete {
+			b.WriteString(chunk.Text)
+		}
+	}
+
+	return a.String(), b.String()
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []Chunk
+	}{
+		{name: "empty texts", want: []Chunk{}},
+		{name: "same texts", a: "a\nb\n", b: "a\nb\n", want: []Chunk{{Equal, "a\nb\n"}}},
+		{name: "inserted text", b: "a\n", want: []Chunk{{Insert, "a\n"}}},
+		{name: "deleted text", a: "a\n", want: []Chunk{{Delete, "a\n"}}},
+		{
+			name: "changed line",
+			a:    "a\nb\nc\n",
+			b:    "a\nx\nc\n",
+			want: []Chunk{{Equal, "a\n"}, {Delete, "b\n"}, {Insert, "x\n"}, {Equal, "c\n"}},
+		},
+		{
+			name: "inserted line",
+			a:    "a\nc\n",
+			b:    "a\nb\nc\n",
+			want: []Chunk{{Equal, "a\n"}, {Insert, "b\n"}, {Equal, "c\n"}},
+		},
+		{
+			name: "deleted lines",
+			a:    "a\nb\nc\nd\n",
+			b:    "a\nd\n",
+			want: []Chunk{{Equal, "a\n"}, {Delete, "b\nc\n"}, {Equal, "d\n"}},
+		},
+		{
+			name: "moved line",
+			a:    "a\nb\nc\n",
+			b:    "b\nc\na\n",
+			want: []Chunk{{Delete, "a\n"}, {Equal, "b\nc\n"}, {Insert, "a\n"}},
+		},
+		{
+			name: "last line without line break",
+			a:    "a\nb",
+			b:    "a\nb\n",
+			want: []Chunk{{Equal, "a\n"}, {Delete, "b"}, {Insert, "b\n"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Lines(tt.a, tt.b)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lines() = %q, want %q", got, tt.want)
+			}
+
+			if a, b := apply(got); a != tt.a || b != tt.b {
+				t.Errorf("chunks give %q and %q, want %q and %q", a, b, tt.a, tt.b)
+			}
+		})
+	}
+}
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []Chunk
+	}{
+		{name: "empty texts", want: []Chunk{}},
+		{
+			name: "changed word",
+			a:    "the quick fox",
+			b:    "the slow fox",
+			want: []Chunk{{Equal, "the "}, {Delete, "quick"}, {Insert, "slow"}, {Equal, " fox"}},
+		},
+		{
+			name: "inserted words",
+			a:    "the fox",
+			b:    "the quick brown fox",
+			want: []Chunk{{Equal, "the "}, {Insert, "quick brown "}, {Equal, "fox"}},
+		},
+		{
+			name: "changed whitespace",
+			a:    "a b",
+			b:    "a\tb",
+			want: []Chunk{{Equal, "a"}, {Delete, " "}, {Insert, "\t"}, {Equal, "b"}},
+		},
+		{
+			name: "cyrillic words",
+			a:    "привет мир",
+			b:    "привет новый мир",
+			want: []Chunk{{Equal, "привет "}, {Insert, "новый "}, {Equal, "мир"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Words(tt.a, tt.b)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Words() = %q, want %q", got, tt.want)
+			}
+
+			if a, b := apply(got); a != tt.a || b != tt.b {
+				t.Errorf("chunks give %q and %q, want %q and %q", a, b, tt.a, tt.b)
+			}
+		})
+	}
+}
+
+func TestDiffTooManyEdits(t *testing.T) {
+	// Texts differing in every line exceed the edit distance limit, so the whole text is replaced.
+	var a, b strings.Builder
+	for i := 0; i < maxEditDistance; i++ {
+		a.WriteString("a\n")
+		b.WriteString("b\n")
+	}
+
+	want := []Chunk{{Delete, a.String()}, {Insert, b.String()}}
+	if got := Lines(a.String(), b.String()); !reflect.DeepEqual(got, want) {
+		t.Errorf("Lines() returned %d chunks, want the text replaced", len(got))
+	}
+}
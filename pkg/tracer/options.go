@@ -0,0 +1,41 @@
+package tracer
+
+import "io"
+
+// Option allows for managing tracer options.
+type Option func(*Tracer)
+
+// Exporter sets the exporter of spans: ExporterNone, ExporterStdout or ExporterOTLP.
+func Exporter(exporter string) Option {
+	return func(t *Tracer) {
+		t.exporter = exporter
+	}
+}
+
+// Endpoint sets the host and port of the OTLP collector.
+func Endpoint(endpoint string) Option {
+	return func(t *Tracer) {
+		t.endpoint = endpoint
+	}
+}
+
+// Insecure disables TLS of connections to the OTLP collector.
+func Insecure(insecure bool) Option {
+	return func(t *Tracer) {
+		t.insecure = insecure
+	}
+}
+
+// SampleRatio sets the share of traces that are sampled. Traces started by clients keep their decisions.
+func SampleRatio(ratio float64) Option {
+	return func(t *Tracer) {
+		t.sampleRatio = ratio
+	}
+}
+
+// Output sets the writer of the stdout exporter.
+func Output(w io.Writer) Option {
+	return func(t *Tracer) {
+		t.output = w
+	}
+}
@@ -0,0 +1,109 @@
+package tracer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters of spans.
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+const (
+	_defaultExporter    = ExporterNone
+	_defaultSampleRatio = 1
+)
+
+// Tracer manages the global OpenTelemetry tracer provider.
+type Tracer struct {
+	exporter    string
+	endpoint    string
+	insecure    bool
+	sampleRatio float64
+	output      io.Writer
+
+	provider *sdktrace.TracerProvider
+}
+
+// New creates a tracer provider exporting spans of the service and makes it global together
+// with the W3C trace context propagator. Tracers created with otel.Tracer use the provider.
+func New(serviceName string, opts ...Option) (*Tracer, error) {
+	t := &Tracer{
+		exporter:    _defaultExporter,
+		sampleRatio: _defaultSampleRatio,
+		output:      os.Stdout,
+	}
+
+	// Custom options
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, fmt.Errorf("tracer - New - resource.Merge: %w", err)
+	}
+
+	providerOpts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(t.sampleRatio))),
+	}
+
+	switch t.exporter {
+	case ExporterNone:
+		// Spans are created, so trace IDs are propagated, but they aren't exported.
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(t.output))
+		if err != nil {
+			return nil, fmt.Errorf("tracer - New - stdouttrace.New: %w", err)
+		}
+		// Spans are written right away, so they are in order with logs.
+		providerOpts = append(providerOpts, sdktrace.WithSyncer(exporter))
+	case ExporterOTLP:
+		clientOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(t.endpoint)}
+		if t.insecure {
+			clientOpts = append(clientOpts, otlptracegrpc.WithInsecure())
+		}
+
+		// The client connects lazily, so an unavailable collector doesn't prevent the start.
+		exporter, err := otlptracegrpc.New(context.Background(), clientOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("tracer - New - otlptracegrpc.New: %w", err)
+		}
+		providerOpts = append(providerOpts, sdktrace.WithBatcher(exporter))
+	default:
+		return nil, fmt.Errorf("tracer - New - unknown exporter %q", t.exporter)
+	}
+
+	t.provider = sdktrace.NewTracerProvider(providerOpts...)
+
+	otel.SetTracerProvider(t.provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return t, nil
+}
+
+// Shutdown exports buffered spans and stops the exporter.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	return t.provider.Shutdown(ctx)
+}
+
+// Provider returns the tracer provider.
+func (t *Tracer) Provider() trace.TracerProvider {
+	return t.provider
+}
@@ -0,0 +1,22 @@
+package wal
+
+import (
+	"time"
+)
+
+// Option allows for managing WAL options.
+type Option func(*WAL)
+
+// Sync sets when appended records are flushed to the disk.
+func Sync(policy SyncPolicy) Option {
+	return func(w *WAL) {
+		w.syncPolicy = policy
+	}
+}
+
+// Interval sets how often records are flushed with SyncInterval policy.
+func Interval(interval time.Duration) Option {
+	return func(w *WAL) {
+		w.syncInterval = interval
+	}
+}
@@ -0,0 +1,530 @@
+package wal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SyncPolicy defines when appended records are flushed to the disk with fsync.
+type SyncPolicy string
+
+const (
+	// SyncAlways flushes every record before Append returns. Appended records survive a crash of the machine.
+	SyncAlways SyncPolicy = "always"
+	// SyncInterval flushes records in the background, a crash of the machine loses records of the last interval.
+	SyncInterval SyncPolicy = "interval"
+	// SyncNever leaves flushing to the OS. Records survive a crash of the process, but not of the machine.
+	SyncNever SyncPolicy = "never"
+)
+
+const (
+	_defaultSyncPolicy   = SyncAlways
+	_defaultSyncInterval = time.Second
+
+	// headerSize is the size of the length of a record, the checksum of the length and the checksum of the data.
+	headerSize    = 12
+	segmentSuffix = ".wal"
+)
+
+// ErrCorrupted is returned by Open if a record in the middle of the log doesn't match its checksum.
+// A damaged last record is expected after a crash during a write, it is dropped instead.
+var ErrCorrupted = errors.New("wal: corrupted record")
+
+// ErrEmptyRecord is returned by Append for empty records. Zeros left by a crash would read as them.
+var ErrEmptyRecord = errors.New("wal: empty record")
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// WAL is an append-only log of records split into numbered segment files in a directory.
+// A record is framed with its length and CRC-32C checksums of the length and of the data, so a torn write
+// at the tail is detected, and a damaged length isn't taken for one.
+// Segments are rotated when the state they describe is saved elsewhere, e.g. in a snapshot,
+// and removed after that.
+type WAL struct {
+	dir          string
+	syncPolicy   SyncPolicy
+	syncInterval time.Duration
+
+	mu      sync.Mutex
+	file    *os.File
+	segment uint64
+	size    int64
+	dirty   bool
+	err     error // A failed write or fsync, the log doesn't accept records after it.
+
+	truncated int64
+	done      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// Open replays records of segments starting from the segment from, calling replay for every record in order,
+// and opens the last segment for appending. Segments before from are removed. The log is created
+// if the directory has no segments. If the last record is incomplete or damaged, it is truncated,
+// see Truncated.
+func Open(dir string, from uint64, replay func(data []byte) error, opts ...Option) (*WAL, error) {
+	w := &WAL{
+		dir:          dir,
+		syncPolicy:   _defaultSyncPolicy,
+		syncInterval: _defaultSyncInterval,
+		done:         make(chan struct{}),
+	}
+
+	// Custom options
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	switch w.syncPolicy {
+	case SyncAlways, SyncInterval, SyncNever:
+	default:
+		return nil, fmt.Errorf("wal: unknown sync policy %q", w.syncPolicy)
+	}
+
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("wal: failed to create directory: %w", err)
+	}
+
+	segments, err := w.segments()
+	if err != nil {
+		return nil, err
+	}
+
+	replayed := make([]uint64, 0, len(segments))
+	for _, segment := range segments {
+		if segment >= from {
+			replayed = append(replayed, segment)
+		}
+	}
+
+	expected := from
+	for i, segment := range replayed {
+		if i == 0 && from == 0 {
+			expected = segment
+		}
+
+		// Segments are written one after another, so a missing one means lost records.
+		if segment != expected {
+			return nil, fmt.Errorf("wal: segment %d is missing", expected)
+		}
+		expected++
+
+		err = w.replay(segment, i == len(replayed)-1, replay)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(replayed) == 0 {
+		err = w.create(max(from, 1))
+	} else {
+		err = w.openLast(replayed[len(replayed)-1])
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = w.Remove(from)
+	if err != nil {
+		w.file.Close()
+		return nil, err
+	}
+
+	if w.syncPolicy == SyncInterval {
+		w.wg.Add(1)
+		go w.syncLoop()
+	}
+
+	return w, nil
+}
+
+// replay reads records of a segment. Only the last segment may end with a damaged record, it is truncated.
+func (w *WAL) replay(segment uint64, last bool, replay func(data []byte) error) error {
+	f, err := os.Open(w.path(segment))
+	if err != nil {
+		return fmt.Errorf("wal: failed to open segment %d: %w", segment, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("wal: failed to stat segment %d: %w", segment, err)
+	}
+	size := info.Size()
+
+	r := bufio.NewReader(f)
+	header := make([]byte, headerSize)
+
+	for offset := int64(0); offset < size; {
+		// A record that doesn't fit the segment was being written during a crash.
+		tail := func() error {
+			if !last {
+				return fmt.Errorf("%w: segment %d is incomplete at offset %d", ErrCorrupted, segment, offset)
+			}
+			return w.truncate(segment, offset, size)
+		}
+
+		if size-offset < headerSize {
+			return tail()
+		}
+
+		_, err = io.ReadFull(r, header)
+		if err != nil {
+			return fmt.Errorf("wal: failed to read segment %d: %w", segment, err)
+		}
+
+		// A length is trusted only if it matches its checksum. Records aren't empty, so a zero length is damaged too.
+		length := int64(binary.LittleEndian.Uint32(header[0:4]))
+		if crc32.Checksum(header[0:4], crcTable) != binary.LittleEndian.Uint32(header[4:8]) || length == 0 {
+			// Zeros are left at the tail by a crash after the file was extended, but before the data was written.
+			zeros, err := onlyZeros(r)
+			if err != nil {
+				return fmt.Errorf("wal: failed to read segment %d: %w", segment, err)
+			}
+			if offset+headerSize == size || zeros {
+				return tail()
+			}
+			return fmt.Errorf("%w: damaged length in segment %d at offset %d", ErrCorrupted, segment, offset)
+		}
+
+		sum := binary.LittleEndian.Uint32(header[8:12])
+		end := offset + headerSize + length
+
+		// The record is the last one of the file and was being written during a crash.
+		if end > size {
+			return tail()
+		}
+
+		data := make([]byte, length)
+		_, err = io.ReadFull(r, data)
+		if err != nil {
+			return fmt.Errorf("wal: failed to read segment %d: %w", segment, err)
+		}
+
+		if crc32.Checksum(data, crcTable) != sum {
+			// Only the last record may be torn, a damaged record followed by others means the file is corrupted.
+			if end == size {
+				return tail()
+			}
+			return fmt.Errorf("%w: checksum mismatch in segment %d at offset %d", ErrCorrupted, segment, offset)
+		}
+
+		err = replay(data)
+		if err != nil {
+			return fmt.Errorf("wal: failed to replay record of segment %d at offset %d: %w", segment, offset, err)
+		}
+
+		offset = end
+	}
+
+	return nil
+}
+
+// onlyZeros reads the rest of a segment and reports whether it has only zero bytes.
+func onlyZeros(r io.Reader) (bool, error) {
+	buf := make([]byte, 4096)
+	for {
+		n, err := r.Read(buf)
+		for _, b := range buf[:n] {
+			if b != 0 {
+				return false, nil
+			}
+		}
+		if err == io.EOF {
+			return true, nil
+		} else if err != nil {
+			return false, err
+		}
+	}
+}
+
+// truncate drops the damaged tail of a segment starting at offset.
+func (w *WAL) truncate(segment uint64, offset int64, size int64) error {
+	err := os.Truncate(w.path(segment), offset)
+	if err != nil {
+		return fmt.Errorf("wal: failed to truncate segment %d: %w", segment, err)
+	}
+
+	w.truncated = size - offset
+
+	return nil
+}
+
+// Truncated returns the number of bytes of the damaged last record dropped by Open, 0 if the log was intact.
+func (w *WAL) Truncated() int64 {
+	return w.truncated
+}
+
+// Err returns the error of a failed write or fsync after which the log doesn't accept records, nil if there was none.
+func (w *WAL) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.err
+}
+
+// Append writes a record to the log. With SyncAlways policy the record is on the disk when Append returns.
+// A failed write is rolled back, so the log stays readable. Records must not be empty.
+func (w *WAL) Append(data []byte) error {
+	if len(data) == 0 {
+		return ErrEmptyRecord
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.err != nil {
+		return w.err
+	}
+
+	record := make([]byte, headerSize+len(data))
+	binary.LittleEndian.PutUint32(record[0:4], uint32(len(data)))
+	binary.LittleEndian.PutUint32(record[4:8], crc32.Checksum(record[0:4], crcTable))
+	binary.LittleEndian.PutUint32(record[8:12], crc32.Checksum(data, crcTable))
+	copy(record[headerSize:], data)
+
+	_, err := w.file.Write(record)
+	if err != nil {
+		// Drop the partially written record, so records appended later aren't read as corrupted.
+		if truncErr := w.file.Truncate(w.size); truncErr != nil {
+			w.err = fmt.Errorf("wal: failed to roll back a write: %w", truncErr)
+		}
+		return fmt.Errorf("wal: failed to write record: %w", err)
+	}
+	w.size += int64(len(record))
+
+	if w.syncPolicy != SyncAlways {
+		w.dirty = true
+		return nil
+	}
+
+	err = w.file.Sync()
+	if err != nil {
+		// It isn't known which records reached the disk after a failed fsync.
+		w.err = fmt.Errorf("wal: failed to sync: %w", err)
+		return w.err
+	}
+
+	return nil
+}
+
+// Sync flushes appended records to the disk.
+func (w *WAL) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.sync()
+}
+
+func (w *WAL) sync() error {
+	if w.err != nil {
+		return w.err
+	}
+	if !w.dirty {
+		return nil
+	}
+
+	err := w.file.Sync()
+	if err != nil {
+		w.err = fmt.Errorf("wal: failed to sync: %w", err)
+		return w.err
+	}
+	w.dirty = false
+
+	return nil
+}
+
+// syncLoop flushes records every sync interval until the log is closed.
+func (w *WAL) syncLoop() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.syncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			// A failed fsync is returned by the next Append.
+			_ = w.Sync()
+		}
+	}
+}
+
+// Rotate flushes the current segment and starts a new one. It returns the number of the new segment,
+// records appended after Rotate go to it.
+func (w *WAL) Rotate() (uint64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.dirty = true
+	err := w.sync()
+	if err != nil {
+		return 0, err
+	}
+
+	err = w.file.Close()
+	if err != nil {
+		return 0, fmt.Errorf("wal: failed to close segment %d: %w", w.segment, err)
+	}
+
+	err = w.create(w.segment + 1)
+	if err != nil {
+		w.err = err
+		return 0, err
+	}
+
+	return w.segment, nil
+}
+
+// Remove removes segments before the segment before. The current segment is never removed.
+func (w *WAL) Remove(before uint64) error {
+	segments, err := w.segments()
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	current := w.segment
+	w.mu.Unlock()
+
+	for _, segment := range segments {
+		if segment >= before || segment == current {
+			continue
+		}
+
+		err = os.Remove(w.path(segment))
+		if err != nil {
+			return fmt.Errorf("wal: failed to remove segment %d: %w", segment, err)
+		}
+	}
+
+	return nil
+}
+
+// Close flushes appended records and closes the log.
+func (w *WAL) Close() error {
+	close(w.done)
+	w.wg.Wait()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	syncErr := w.sync()
+	err := w.file.Close()
+	if syncErr != nil {
+		return syncErr
+	}
+	if err != nil {
+		return fmt.Errorf("wal: failed to close segment %d: %w", w.segment, err)
+	}
+
+	return nil
+}
+
+// create creates an empty segment and makes it current.
+func (w *WAL) create(segment uint64) error {
+	f, err := os.OpenFile(w.path(segment), os.O_WRONLY|os.O_APPEND|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("wal: failed to create segment %d: %w", segment, err)
+	}
+
+	// The new file is durable only after its directory entry is flushed.
+	err = SyncDir(w.dir)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	w.file, w.segment, w.size, w.dirty = f, segment, 0, false
+
+	return nil
+}
+
+// openLast opens the replayed last segment for appending.
+func (w *WAL) openLast(segment uint64) error {
+	f, err := os.OpenFile(w.path(segment), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("wal: failed to open segment %d: %w", segment, err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("wal: failed to stat segment %d: %w", segment, err)
+	}
+
+	w.file, w.segment, w.size = f, segment, info.Size()
+
+	// The truncation must reach the disk before new records are appended after it.
+	if w.truncated > 0 {
+		w.dirty = true
+		err = w.sync()
+		if err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	return nil
+}
+
+// segments returns numbers of segments in the directory in ascending order.
+func (w *WAL) segments() ([]uint64, error) {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return nil, fmt.Errorf("wal: failed to read directory: %w", err)
+	}
+
+	segments := make([]uint64, 0, len(entries))
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), segmentSuffix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+
+		segment, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, segment)
+	}
+
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i] < segments[j]
+	})
+
+	return segments, nil
+}
+
+func (w *WAL) path(segment uint64) string {
+	return filepath.Join(w.dir, fmt.Sprintf("%020d%s", segment, segmentSuffix))
+}
+
+// SyncDir flushes entries of a directory, so files created or renamed in it survive a crash.
+func SyncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("wal: failed to open directory: %w", err)
+	}
+	defer d.Close()
+
+	err = d.Sync()
+	if err != nil {
+		return fmt.Errorf("wal: failed to sync directory: %w", err)
+	}
+
+	return nil
+}
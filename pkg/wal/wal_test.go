@@ -0,0 +1,329 @@
+package wal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// openRecords opens the log in dir and returns it with the replayed records.
+func openRecords(t *testing.T, dir string, from uint64) (*WAL, []string, error) {
+	t.Helper()
+
+	records := make([]string, 0)
+	w, err := Open(dir, from, func(data []byte) error {
+		records = append(records, string(data))
+		return nil
+	}, Sync(SyncNever))
+
+	return w, records, err
+}
+
+func closeLog(t *testing.T, w *WAL) {
+	t.Helper()
+
+	err := w.Close()
+	if err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+// appendRecords appends records to the log.
+func appendRecords(t *testing.T, w *WAL, records ...string) {
+	t.Helper()
+
+	for _, record := range records {
+		err := w.Append([]byte(record))
+		if err != nil {
+			t.Fatalf("Append(%q): %v", record, err)
+		}
+	}
+}
+
+// writeSegment creates a log in a new directory with the records in its first segment
+// and returns the directory with the contents of the segment.
+func writeSegment(t *testing.T, records ...string) (string, []byte) {
+	t.Helper()
+
+	dir := t.TempDir()
+	w, _, err := openRecords(t, dir, 0)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	appendRecords(t, w, records...)
+	closeLog(t, w)
+
+	data, err := os.ReadFile(w.path(1))
+	if err != nil {
+		t.Fatalf("failed to read segment: %v", err)
+	}
+
+	return dir, data
+}
+
+func checkRecords(t *testing.T, got []string, want ...string) {
+	t.Helper()
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("records = %q, want %q", got, want)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	dir, _ := writeSegment(t, "first", "second", "third")
+
+	w, records, err := openRecords(t, dir, 0)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer closeLog(t, w)
+	checkRecords(t, records, "first", "second", "third")
+
+	if w.Truncated() != 0 {
+		t.Fatalf("Truncated = %d, want 0", w.Truncated())
+	}
+}
+
+func TestReplayTornTail(t *testing.T) {
+	tests := []struct {
+		name   string
+		damage func(segment []byte) []byte
+		want   []string
+	}{
+		{
+			name:   "partial header",
+			damage: func(segment []byte) []byte { return append(segment, 5, 0, 0) },
+			want:   []string{"first", "second"},
+		},
+		{
+			name:   "partial data",
+			damage: func(segment []byte) []byte { return segment[:len(segment)-3] },
+			want:   []string{"first"},
+		},
+		{
+			name: "damaged last data",
+			damage: func(segment []byte) []byte {
+				segment[len(segment)-1] ^= 0xff
+				return segment
+			},
+			want: []string{"first"},
+		},
+		{
+			name: "damaged last header",
+			damage: func(segment []byte) []byte {
+				return append(segment, 0xff, 0xff, 0xff, 0xff, 1, 2, 3, 4, 5, 6, 7, 8)
+			},
+			want: []string{"first", "second"},
+		},
+		{
+			name:   "zero-filled tail",
+			damage: func(segment []byte) []byte { return append(segment, make([]byte, 100)...) },
+			want:   []string{"first", "second"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, segment := writeSegment(t, "first", "second")
+			damaged := tt.damage(bytes.Clone(segment))
+
+			err := os.WriteFile(dir+"/00000000000000000001.wal", damaged, 0o644)
+			if err != nil {
+				t.Fatalf("failed to damage segment: %v", err)
+			}
+
+			w, records, err := openRecords(t, dir, 0)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			checkRecords(t, records, tt.want...)
+
+			if w.Truncated() <= 0 {
+				t.Fatalf("Truncated = %d, want positive", w.Truncated())
+			}
+
+			// Records appended after the truncation are replayed after the intact ones.
+			appendRecords(t, w, "third")
+			closeLog(t, w)
+
+			w, records, err = openRecords(t, dir, 0)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			defer closeLog(t, w)
+			checkRecords(t, records, append(tt.want, "third")...)
+		})
+	}
+}
+
+func TestReplayCorrupted(t *testing.T) {
+	tests := []struct {
+		name   string
+		damage func(segment []byte) []byte
+	}{
+		{
+			name: "damaged data",
+			damage: func(segment []byte) []byte {
+				segment[headerSize] ^= 0xff
+				return segment
+			},
+		},
+		{
+			// The length exceeds the file, but the record isn't the last one.
+			name: "oversize length",
+			damage: func(segment []byte) []byte {
+				segment[3] = 0x7f
+				return segment
+			},
+		},
+		{
+			name: "zero length",
+			damage: func(segment []byte) []byte {
+				copy(segment[0:4], []byte{0, 0, 0, 0})
+				return segment
+			},
+		},
+		{
+			name: "zeros in the middle",
+			damage: func(segment []byte) []byte {
+				copy(segment[:headerSize+len("first")], make([]byte, headerSize+len("first")))
+				return segment
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, segment := writeSegment(t, "first", "second", "third")
+
+			err := os.WriteFile(dir+"/00000000000000000001.wal", tt.damage(bytes.Clone(segment)), 0o644)
+			if err != nil {
+				t.Fatalf("failed to damage segment: %v", err)
+			}
+
+			_, _, err = openRecords(t, dir, 0)
+			if !errors.Is(err, ErrCorrupted) {
+				t.Fatalf("Open error = %v, want ErrCorrupted", err)
+			}
+		})
+	}
+}
+
+func TestReplayTornTailOfEarlierSegment(t *testing.T) {
+	dir, segment := writeSegment(t, "first", "second")
+
+	w, _, err := openRecords(t, dir, 0)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	_, err = w.Rotate()
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	appendRecords(t, w, "third")
+	closeLog(t, w)
+
+	// Only the last segment may end with a torn record.
+	err = os.WriteFile(w.path(1), segment[:len(segment)-3], 0o644)
+	if err != nil {
+		t.Fatalf("failed to damage segment: %v", err)
+	}
+
+	_, _, err = openRecords(t, dir, 0)
+	if !errors.Is(err, ErrCorrupted) {
+		t.Fatalf("Open error = %v, want ErrCorrupted", err)
+	}
+}
+
+func TestRotateAndRemove(t *testing.T) {
+	dir := t.TempDir()
+
+	w, _, err := openRecords(t, dir, 0)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	appendRecords(t, w, "first")
+
+	segment, err := w.Rotate()
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if segment != 2 {
+		t.Fatalf("Rotate = %d, want 2", segment)
+	}
+	appendRecords(t, w, "second")
+
+	// All segments are replayed until they are removed.
+	closeLog(t, w)
+	w, records, err := openRecords(t, dir, 0)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	checkRecords(t, records, "first", "second")
+
+	err = w.Remove(segment)
+	if err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if _, err := os.Stat(w.path(1)); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("segment 1 isn't removed: %v", err)
+	}
+
+	// The current segment is never removed.
+	err = w.Remove(segment + 1)
+	if err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if _, err := os.Stat(w.path(segment)); err != nil {
+		t.Fatalf("current segment is removed: %v", err)
+	}
+
+	closeLog(t, w)
+	w, records, err = openRecords(t, dir, segment)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer closeLog(t, w)
+	checkRecords(t, records, "second")
+}
+
+func TestOpenMissingSegment(t *testing.T) {
+	dir := t.TempDir()
+
+	w, _, err := openRecords(t, dir, 0)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		_, err = w.Rotate()
+		if err != nil {
+			t.Fatalf("Rotate: %v", err)
+		}
+	}
+	closeLog(t, w)
+
+	err = os.Remove(w.path(2))
+	if err != nil {
+		t.Fatalf("failed to remove segment: %v", err)
+	}
+
+	_, _, err = openRecords(t, dir, 0)
+	if err == nil || err.Error() != "wal: segment 2 is missing" {
+		t.Fatalf("Open error = %v, want a missing segment", err)
+	}
+}
+
+func TestAppendEmptyRecord(t *testing.T) {
+	w, _, err := openRecords(t, t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer closeLog(t, w)
+
+	err = w.Append(nil)
+	if !errors.Is(err, ErrEmptyRecord) {
+		t.Fatalf("Append error = %v, want ErrEmptyRecord", err)
+	}
+}